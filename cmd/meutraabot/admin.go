@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/pkg/errors"
+)
+
+// The admin commands work on the database directly, by channel and user id,
+// so that they work while the bot is stopped or Twitch is unavailable. The bot
+// only joins added channels and leaves removed ones when it is restarted.
+
+// openDatabase prepares the configured database for an admin command.
+func openDatabase() (*Server, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ValidateDatabase(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{config: config}
+	if err := s.PrepareDatabase(); nil != err {
+		return nil, err
+	}
+	return s, nil
+}
+
+// runAdmin runs one of the admin commands against the configured database.
+func runAdmin(run func(ctx context.Context, q db.Querier, out io.Writer) error) error {
+	s, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return run(context.Background(), s.q, os.Stdout)
+}
+
+// parseID checks that an argument is a Twitch id.
+func parseID(id string) (string, error) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%v is not a valid id", id)
+	}
+	return strconv.FormatUint(n, 10), nil
+}
+
+func runChannels(args []string) error {
+	usage := errors.New("usage: channels [list|add ID|remove ID]")
+	if len(args) == 0 {
+		return usage
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		return runAdmin(listChannels)
+	case (args[0] == "add" || args[0] == "remove") && len(args) == 2:
+		id, err := parseID(args[1])
+		if err != nil {
+			return err
+		}
+		if args[0] == "add" {
+			return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+				return addChannel(ctx, q, out, id)
+			})
+		}
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return removeChannel(ctx, q, out, id)
+		})
+	}
+	return usage
+}
+
+func listChannels(ctx context.Context, q db.Querier, out io.Writer) error {
+	ids, err := q.GetChannels(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to get channels")
+	}
+	for _, id := range ids {
+		fmt.Fprintln(out, id)
+	}
+	return nil
+}
+
+func addChannel(ctx context.Context, q db.Querier, out io.Writer, id string) error {
+	if err := q.CreateChannel(ctx, id); err != nil {
+		return errors.Wrap(err, "unable to add channel")
+	}
+	fmt.Fprintln(out, "channel", id, "added")
+	return nil
+}
+
+// removeChannel removes a channel, its OpenAI token and webhooks, like
+// unregistering through the API. Its commands are kept.
+func removeChannel(ctx context.Context, q db.Querier, out io.Writer, id string) error {
+	if _, err := q.GetChannel(ctx, id); err == sql.ErrNoRows {
+		return fmt.Errorf("channel %v does not exist", id)
+	} else if err != nil {
+		return errors.Wrap(err, "unable to get channel")
+	}
+	if err := q.DeleteChannel(ctx, id); err != nil {
+		return errors.Wrap(err, "unable to remove channel")
+	}
+	if err := q.DeleteSecret(ctx, channelTokenSecret(id)); err != nil {
+		return errors.Wrap(err, "unable to remove channel token")
+	}
+	if err := deleteIncomingWebhooks(ctx, q, id); err != nil {
+		return errors.Wrap(err, "unable to remove incoming webhooks")
+	}
+	if err := deleteWebhooks(ctx, q, id); err != nil {
+		return errors.Wrap(err, "unable to remove webhooks")
+	}
+	fmt.Fprintln(out, "channel", id, "removed")
+	return nil
+}
+
+func runCommands(args []string) error {
+	usage := errors.New("usage: commands [list CHANNEL|set CHANNEL NAME TEMPLATE|unset CHANNEL NAME|export CHANNEL], where CHANNEL 0 holds the global commands")
+	if len(args) < 2 {
+		return usage
+	}
+	id, err := parseID(args[1])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 2:
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return listCommands(ctx, q, out, id)
+		})
+	case args[0] == "set" && len(args) >= 3:
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return setCommand(ctx, q, out, id, args[2], strings.Join(args[3:], " "))
+		})
+	case args[0] == "unset" && len(args) == 3:
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return unsetCommand(ctx, q, out, id, args[2])
+		})
+	case args[0] == "export" && len(args) == 2:
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return exportCommands(ctx, q, out, id)
+		})
+	}
+	return usage
+}
+
+func listCommands(ctx context.Context, q db.Querier, out io.Writer, id string) error {
+	commands, err := q.GetCommandsByID(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "unable to get commands")
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "%v\t%v\n", c.Name, c.Template)
+	}
+	return w.Flush()
+}
+
+func setCommand(ctx context.Context, q db.Querier, out io.Writer, id, name, template string) error {
+	if err := validateCommand(name, template, ""); err != nil {
+		return err
+	}
+	if err := q.SetCommand(ctx, db.SetCommandParams{
+		ChannelID: id,
+		Name:      name,
+		Template:  template,
+		UpdatedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	}); err != nil {
+		return errors.Wrap(err, "unable to set command")
+	}
+	fmt.Fprintf(out, "command %v set\n", name)
+	return nil
+}
+
+func unsetCommand(ctx context.Context, q db.Querier, out io.Writer, id, name string) error {
+	if _, err := q.GetCommand(ctx, db.GetCommandParams{ChannelID: id, Name: name}); err == sql.ErrNoRows {
+		return fmt.Errorf("command %v does not exist", name)
+	} else if err != nil {
+		return errors.Wrap(err, "unable to get command")
+	}
+	if err := q.DeleteCommand(ctx, db.DeleteCommandParams{ChannelID: id, Name: name}); err != nil {
+		return errors.Wrap(err, "unable to unset command")
+	}
+	fmt.Fprintf(out, "command %v unset\n", name)
+	return nil
+}
+
+// ExportedCommand is a command as written by the commands export command.
+type ExportedCommand struct {
+	ChannelID   string `json:"channel_id"`
+	Name        string `json:"name"`
+	Template    string `json:"template"`
+	Description string `json:"description,omitempty"`
+}
+
+func exportCommands(ctx context.Context, q db.Querier, out io.Writer, id string) error {
+	commands, err := q.GetCommandsByID(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "unable to get commands")
+	}
+	exported := make([]ExportedCommand, len(commands))
+	for i, c := range commands {
+		exported[i] = ExportedCommand{ChannelID: id, Name: c.Name, Template: c.Template, Description: c.Description}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(exported)
+}
+
+func runApprovals(args []string) error {
+	usage := errors.New("usage: approvals [list CHANNEL|add CHANNEL USER|remove CHANNEL USER]")
+	if len(args) < 2 {
+		return usage
+	}
+	ids := make([]string, len(args)-1)
+	for i, arg := range args[1:] {
+		id, err := parseID(arg)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+
+	switch {
+	case args[0] == "list" && len(ids) == 1:
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return listApprovals(ctx, q, out, ids[0])
+		})
+	case args[0] == "add" && len(ids) == 2:
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return addApproval(ctx, q, out, ids[0], ids[1])
+		})
+	case args[0] == "remove" && len(ids) == 2:
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return removeApproval(ctx, q, out, ids[0], ids[1])
+		})
+	}
+	return usage
+}
+
+// listApprovals lists the users approved with +approve, not those approved
+// automatically when they joined.
+func listApprovals(ctx context.Context, q db.Querier, out io.Writer, id string) error {
+	approvals, err := q.GetApprovals(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "unable to get approvals")
+	}
+	for _, a := range approvals {
+		fmt.Fprintln(out, a.UserID)
+	}
+	return nil
+}
+
+func addApproval(ctx context.Context, q db.Querier, out io.Writer, channelID, userID string) error {
+	// Replace an automatic approval with a manual one
+	if err := q.Unapprove(ctx, db.UnapproveParams{ChannelID: channelID, UserID: userID}); err != nil {
+		return errors.Wrap(err, "unable to approve user")
+	}
+	if err := q.Approve(ctx, db.ApproveParams{ChannelID: channelID, UserID: userID, Manual: true}); err != nil {
+		return errors.Wrap(err, "unable to approve user")
+	}
+	fmt.Fprintln(out, "user", userID, "approved")
+	return nil
+}
+
+// removeApproval only removes the approval. Unlike +unapprove the user is
+// not banned, which needs Twitch.
+func removeApproval(ctx context.Context, q db.Querier, out io.Writer, channelID, userID string) error {
+	if err := q.Unapprove(ctx, db.UnapproveParams{ChannelID: channelID, UserID: userID}); err != nil {
+		return errors.Wrap(err, "unable to unapprove user")
+	}
+	fmt.Fprintln(out, "user", userID, "unapproved")
+	return nil
+}
+
+// channelSetting is a channel setting that can be read and changed by name.
+type channelSetting struct {
+	get func(c *db.Channel) string
+	set func(c *db.Channel, value string) error
+}
+
+var channelSettings = map[string]channelSetting{
+	"autoreply_enabled": {
+		get: func(c *db.Channel) string { return strconv.FormatBool(c.AutoreplyEnabled) },
+		set: func(c *db.Channel, value string) (err error) {
+			c.AutoreplyEnabled, err = strconv.ParseBool(value)
+			return err
+		},
+	},
+	"autoreply_frequency": {
+		get: func(c *db.Channel) string { return strconv.FormatFloat(c.AutoreplyFrequency, 'f', -1, 64) },
+		set: func(c *db.Channel, value string) (err error) {
+			c.AutoreplyFrequency, err = strconv.ParseFloat(value, 64)
+			return err
+		},
+	},
+	"reply_safety": {
+		get: func(c *db.Channel) string { return strconv.FormatInt(c.ReplySafety, 10) },
+		set: func(c *db.Channel, value string) (err error) {
+			c.ReplySafety, err = strconv.ParseInt(value, 10, 64)
+			return err
+		},
+	},
+	"chatlog_enabled": {
+		get: func(c *db.Channel) string { return strconv.FormatBool(c.ChatlogEnabled) },
+		set: func(c *db.Channel, value string) (err error) {
+			c.ChatlogEnabled, err = strconv.ParseBool(value)
+			return err
+		},
+	},
+	"chatlog_retention_days": {
+		get: func(c *db.Channel) string { return strconv.FormatInt(c.ChatlogRetentionDays, 10) },
+		set: func(c *db.Channel, value string) (err error) {
+			c.ChatlogRetentionDays, err = strconv.ParseInt(value, 10, 64)
+			return err
+		},
+	},
+	"commands_public": {
+		get: func(c *db.Channel) string { return strconv.FormatBool(c.CommandsPublic) },
+		set: func(c *db.Channel, value string) (err error) {
+			c.CommandsPublic, err = strconv.ParseBool(value)
+			return err
+		},
+	},
+	"listed": {
+		get: func(c *db.Channel) string { return strconv.FormatBool(c.Listed) },
+		set: func(c *db.Channel, value string) (err error) {
+			c.Listed, err = strconv.ParseBool(value)
+			return err
+		},
+	},
+}
+
+// settingNames are the names of channelSettings in the order they are listed.
+var settingNames = []string{"autoreply_enabled", "autoreply_frequency", "reply_safety", "chatlog_enabled", "chatlog_retention_days", "commands_public", "listed"}
+
+func runSettings(args []string) error {
+	usage := errors.New("usage: settings [get CHANNEL [NAME]|set CHANNEL NAME VALUE], where NAME is one of " + strings.Join(settingNames, ", "))
+	if len(args) < 2 {
+		return usage
+	}
+	id, err := parseID(args[1])
+	if err != nil {
+		return err
+	}
+	if len(args) > 2 {
+		if _, ok := channelSettings[args[2]]; !ok {
+			return fmt.Errorf("unknown setting %v", args[2])
+		}
+	}
+
+	switch {
+	case args[0] == "get" && (len(args) == 2 || len(args) == 3):
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return getSettings(ctx, q, out, id, args[2:]...)
+		})
+	case args[0] == "set" && len(args) == 4:
+		return runAdmin(func(ctx context.Context, q db.Querier, out io.Writer) error {
+			return setSetting(ctx, q, out, id, args[2], args[3])
+		})
+	}
+	return usage
+}
+
+func getChannel(ctx context.Context, q db.Querier, id string) (db.Channel, error) {
+	channel, err := q.GetChannel(ctx, id)
+	if err == sql.ErrNoRows {
+		return channel, fmt.Errorf("channel %v does not exist", id)
+	} else if err != nil {
+		return channel, errors.Wrap(err, "unable to get channel")
+	}
+	return channel, nil
+}
+
+// getSettings prints the named settings of a channel, or all of them.
+func getSettings(ctx context.Context, q db.Querier, out io.Writer, id string, names ...string) error {
+	channel, err := getChannel(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	if len(names) == 1 {
+		fmt.Fprintln(out, channelSettings[names[0]].get(&channel))
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, name := range settingNames {
+		fmt.Fprintf(w, "%v\t%v\n", name, channelSettings[name].get(&channel))
+	}
+	return w.Flush()
+}
+
+func setSetting(ctx context.Context, q db.Querier, out io.Writer, id, name, value string) error {
+	channel, err := getChannel(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	if err := channelSettings[name].set(&channel, value); err != nil {
+		return fmt.Errorf("invalid value for %v: %v", name, value)
+	}
+	if err := validateChannelSettings(&channel); err != nil {
+		return err
+	}
+	if err := q.UpdateChannel(ctx, db.UpdateChannelParams{
+		ChannelID:            id,
+		AutoreplyEnabled:     channel.AutoreplyEnabled,
+		AutoreplyFrequency:   channel.AutoreplyFrequency,
+		ReplySafety:          channel.ReplySafety,
+		ChatlogEnabled:       channel.ChatlogEnabled,
+		ChatlogRetentionDays: channel.ChatlogRetentionDays,
+		CommandsPublic:       channel.CommandsPublic,
+		Listed:               channel.Listed,
+	}); err != nil {
+		return errors.Wrap(err, "unable to update channel")
+	}
+	fmt.Fprintf(out, "%v set to %v\n", name, channelSettings[name].get(&channel))
+	return nil
+}
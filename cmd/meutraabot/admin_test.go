@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+)
+
+func TestSettings(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestServer(t)
+	out := &strings.Builder{}
+
+	if err := setSetting(ctx, s.q, out, testBroadcaster.ID, "reply_safety", "1"); err == nil {
+		t.Error("expected an unknown channel to be an error")
+	}
+	if err := addChannel(ctx, s.q, out, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setSetting(ctx, s.q, out, testBroadcaster.ID, "chatlog_enabled", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setSetting(ctx, s.q, out, testBroadcaster.ID, "autoreply_frequency", "10"); err == nil {
+		t.Error("expected an out of range frequency to be an error")
+	}
+	if err := setSetting(ctx, s.q, out, testBroadcaster.ID, "reply_safety", "high"); err == nil {
+		t.Error("expected an invalid number to be an error")
+	}
+
+	channel, err := s.q.GetChannel(ctx, testBroadcaster.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !channel.ChatlogEnabled || channel.AutoreplyFrequency != 2 || channel.ReplySafety != 2 {
+		t.Errorf("expected only the chat log to be enabled, got %+v", channel)
+	}
+
+	out.Reset()
+	if err := getSettings(ctx, s.q, out, testBroadcaster.ID, "chatlog_enabled"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "true\n" {
+		t.Errorf("unexpected setting %q", out)
+	}
+}
+
+func TestRemoveChannel(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestServer(t)
+	out := &strings.Builder{}
+
+	if err := addChannel(ctx, s.q, out, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.secrets.Set(ctx, channelTokenSecret(testBroadcaster.ID), "sk-token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.q.CreateIncomingWebhook(ctx, db.CreateIncomingWebhookParams{
+		ChannelID: testBroadcaster.ID,
+		Command:   "^!donation",
+		TokenHash: hashToken("token"),
+		CreatedBy: testBroadcaster.ID,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	hook, err := s.q.CreateWebhook(ctx, db.CreateWebhookParams{
+		ChannelID: testBroadcaster.ID,
+		Url:       "https://example.com/hook",
+		Events:    WebhookChannelLeft,
+		CreatedBy: testBroadcaster.ID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.secrets.Set(ctx, webhookSecret(hook.ID), "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeChannel(ctx, s.q, out, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := s.secrets.Exists(ctx, channelTokenSecret(testBroadcaster.ID)); err != nil || exists {
+		t.Errorf("expected the channel token to be removed, %v", err)
+	}
+	if hooks, err := s.q.GetIncomingWebhooks(ctx, testBroadcaster.ID); err != nil || len(hooks) != 0 {
+		t.Errorf("expected the incoming webhooks to be removed, got %v, %v", hooks, err)
+	}
+	if hooks, err := s.q.GetWebhooks(ctx, testBroadcaster.ID); err != nil || len(hooks) != 0 {
+		t.Errorf("expected the webhooks to be removed, got %v, %v", hooks, err)
+	}
+	if exists, err := s.secrets.Exists(ctx, webhookSecret(hook.ID)); err != nil || exists {
+		t.Errorf("expected the webhook secret to be removed, %v", err)
+	}
+	if err := removeChannel(ctx, s.q, out, testBroadcaster.ID); err == nil {
+		t.Error("expected removing a removed channel to be an error")
+	}
+}
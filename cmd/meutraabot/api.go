@@ -1,17 +1,21 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	l "log"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/go-chi/hostrouter"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/crypto/acme/autocert"
 
 	"github.com/go-chi/chi/v5"
@@ -20,107 +24,183 @@ import (
 	"github.com/meutraa/meutraabot/pkg/db"
 	"github.com/nicklaw5/helix/v2"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 )
 
 func (s *Server) PrepareAPI() {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(realIP(s.config.API.TrustsProxy))
+	r.Use(logRequests)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(unlessEventStream(middleware.Timeout(30 * time.Second)))
 
-	ar := chi.NewRouter()
-	ar.Use(cors.Handler(cors.Options{
-		AllowedOrigins: []string{"https://meuua.com"},
-		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-CSRF-Token"},
-		AllowCredentials: false,
-		MaxAge:           3600,
-	}))
+	ar := s.apiRouter()
 
-	ar.Route("/channels", func(r chi.Router) {
-		r.Get("/", s.listChannels())
-		r.Route("/{id}", func(r chi.Router) {
-			r.Put("/", s.registerChannel())
-			r.Get("/", s.getChannel())
-			r.Delete("/", s.unregisterChannel())
-			r.Patch("/", s.patchChannel())
-			r.Get("/commands", s.listCommands())
-			r.Get("/approvals", s.listApprovals())
-		})
-	})
+	or := chi.NewRouter()
+	or.Get("/", s.oauthCallback())
 
-	ar.Route("/commands", func(r chi.Router) {
-		r.Get("/", s.listLocalCommands("0"))
+	r.Group(func(r chi.Router) {
+		r.Use(s.authenticate)
+		r.Use(limitAnonymous(newRateLimiter(s.config.API.AnonymousRateLimit, time.Minute)))
+		r.Get("/healthz", s.healthz())
+		r.Get("/readyz", s.readyz())
 	})
 
-	or := chi.NewRouter()
-	or.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		l.Println("Received request to oauth")
-		code := r.URL.Query().Get("code")
-		if code == "" {
-			l.Println("user access token missing from oauth redirect")
-		}
-		select {
-		case s.oauth <- code:
-			l.Println("sent user access token to internal")
-			state := r.URL.Query().Get("state")
-			s.oauth <- state
-			l.Println("sent state to internal")
+	switch s.config.API.Routing {
+	case RoutingPath:
+		r.Mount("/api", ar)
+		r.Mount("/oauth", or)
+	default:
+		hr := hostrouter.New()
+		hr.Map(s.config.API.Host, ar)
+		hr.Map(s.config.API.OAuthHost, or)
+		r.Mount("/", hr)
+	}
+
+	server := &http.Server{
+		Addr:    s.config.API.Addr,
+		Handler: r,
+	}
+
+	go func() {
+		apiLog.Info("serving http endpoints", "addr", s.config.API.Addr, "mode", s.config.API.Mode)
+		var err error
+		switch s.config.API.Mode {
+		case ListenHTTP:
+			err = server.ListenAndServe()
+		case ListenTLS:
+			err = server.ListenAndServeTLS(s.config.API.CertFile, s.config.API.KeyFile)
 		default:
-			l.Println("user access token not sent to internal")
+			certManager := autocert.Manager{
+				Prompt:     autocert.AcceptTOS,
+				HostPolicy: autocert.HostWhitelist(s.config.API.Host, s.config.API.OAuthHost),
+				Cache:      autocert.DirCache(s.config.API.CertDir),
+			}
+			server.TLSConfig = &tls.Config{
+				GetCertificate: certManager.GetCertificate,
+			}
+			go http.ListenAndServe(":http", certManager.HTTPHandler(nil))
+			err = server.ListenAndServeTLS("", "")
 		}
-
-		if code != "" {
-			w.WriteHeader(http.StatusOK)
-			return
+		if nil != err {
+			panic(err)
 		}
+	}()
+}
 
-		w.Header().Set("Content-Type", "text/html")
-		// Get the "access_token" from the url fragment using javascript. Display the token large in the center of the page.
-		w.Write([]byte(`<html>
-				<head>
-						<script>
-								var url = window.location.href;
-								var access_token = url.split("#")[1].split("&")[0].split("=")[1];
-								document.write("<h1 style='text-align:center; font-size: 96px;'>" + access_token + "</h1>");
-						</script>
-				</head>
-		</html>`))
-	})
-
-	hr := hostrouter.New()
-	hr.Map("api.meuua.com", ar)
-	hr.Map("oauth.meuua.com", or)
-
-	certManager := autocert.Manager{
-		Prompt:     autocert.AcceptTOS,
-		HostPolicy: autocert.HostWhitelist("api.meuua.com", "oauth.meuua.com"),
-		Cache:      autocert.DirCache("certs"),
+// serveMetrics serves Prometheus metrics on their own address, away from the
+// public API, as they are labelled with the channels the bot is in.
+func (s *Server) serveMetrics() {
+	if s.config.API.MetricsAddr == "" {
+		return
 	}
 
-	r.Mount("/", hr)
-
+	r := chi.NewRouter()
+	r.Handle("/metrics", promhttp.Handler())
 	server := &http.Server{
-		Addr:    ":https",
+		Addr:    s.config.API.MetricsAddr,
 		Handler: r,
-		TLSConfig: &tls.Config{
-			GetCertificate: certManager.GetCertificate,
-		},
 	}
 
 	go func() {
-		l.Println("serving http endpoints")
-		go http.ListenAndServe(":http", certManager.HTTPHandler(nil))
-		err := server.ListenAndServeTLS("", "")
-		if nil != err {
+		apiLog.Info("serving metrics", "addr", s.config.API.MetricsAddr)
+		if err := server.ListenAndServe(); err != nil {
 			panic(err)
 		}
 	}()
 }
 
+// apiRouter returns the routes of the API.
+func (s *Server) apiRouter() chi.Router {
+	ar := chi.NewRouter()
+	ar.Use(cors.Handler(cors.Options{
+		AllowedOrigins: s.config.API.CORSOrigins,
+		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
+		AllowedMethods:   []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowCredentials: false,
+		MaxAge:           3600,
+	}))
+
+	ar.Use(s.authenticate)
+	ar.Use(limitAnonymous(newRateLimiter(s.config.API.AnonymousRateLimit, time.Minute)))
+
+	ar.Get("/openapi.yaml", getSpec())
+	ar.Get("/openapi.json", getSpecJSON())
+	ar.With(s.requireRole(RoleNone), validateRequest).Get("/me", s.getMe())
+	ar.With(validateRequest).Post("/hooks/{token}", s.receiveWebhook())
+
+	ar.Route("/channels", func(r chi.Router) {
+		r.With(validateRequest).Get("/", s.listChannels())
+		r.Route("/{id}", func(r chi.Router) {
+			r.Use(channelParam)
+			r.Group(func(r chi.Router) {
+				r.Use(s.requireVisibleCommands, validateRequest)
+				r.Get("/commands", s.listCommands())
+				r.Get("/commands/{name}", s.getCommand())
+				r.Get("/numbers", s.listNumbers())
+				r.Get("/numbers/{name}", s.getNumber())
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.requireRole(RoleModerator), validateRequest)
+				r.Get("/", s.getChannel())
+				r.Get("/approvals", s.listApprovals())
+				r.Get("/bans", s.listBans())
+				r.Put("/commands/{name}", s.putCommand())
+				r.Patch("/commands/{name}", s.patchCommand())
+				r.Delete("/commands/{name}", s.deleteCommand())
+				r.Put("/numbers/{name}", s.putNumber())
+				r.Delete("/numbers/{name}", s.deleteNumber())
+				r.Get("/messages", s.listMessages())
+				r.Get("/events", s.streamEvents())
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.requireRole(RoleEditor), validateRequest)
+				r.Patch("/", s.patchChannel())
+				r.Get("/webhooks", s.listWebhooks())
+				r.Post("/webhooks", s.createWebhook())
+				r.Delete("/webhooks/{webhook}", s.deleteWebhook())
+				r.Get("/webhooks/{webhook}/deliveries", s.listWebhookDeliveries())
+				r.Get("/incoming-webhooks", s.listIncomingWebhooks())
+				r.Post("/incoming-webhooks", s.createIncomingWebhook())
+				r.Delete("/incoming-webhooks/{webhook}", s.deleteIncomingWebhook())
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.requireRole(RoleBroadcaster), validateRequest)
+				r.Put("/", s.registerChannel())
+				r.Delete("/", s.unregisterChannel())
+				r.Put("/approvals/{user}", s.putApproval())
+				r.Delete("/approvals/{user}", s.deleteApproval())
+				r.Get("/audit", s.listAudit())
+				r.Get("/grants", s.listGrants())
+				r.Put("/grants/{user}", s.putGrant())
+				r.Delete("/grants/{user}", s.deleteGrant())
+			})
+		})
+	})
+
+	ar.Route("/commands", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(validateRequest)
+			r.Get("/", s.listLocalCommands("0"))
+			r.Get("/{name}", s.getCommand())
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.requireRole(RoleOwner), validateRequest)
+			r.Put("/{name}", s.putCommand())
+			r.Patch("/{name}", s.patchCommand())
+			r.Delete("/{name}", s.deleteCommand())
+		})
+	})
+
+	return ar
+}
+
 func (s *Server) listLocalCommands(channelID string) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		commands, err := s.q.GetCommandsByID(r.Context(), channelID)
@@ -129,7 +209,7 @@ func (s *Server) listLocalCommands(channelID string) http.HandlerFunc {
 			return
 		}
 
-		res, err := json.Marshal(commands)
+		res, err := json.Marshal(newCommands(commands))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -141,110 +221,342 @@ func (s *Server) listLocalCommands(channelID string) http.HandlerFunc {
 
 func (s *Server) registerChannel() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
-
-		idstr, err := strconv.ParseInt(id, 10, 64)
+		user, err := s.channelUser(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), http.StatusBadGateway)
 			return
 		}
-		id = strconv.FormatInt(idstr, 10)
 
-		token := r.Header.Get("Authorization")
-		if len(token) == 0 {
-			http.Error(w, "Missing authorization header", http.StatusUnauthorized)
+		before := s.channelState(r.Context(), user.ID)
+		if err := s.q.CreateChannel(r.Context(), user.ID); nil != err {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		s.channelChanged(user.ID)
+		s.auditRequest(r, user.ID, AuditChannelRegistered, before, s.channelState(r.Context(), user.ID))
+		s.notify(user.ID, WebhookChannelJoined, ChannelEvent{Login: user.Login})
+
+		// Channels are joined when chat connects if the bot is not yet authorized
+		if chat := s.chat(); chat != nil {
+			s.JoinChannels([]string{user.Login}, []string{user.ID})
+			msg := "Hi " + user.DisplayName + " 👋"
+			go func() {
+				time.Sleep(time.Second * 2)
+				chat.Say(user.Login, msg)
+			}()
+		}
 
-		user, err := s.getUserFromToken(token)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (s *Server) unregisterChannel() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.channelUser(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), http.StatusBadGateway)
 			return
 		}
 
-		if user.ID != id {
-			http.Error(w, "Not authorized to register this channel", http.StatusForbidden)
+		before := s.channelState(r.Context(), user.ID)
+		if err := s.q.DeleteChannel(r.Context(), user.ID); nil != err {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.channelChanged(user.ID)
+		s.auditRequest(r, user.ID, AuditChannelUnregistered, before, nil)
+		if err := s.notifyLeft(r.Context(), user.ID, ChannelEvent{Login: user.Login}); nil != err {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		if err := s.q.CreateChannel(r.Context(), user.ID); nil != err {
+		if err := s.secrets.Delete(r.Context(), channelTokenSecret(user.ID)); nil != err {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := deleteIncomingWebhooks(r.Context(), s.q, user.ID); nil != err {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		s.JoinChannels([]string{user.Login}, []string{user.ID})
-		msg := "Hi " + user.DisplayName + " 👋"
-		go func() {
-			time.Sleep(time.Second * 2)
-			s.irc.Say(user.Login, msg)
-		}()
+		if chat := s.chat(); chat != nil {
+			go func() {
+				time.Sleep(2 * time.Second)
+				chat.Depart(user.Login)
+			}()
+
+			chat.Say(user.Login, "Bye "+user.DisplayName+"👋")
+		}
 
 		w.WriteHeader(http.StatusOK)
 	})
 }
 
-func (s *Server) unregisterChannel() http.HandlerFunc {
+func (s *Server) listCommands() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
+		id := requestChannel(r)
+
+		commands, err := s.q.GetCommandsByID(r.Context(), id)
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-		idstr, err := strconv.ParseInt(id, 10, 64)
+		res, err := json.Marshal(newCommands(commands))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		id = strconv.FormatInt(idstr, 10)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
+
+// listApprovals lists the users approved in a channel with +approve, or with
+// ?type=automatic those approved when they were found not to be bots. Pages are
+// ordered by user id, and linked to with ?after= the last id of a page and
+// ?before= the first, as Twitch may not return every user approved.
+func (s *Server) listApprovals() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestChannel(r)
 
-		token := r.Header.Get("Authorization")
-		if len(token) == 0 {
-			http.Error(w, "Missing authorization header", http.StatusUnauthorized)
+		manual := true
+		switch r.URL.Query().Get("type") {
+		case "", "manual":
+		case "automatic":
+			manual = false
+		default:
+			http.Error(w, "type must be manual or automatic", http.StatusBadRequest)
 			return
 		}
 
-		user, err := s.getUserFromToken(token)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		// Twitch returns at most 100 users at once
+		limit := int64(100)
+		if str := r.URL.Query().Get("limit"); str != "" {
+			var err error
+			limit, err = strconv.ParseInt(str, 10, 64)
+			if err != nil || limit < 1 || limit > 100 {
+				http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+		}
+
+		after, before := r.URL.Query().Get("after"), r.URL.Query().Get("before")
+		if after != "" && before != "" {
+			http.Error(w, "only one of after and before may be given", http.StatusBadRequest)
 			return
 		}
 
-		if user.ID != id {
-			http.Error(w, "Not authorized to unregister this channel", http.StatusForbidden)
+		var approvals []db.Approval
+		var err error
+		if before != "" {
+			approvals, err = s.q.GetApprovalsByTypeBefore(r.Context(), db.GetApprovalsByTypeBeforeParams{
+				ChannelID: id,
+				Manual:    manual,
+				UserID:    before,
+				Limit:     limit,
+			})
+			approvals = lo.Reverse(approvals)
+		} else {
+			approvals, err = s.q.GetApprovalsByType(r.Context(), db.GetApprovalsByTypeParams{
+				ChannelID: id,
+				Manual:    manual,
+				UserID:    after,
+				Limit:     limit,
+			})
+		}
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		if err := s.q.DeleteChannel(r.Context(), user.ID); nil != err {
+		links := []string{}
+		if len(approvals) > 0 {
+			full := int64(len(approvals)) == limit
+			if full || before != "" {
+				links = append(links, pageLink(r, "after", approvals[len(approvals)-1].UserID, "next"))
+			}
+			if after != "" || (full && before != "") {
+				links = append(links, pageLink(r, "before", approvals[0].UserID, "prev"))
+			}
+		}
+		if len(links) > 0 {
+			w.Header().Set("Link", strings.Join(links, ", "))
+		}
+
+		userIDs := []string{}
+		for _, a := range approvals {
+			userIDs = append(userIDs, a.UserID)
+		}
+
+		users := []helix.User{}
+		// Without ids Twitch would return the user of the token
+		if len(userIDs) > 0 {
+			users, err = Users(s.twitch, userIDs, []string{})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Twitch returns users in any order
+		byID := lo.KeyBy(users, func(u helix.User) string { return u.ID })
+		res, err := json.Marshal(lo.FilterMap(userIDs, func(id string, _ int) (TwitchUser, bool) {
+			u, ok := byID[id]
+			return newTwitchUser(u), ok
+		}))
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
+
+// pageLink returns a link to another page of a list, where the cursor is the
+// value of the parameter.
+func pageLink(r *http.Request, param, cursor, rel string) string {
+	u := *r.URL
+	query := u.Query()
+	query.Del("after")
+	query.Del("before")
+	query.Set(param, cursor)
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf("<%v>; rel=%q", u.String(), rel)
+}
+
+// approvalTarget returns the channel and the user of an approval request as
+// the data of a command, writing an error and returning false if either is
+// unknown.
+func (s *Server) approvalTarget(w http.ResponseWriter, r *http.Request) (Data, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "user"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return Data{}, false
+	}
+
+	channel, err := s.channelUser(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return Data{}, false
+	}
 
-		go func() {
-			time.Sleep(2 * time.Second)
-			s.irc.Depart(user.Login)
-		}()
+	user, err := User(s.twitch, strconv.FormatInt(userID, 10), "")
+	if err != nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return Data{}, false
+	}
+
+	return Data{
+		Channel:   channel.Login,
+		ChannelID: channel.ID,
+		User:      user.Login,
+		UserID:    user.ID,
+	}, true
+}
 
-		s.irc.Say(user.Login, "Bye "+user.DisplayName+"👋")
+// putApproval approves a user and unbans them, like +approve.
+func (s *Server) putApproval() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, ok := s.approvalTarget(w, r)
+		if !ok {
+			return
+		}
+
+		before := s.approvalState(r.Context(), d)
+		if err := s.approveUser(r.Context(), d); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, d.ChannelID, AuditApprovalGranted, before, s.approvalState(r.Context(), d))
 
 		w.WriteHeader(http.StatusOK)
 	})
 }
 
-func (s *Server) listCommands() http.HandlerFunc {
+// deleteApproval removes the approval of a user and bans them, like
+// +unapprove.
+func (s *Server) deleteApproval() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
-		// verify id is an int
-		idstr, err := strconv.ParseInt(id, 10, 64)
+		d, ok := s.approvalTarget(w, r)
+		if !ok {
+			return
+		}
+
+		approved, err := s.q.IsApproved(r.Context(), db.IsApprovedParams{ChannelID: d.ChannelID, UserID: d.UserID})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if approved == 0 {
+			http.Error(w, "user is not approved", http.StatusNotFound)
 			return
 		}
-		id = strconv.FormatInt(idstr, 10)
 
-		commands, err := s.q.GetCommandsByID(r.Context(), id)
+		before := s.approvalState(r.Context(), d)
+		if err := s.unapproveUser(r.Context(), d); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, d.ChannelID, AuditApprovalRevoked, before, nil)
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// Ban is a ban or timeout as returned by the API.
+type Ban struct {
+	ID        int64  `json:"id"`
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	// Seconds, or 0 for a ban
+	Duration  int64      `json:"duration"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+	LiftedAt  *time.Time `json:"lifted_at"`
+}
+
+func newBan(ban db.Ban) Ban {
+	return Ban{
+		ID:        ban.ID,
+		UserID:    ban.UserID,
+		UserLogin: ban.UserLogin,
+		Duration:  ban.Duration,
+		Reason:    ban.Reason,
+		CreatedAt: ban.CreatedAt,
+		LiftedAt:  nullTime(ban.LiftedAt),
+	}
+}
+
+// nullTime returns the time of a nullable column, or nil.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// listBans lists the most recent bans and timeouts the bot made in a channel.
+func (s *Server) listBans() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := int64(50)
+		if str := r.URL.Query().Get("limit"); str != "" {
+			var err error
+			limit, err = strconv.ParseInt(str, 10, 64)
+			if err != nil || limit < 1 || limit > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+		}
+
+		bans, err := s.q.GetBans(r.Context(), db.GetBansParams{ChannelID: requestChannel(r), Limit: limit})
 		if err != nil && err != sql.ErrNoRows {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		res, err := json.Marshal(commands)
+		res, err := json.Marshal(lo.Map(bans, func(ban db.Ban, _ int) Ban { return newBan(ban) }))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -254,35 +566,68 @@ func (s *Server) listCommands() http.HandlerFunc {
 	})
 }
 
-func (s *Server) listApprovals() http.HandlerFunc {
+// LoggedMessage is a message of the chat log as returned by the API.
+type LoggedMessage struct {
+	ID int64 `json:"id"`
+	// The id of the message on Twitch
+	MessageID       string          `json:"message_id"`
+	UserID          string          `json:"user_id"`
+	UserLogin       string          `json:"user_login"`
+	UserDisplayName string          `json:"user_display_name"`
+	Message         string          `json:"message"`
+	Tags            json.RawMessage `json:"tags"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+func newLoggedMessage(m db.Message) LoggedMessage {
+	tags := json.RawMessage(m.Tags)
+	if !json.Valid(tags) {
+		tags = json.RawMessage("{}")
+	}
+	return LoggedMessage{
+		ID:              m.ID,
+		MessageID:       m.MessageID,
+		UserID:          m.UserID,
+		UserLogin:       m.UserLogin,
+		UserDisplayName: m.UserDisplayName,
+		Message:         m.Message,
+		Tags:            tags,
+		CreatedAt:       m.CreatedAt,
+	}
+}
+
+func (s *Server) listMessages() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
-		// verify id is an int
-		idstr, err := strconv.ParseInt(id, 10, 64)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		id = strconv.FormatInt(idstr, 10)
+		id := requestChannel(r)
 
-		approvals, err := s.q.GetApprovals(r.Context(), id)
-		if err != nil && err != sql.ErrNoRows {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		username := strings.ToLower(r.URL.Query().Get("user"))
+		if username == "" {
+			http.Error(w, "user is required", http.StatusBadRequest)
 			return
 		}
 
-		channelIDs := []string{}
-		for _, a := range approvals {
-			channelIDs = append(channelIDs, a.UserID)
+		limit := int64(50)
+		if str := r.URL.Query().Get("limit"); str != "" {
+			var err error
+			limit, err = strconv.ParseInt(str, 10, 64)
+			if err != nil || limit < 1 || limit > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
 		}
 
-		resp, err := Users(s.twitch, channelIDs, []string{})
-		if err != nil {
+		messages, err := s.q.SearchUserMessages(r.Context(), db.SearchUserMessagesParams{
+			ChannelID: id,
+			UserLogin: username,
+			Message:   likeEscaper.Replace(r.URL.Query().Get("q")),
+			Limit:     limit,
+		})
+		if err != nil && err != sql.ErrNoRows {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		res, err := json.Marshal(resp)
+		res, err := json.Marshal(lo.Map(messages, func(m db.Message, _ int) LoggedMessage { return newLoggedMessage(m) }))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -293,19 +638,14 @@ func (s *Server) listApprovals() http.HandlerFunc {
 }
 
 func (s *Server) getUserFromToken(token string) (helix.User, error) {
-	client, err := helix.NewClient(&helix.Options{
-		ClientID:     s.env.twitchClientID,
-		ClientSecret: s.env.twitchClientSecret,
-	})
+	client, err := s.twitchForUser(token)
 	if err != nil {
 		return helix.User{}, errors.Wrap(err, "Unable to create twitch api client")
 	}
 
-	client.SetUserAccessToken(token)
-
 	resp, err := client.GetUsers(&helix.UsersParams{})
 	if err != nil {
-		fmt.Println("Unable to get user", err)
+		apiLog.Warn("unable to get user for token", "err", err)
 		return helix.User{}, err
 	}
 
@@ -316,72 +656,500 @@ func (s *Server) getUserFromToken(token string) (helix.User, error) {
 	return resp.Data.Users[0], nil
 }
 
-func (s *Server) patchChannel() http.HandlerFunc {
+// validateChannelSettings checks that the settings of a channel are in range.
+func validateChannelSettings(channel *db.Channel) error {
+	if channel.ReplySafety < 0 || channel.ReplySafety > 3 {
+		return errors.New("reply safety must be between 0 and 3")
+	}
+	if channel.AutoreplyFrequency < 1 || channel.AutoreplyFrequency > 5 {
+		return errors.New("autoreply frequency must be between 1 and 5")
+	}
+	if channel.ChatlogRetentionDays < 1 || channel.ChatlogRetentionDays > 365 {
+		return errors.New("chat log retention must be between 1 and 365 days")
+	}
+	return nil
+}
+
+// Limits on the size of commands set through the API.
+const (
+	maxCommandName        = 100
+	maxCommandTemplate    = 2000
+	maxCommandDescription = 300
+)
+
+// validateCommand checks that a command's name is a regular expression and its
+// template parses.
+func validateCommand(name, tmpl, description string) error {
+	switch {
+	case name == "":
+		return errors.New("name is required")
+	case len(name) > maxCommandName:
+		return fmt.Errorf("name must be at most %v bytes", maxCommandName)
+	case strings.ContainsAny(name, " \t\n"):
+		return errors.New("name must not contain whitespace")
+	case len(tmpl) > maxCommandTemplate:
+		return fmt.Errorf("template must be at most %v bytes", maxCommandTemplate)
+	case len(description) > maxCommandDescription:
+		return fmt.Errorf("description must be at most %v bytes", maxCommandDescription)
+	}
+	// Names are matched against messages as regular expressions
+	if _, err := regexp.Compile(name); err != nil {
+		return errors.Wrap(err, "invalid command name")
+	}
+	// The functions are only needed to parse, so they are never called
+	functions := (&Server{}).FuncMap(context.Background(), Data{}, nil)
+	if _, err := template.New(name).Funcs(functions).Parse(tmpl); err != nil {
+		return errors.Wrap(err, "invalid template")
+	}
+	return nil
+}
+
+// Command is a command as returned by the API.
+type Command struct {
+	Name        string `json:"name"`
+	Template    string `json:"template"`
+	Description string `json:"description"`
+	// The id of the user that last changed the command
+	UpdatedBy string     `json:"updated_by"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+func newCommand(command db.Command) Command {
+	return Command{
+		Name:        command.Name,
+		Template:    command.Template,
+		Description: command.Description,
+		UpdatedBy:   command.UpdatedBy,
+		UpdatedAt:   nullTime(command.UpdatedAt),
+	}
+}
+
+func newCommands(rows []db.GetCommandsByIDRow) []Command {
+	return lo.Map(rows, func(row db.GetCommandsByIDRow, _ int) Command {
+		return Command{
+			Name:        row.Name,
+			Template:    row.Template,
+			Description: row.Description,
+			UpdatedBy:   row.UpdatedBy,
+			UpdatedAt:   nullTime(row.UpdatedAt),
+		}
+	})
+}
+
+// CommandBody is the body of requests that set a command. Fields left out of
+// a PATCH keep their current values.
+type CommandBody struct {
+	Template    *string `json:"template"`
+	Description *string `json:"description"`
+}
+
+// commandKey returns the channel id and name of the command in the request,
+// where global commands are in channel 0. It writes an error and returns false
+// if the name is not escaped properly.
+func commandKey(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	id := requestChannel(r)
+	if id == "" {
+		id = "0"
+	}
+
+	name, err := url.PathUnescape(chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", "", false
+	}
+	return id, name, true
+}
+
+// writeCommand responds with a command and its metadata.
+func (s *Server) writeCommand(w http.ResponseWriter, r *http.Request, id, name string) {
+	command, err := s.q.GetCommandDetails(r.Context(), db.GetCommandDetailsParams{ChannelID: id, Name: name})
+	if err == sql.ErrNoRows {
+		http.Error(w, "command not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res, err := json.Marshal(newCommand(command))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(res)
+}
+
+func (s *Server) getCommand() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
+		id, name, ok := commandKey(w, r)
+		if !ok {
+			return
+		}
 
-		token := r.Header.Get("Authorization")
-		if len(token) == 0 {
-			http.Error(w, "Missing authorization header", http.StatusUnauthorized)
+		s.writeCommand(w, r, id, name)
+	})
+}
+
+// putCommand creates or replaces a command.
+func (s *Server) putCommand() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, name, ok := commandKey(w, r)
+		if !ok {
 			return
 		}
 
-		user, err := s.getUserFromToken(token)
-		if err != nil {
+		body := CommandBody{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if body.Template == nil {
+			http.Error(w, "template is required", http.StatusBadRequest)
+			return
+		}
 
-		if user.ID != id && user.ID != s.env.twitchOwnerID {
-			http.Error(w, "Not authorized to patch", http.StatusForbidden)
+		command := db.Command{ChannelID: id, Name: name, Template: *body.Template}
+		if body.Description != nil {
+			command.Description = *body.Description
+		}
+		s.saveCommand(w, r, command)
+	})
+}
+
+// patchCommand changes the template or description of a command.
+func (s *Server) patchCommand() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, name, ok := commandKey(w, r)
+		if !ok {
 			return
 		}
 
-		// verify id is an int
-		idstr, err := strconv.ParseInt(id, 10, 64)
-		if err != nil {
+		command, err := s.q.GetCommandDetails(r.Context(), db.GetCommandDetailsParams{ChannelID: id, Name: name})
+		if err == sql.ErrNoRows {
+			http.Error(w, "command not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body := CommandBody{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		id = strconv.FormatInt(idstr, 10)
+		if body.Template != nil {
+			command.Template = *body.Template
+		}
+		if body.Description != nil {
+			command.Description = *body.Description
+		}
+		s.saveCommand(w, r, command)
+	})
+}
+
+func (s *Server) saveCommand(w http.ResponseWriter, r *http.Request, command db.Command) {
+	user, _ := requestUser(r)
+	if err := validateCommand(command.Name, command.Template, command.Description); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	before := s.commandState(r.Context(), command.ChannelID, command.Name)
+	if err := s.q.SaveCommand(r.Context(), db.SaveCommandParams{
+		ChannelID:   command.ChannelID,
+		Name:        command.Name,
+		Template:    command.Template,
+		Description: command.Description,
+		UpdatedBy:   user.ID,
+		UpdatedAt:   sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.auditRequest(r, command.ChannelID, AuditCommandSet, before, s.commandState(r.Context(), command.ChannelID, command.Name))
+	s.notify(command.ChannelID, WebhookCommandChanged, CommandChangedEvent{
+		Name:        command.Name,
+		Action:      "set",
+		Template:    command.Template,
+		Description: command.Description,
+		UpdatedBy:   user.ID,
+	})
+
+	s.writeCommand(w, r, command.ChannelID, command.Name)
+}
+
+func (s *Server) deleteCommand() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, name, ok := commandKey(w, r)
+		if !ok {
+			return
+		}
+
+		command, err := s.q.GetCommandDetails(r.Context(), db.GetCommandDetailsParams{ChannelID: id, Name: name})
+		if err == sql.ErrNoRows {
+			http.Error(w, "command not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := s.q.DeleteCommand(r.Context(), db.DeleteCommandParams{ChannelID: id, Name: name}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, id, AuditCommandDeleted, newCommand(command), nil)
+		user, _ := requestUser(r)
+		s.notify(id, WebhookCommandChanged, CommandChangedEvent{Name: name, Action: "deleted", UpdatedBy: user.ID})
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
 
-		// parse Channel from request body
-		var channel db.Channel
-		err = json.NewDecoder(r.Body).Decode(&channel)
+// maxNumberName limits the names of numbers set through the API and +setnum.
+const maxNumberName = 100
+
+// validateNumberName checks the name of a number, as used by getnum and addnum.
+func validateNumberName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("name is required")
+	case len(name) > maxNumberName:
+		return fmt.Errorf("name must be at most %v bytes", maxNumberName)
+	case strings.ContainsAny(name, " \t\n"):
+		return errors.New("name must not contain whitespace")
+	}
+	return nil
+}
+
+// numberName returns the name of the number in the request, writing an error
+// and returning false if it is not escaped properly.
+func numberName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name, err := url.PathUnescape(chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// Number is a number as returned by the API.
+type Number struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+func newNumber(number db.Number) Number {
+	return Number{Name: number.Name, Value: number.Value}
+}
+
+func (s *Server) listNumbers() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		numbers, err := s.q.GetNumbers(r.Context(), requestChannel(r))
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res, err := json.Marshal(lo.Map(numbers, func(n db.Number, _ int) Number { return newNumber(n) }))
 		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
+
+// writeNumber responds with a number, which is 0 until it is set, as in
+// getnum.
+func (s *Server) writeNumber(w http.ResponseWriter, r *http.Request, name string) {
+	number, err := s.q.GetNumber(r.Context(), db.GetNumberParams{ChannelID: requestChannel(r), Name: name})
+	if err == sql.ErrNoRows {
+		number = db.Number{ChannelID: requestChannel(r), Name: name}
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res, err := json.Marshal(newNumber(number))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(res)
+}
+
+func (s *Server) getNumber() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := numberName(w, r)
+		if !ok {
+			return
+		}
+		s.writeNumber(w, r, name)
+	})
+}
+
+// NumberBody is the body of a request setting a number.
+type NumberBody struct {
+	Value *int64 `json:"value"`
+}
+
+func (s *Server) putNumber() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := numberName(w, r)
+		if !ok {
+			return
+		}
+		if err := validateNumberName(name); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		if channel.ReplySafety < 0 || channel.ReplySafety > 3 {
-			http.Error(w, "reply safety must be between 0 and 3", http.StatusBadRequest)
+		body := NumberBody{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body.Value == nil {
+			http.Error(w, "value is required", http.StatusBadRequest)
 			return
 		}
 
-		if channel.AutoreplyFrequency < 1 || channel.AutoreplyFrequency > 5 {
-			http.Error(w, "autoreply frequency must be between 1 and 5", http.StatusBadRequest)
+		before := s.numberState(r.Context(), requestChannel(r), name)
+		if err := s.q.SetNumber(r.Context(), db.SetNumberParams{
+			ChannelID: requestChannel(r),
+			Name:      name,
+			Value:     *body.Value,
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		s.auditRequest(r, requestChannel(r), AuditNumberSet, before, s.numberState(r.Context(), requestChannel(r), name))
+		s.publish(requestChannel(r), EventNumber, NumberEvent{Name: name, Value: *body.Value})
 
-		if channel.OpenaiToken.String != "" && len(channel.OpenaiToken.String) > 8 {
-			// check string matches regex "^sk-\w{48}$"
-			regex, err := regexp.Compile("^sk-\\w{48}$")
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
+		s.writeNumber(w, r, name)
+	})
+}
+
+// deleteNumber resets a number to 0.
+func (s *Server) deleteNumber() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := numberName(w, r)
+		if !ok {
+			return
+		}
+
+		number, err := s.q.GetNumber(r.Context(), db.GetNumberParams{ChannelID: requestChannel(r), Name: name})
+		if err == sql.ErrNoRows {
+			http.Error(w, "number not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := s.q.DeleteNumber(r.Context(), db.DeleteNumberParams{ChannelID: requestChannel(r), Name: name}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, requestChannel(r), AuditNumberDeleted, newNumber(number), nil)
+		s.publish(requestChannel(r), EventNumber, NumberEvent{Name: name})
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// Channel is the settings of a channel as returned by the API.
+type Channel struct {
+	ChannelID          string  `json:"channel_id"`
+	AutoreplyEnabled   bool    `json:"autoreply_enabled"`
+	AutoreplyFrequency float64 `json:"autoreply_frequency"`
+	ReplySafety        int64   `json:"reply_safety"`
+	// The token itself is kept in the secret store and never returned
+	HasOpenaiToken       bool  `json:"has_openai_token"`
+	ChatlogEnabled       bool  `json:"chatlog_enabled"`
+	ChatlogRetentionDays int64 `json:"chatlog_retention_days"`
+	CommandsPublic       bool  `json:"commands_public"`
+	Listed               bool  `json:"listed"`
+}
+
+// ChannelSettings is the body of a request changing the settings of a
+// channel. Settings left out keep their current values.
+type ChannelSettings struct {
+	AutoreplyEnabled     *bool    `json:"autoreply_enabled"`
+	AutoreplyFrequency   *float64 `json:"autoreply_frequency"`
+	ReplySafety          *int64   `json:"reply_safety"`
+	OpenaiToken          *string  `json:"openai_token"`
+	ChatlogEnabled       *bool    `json:"chatlog_enabled"`
+	ChatlogRetentionDays *int64   `json:"chatlog_retention_days"`
+	CommandsPublic       *bool    `json:"commands_public"`
+	Listed               *bool    `json:"listed"`
+}
+
+// apply changes the settings of a channel to those of the body.
+func (settings ChannelSettings) apply(channel *db.Channel) {
+	if settings.AutoreplyEnabled != nil {
+		channel.AutoreplyEnabled = *settings.AutoreplyEnabled
+	}
+	if settings.AutoreplyFrequency != nil {
+		channel.AutoreplyFrequency = *settings.AutoreplyFrequency
+	}
+	if settings.ReplySafety != nil {
+		channel.ReplySafety = *settings.ReplySafety
+	}
+	if settings.ChatlogEnabled != nil {
+		channel.ChatlogEnabled = *settings.ChatlogEnabled
+	}
+	if settings.ChatlogRetentionDays != nil {
+		channel.ChatlogRetentionDays = *settings.ChatlogRetentionDays
+	}
+	if settings.CommandsPublic != nil {
+		channel.CommandsPublic = *settings.CommandsPublic
+	}
+	if settings.Listed != nil {
+		channel.Listed = *settings.Listed
+	}
+}
+
+var openaiTokenPattern = regexp.MustCompile(`^sk-\w{48}$`)
+
+func (s *Server) patchChannel() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestChannel(r)
 
-			if !regex.MatchString(channel.OpenaiToken.String) {
+		channel, err := s.q.GetChannel(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "channel not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		before := s.channelState(r.Context(), id)
+
+		settings := ChannelSettings{}
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		settings.apply(&channel)
+
+		if err := validateChannelSettings(&channel); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if settings.OpenaiToken != nil {
+			if !openaiTokenPattern.MatchString(*settings.OpenaiToken) {
 				http.Error(w, "openai token invalid format", http.StatusBadRequest)
 				return
 			}
 
-			channel.OpenaiToken.Valid = true
-
-			err = s.q.UpdateChannelToken(r.Context(), db.UpdateChannelTokenParams{
-				ChannelID:   id,
-				OpenaiToken: channel.OpenaiToken,
-			})
+			err = s.secrets.Set(r.Context(), channelTokenSecret(id), *settings.OpenaiToken)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -389,42 +1157,62 @@ func (s *Server) patchChannel() http.HandlerFunc {
 		}
 
 		err = s.q.UpdateChannel(r.Context(), db.UpdateChannelParams{
-			ChannelID:          id,
-			AutoreplyEnabled:   channel.AutoreplyEnabled,
-			AutoreplyFrequency: channel.AutoreplyFrequency,
-			ReplySafety:        channel.ReplySafety,
+			ChannelID:            id,
+			AutoreplyEnabled:     channel.AutoreplyEnabled,
+			AutoreplyFrequency:   channel.AutoreplyFrequency,
+			ReplySafety:          channel.ReplySafety,
+			ChatlogEnabled:       channel.ChatlogEnabled,
+			ChatlogRetentionDays: channel.ChatlogRetentionDays,
+			CommandsPublic:       channel.CommandsPublic,
+			Listed:               channel.Listed,
 		})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		s.channelChanged(id)
+		s.auditRequest(r, id, AuditChannelUpdated, before, s.channelState(r.Context(), id))
 
 		s.getChannel()(w, r)
 	})
 }
 
+// loadChannel returns the settings of a channel as returned by the API.
+func (s *Server) loadChannel(ctx context.Context, id string) (Channel, error) {
+	channel, err := s.q.GetChannel(ctx, id)
+	if err != nil {
+		return Channel{}, err
+	}
+
+	hasToken, err := s.secrets.Exists(ctx, channelTokenSecret(id))
+	if err != nil {
+		return Channel{}, err
+	}
+
+	return Channel{
+		ChannelID:            channel.ChannelID,
+		AutoreplyEnabled:     channel.AutoreplyEnabled,
+		AutoreplyFrequency:   channel.AutoreplyFrequency,
+		ReplySafety:          channel.ReplySafety,
+		HasOpenaiToken:       hasToken,
+		ChatlogEnabled:       channel.ChatlogEnabled,
+		ChatlogRetentionDays: channel.ChatlogRetentionDays,
+		CommandsPublic:       channel.CommandsPublic,
+		Listed:               channel.Listed,
+	}, nil
+}
+
 func (s *Server) getChannel() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := chi.URLParam(r, "id")
-
-		// verify id is an int
-		idstr, err := strconv.ParseInt(id, 10, 64)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		channel, err := s.loadChannel(r.Context(), requestChannel(r))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "channel not found", http.StatusNotFound)
 			return
-		}
-		id = strconv.FormatInt(idstr, 10)
-
-		channel, err := s.q.GetChannel(r.Context(), id)
-		if err != nil {
+		} else if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		if channel.OpenaiToken.Valid {
-			channel.OpenaiToken.String = "******"
-		}
-
 		res, err := json.Marshal(channel)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -435,21 +1223,50 @@ func (s *Server) getChannel() http.HandlerFunc {
 	})
 }
 
+// TwitchUser is a Twitch user as returned by the API.
+type TwitchUser struct {
+	ID              string `json:"id"`
+	Login           string `json:"login"`
+	DisplayName     string `json:"display_name"`
+	ProfileImageURL string `json:"profile_image_url"`
+}
+
+func newTwitchUser(user helix.User) TwitchUser {
+	return TwitchUser{
+		ID:              user.ID,
+		Login:           user.Login,
+		DisplayName:     user.DisplayName,
+		ProfileImageURL: user.ProfileImageURL,
+	}
+}
+
+// listChannels lists the channels that chose to be listed, or every channel
+// for the owner.
 func (s *Server) listChannels() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		channels, err := s.q.GetChannels(r.Context())
+		var channels []string
+		var err error
+		if user, ok := requestUser(r); ok && user.ID == s.config.Twitch.OwnerID {
+			channels, err = s.q.GetChannels(r.Context())
+		} else {
+			channels, err = s.q.GetListedChannels(r.Context())
+		}
 		if err != nil && err != sql.ErrNoRows {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		resp, err := Users(s.twitch, channels, []string{})
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		users := []helix.User{}
+		// Without ids Twitch would return the user of the token
+		if len(channels) > 0 {
+			users, err = Users(s.twitch, channels, []string{})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
 		}
 
-		res, err := json.Marshal(resp)
+		res, err := json.Marshal(lo.Map(users, func(u helix.User, _ int) TwitchUser { return newTwitchUser(u) }))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/samber/lo"
+)
+
+// request sends a request to the API as the user the token belongs to, and
+// checks that the response matches the specification.
+func request(t *testing.T, s *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	}
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	routes := s.apiRouter()
+	routes.ServeHTTP(w, r)
+
+	doc, err := loadSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route, params, ok := specRoute(doc, routes, r); ok {
+		if err := openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{Request: r, PathParams: params, Route: route},
+			Status:                 w.Code,
+			Header:                 w.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+			Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+		}); err != nil {
+			t.Errorf("%v %v: response does not match the specification: %v", method, path, err)
+		}
+	}
+	return w
+}
+
+func TestRegisterChannel(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, chat := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+
+	if w := request(t, s, http.MethodPut, "/channels/2", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/3", "streamer-token", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for another channel, got %v", w.Code)
+	}
+
+	if w := request(t, s, http.MethodPut, "/channels/2", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if _, err := s.q.GetChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Errorf("expected the channel to be created: %v", err)
+	}
+	if len(chat.Joined) != 1 || chat.Joined[0] != testBroadcaster.Login {
+		t.Errorf("expected the channel to be joined, joined %v", chat.Joined)
+	}
+}
+
+func TestPatchChannel(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+
+	for _, method := range []string{http.MethodGet, http.MethodPatch} {
+		if w := request(t, s, method, "/channels/2", "streamer-token", `{"listed": true}`); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for %v of an unregistered channel, got %v", method, w.Code)
+		}
+	}
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	token := "sk-" + strings.Repeat("a", 48)
+	body := `{"autoreply_enabled": true, "openai_token": "` + token + `"}`
+
+	if w := request(t, s, http.MethodPatch, "/channels/2", "viewer-token", body); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer, got %v", w.Code)
+	}
+
+	w := request(t, s, http.MethodPatch, "/channels/2", "streamer-token", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+
+	channel := Channel{}
+	if err := json.Unmarshal(w.Body.Bytes(), &channel); err != nil {
+		t.Fatal(err)
+	}
+	if !channel.AutoreplyEnabled || channel.AutoreplyFrequency != 2 {
+		t.Errorf("expected only autoreply to change, got %+v", channel)
+	}
+	if !channel.HasOpenaiToken {
+		t.Errorf("expected the token to be set, got %+v", channel)
+	}
+
+	stored, err := s.secrets.Get(ctx, channelTokenSecret(testBroadcaster.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != token {
+		t.Errorf("expected the token to be stored, got %q", stored)
+	}
+}
+
+func TestCommandAPI(t *testing.T) {
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["owner-token"] = testOwner.ID
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+	if err := s.q.CreateChannel(context.Background(), testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(t, s, http.MethodPut, "/channels/2/commands/!hi", "viewer-token", `{"template": "hi"}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/commands/!hi", "streamer-token", `{"template": "hi"}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a global command, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/commands/!hi", "streamer-token", `{"template": "{{ .Nope"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a broken template, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/commands/(", "streamer-token", `{"template": "hi"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid name, got %v", w.Code)
+	}
+
+	w := request(t, s, http.MethodPut, "/channels/2/commands/!hi", "streamer-token", `{"template": "hi {{ user }}", "description": "greets"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+
+	w = request(t, s, http.MethodPatch, "/channels/2/commands/!hi", "streamer-token", `{"template": "hello"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	command := Command{}
+	if err := json.Unmarshal(w.Body.Bytes(), &command); err != nil {
+		t.Fatal(err)
+	}
+	if command.Template != "hello" || command.Description != "greets" || command.UpdatedBy != testBroadcaster.ID || command.UpdatedAt == nil {
+		t.Errorf("expected the template to change and keep the description, got %+v", command)
+	}
+
+	if w := request(t, s, http.MethodPatch, "/channels/2/commands/!bye", "streamer-token", `{"template": "bye"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 patching a missing command, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/commands/!hi", "owner-token", `{"template": "hi"}`); w.Code != http.StatusOK {
+		t.Errorf("expected the owner to set a global command, got %v: %v", w.Code, w.Body)
+	}
+
+	if w := request(t, s, http.MethodDelete, "/channels/2/commands/!hi", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodGet, "/channels/2/commands/!hi", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected the command to be deleted, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodDelete, "/channels/2/commands/!hi", "streamer-token", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a missing command, got %v", w.Code)
+	}
+}
+
+func TestApprovalAPI(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.q.Approve(ctx, db.ApproveParams{ChannelID: testBroadcaster.ID, UserID: testViewer.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(t, s, http.MethodPut, "/channels/2/approvals/4", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if actions := twitch.TakeActions(); len(actions) != 1 || actions[0].Action != "unban" || actions[0].UserID != testSpamBot.ID {
+		t.Errorf("expected the approved user to be unbanned, got %v", actions)
+	}
+
+	for query, login := range map[string]string{"": testSpamBot.Login, "?type=automatic": testViewer.Login} {
+		w := request(t, s, http.MethodGet, "/channels/2/approvals"+query, "streamer-token", "")
+		users := []TwitchUser{}
+		if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 1 || users[0].Login != login {
+			t.Errorf("expected %q to list %v, got %v", query, login, users)
+		}
+	}
+
+	if w := request(t, s, http.MethodDelete, "/channels/2/approvals/4", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if actions := twitch.TakeActions(); len(actions) != 1 || actions[0].Action != "ban" || actions[0].UserID != testSpamBot.ID {
+		t.Errorf("expected the unapproved user to be banned, got %v", actions)
+	}
+	if w := request(t, s, http.MethodDelete, "/channels/2/approvals/4", "streamer-token", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a user that is not approved, got %v", w.Code)
+	}
+
+	w := request(t, s, http.MethodGet, "/channels/2/bans", "streamer-token", "")
+	bans := []Ban{}
+	if err := json.Unmarshal(w.Body.Bytes(), &bans); err != nil {
+		t.Fatal(err)
+	}
+	if len(bans) != 1 || bans[0].UserLogin != testSpamBot.Login || bans[0].Reason != "bot unapproved" || bans[0].LiftedAt != nil {
+		t.Errorf("expected the ban to be recorded, got %+v", bans)
+	}
+
+	if w := request(t, s, http.MethodPut, "/channels/2/approvals/4", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	lifted, err := s.q.GetBans(ctx, db.GetBansParams{ChannelID: testBroadcaster.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lifted) != 1 || !lifted[0].LiftedAt.Valid {
+		t.Errorf("expected the ban to be lifted, got %+v", lifted)
+	}
+}
+
+func TestApprovalPages(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []helix.User{testSpamBot, testOwner, testViewer} {
+		if err := s.q.Approve(ctx, db.ApproveParams{ChannelID: testBroadcaster.ID, UserID: user.ID, Manual: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page := func(query string) ([]string, string) {
+		t.Helper()
+		w := request(t, s, http.MethodGet, "/channels/2/approvals"+query, "streamer-token", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %q, got %v: %v", query, w.Code, w.Body)
+		}
+		users := []TwitchUser{}
+		if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil {
+			t.Fatal(err)
+		}
+		return lo.Map(users, func(u TwitchUser, _ int) string { return u.ID }), w.Header().Get("Link")
+	}
+
+	if ids, link := page("?limit=2"); !slices.Equal(ids, []string{"1", "3"}) || link != `</channels/2/approvals?after=3&limit=2>; rel="next"` {
+		t.Errorf("expected the first page to link to the next, got %v %v", ids, link)
+	}
+	if ids, link := page("?limit=2&after=3"); !slices.Equal(ids, []string{"4"}) || link != `</channels/2/approvals?before=4&limit=2>; rel="prev"` {
+		t.Errorf("expected the last page to link to the previous, got %v %v", ids, link)
+	}
+	if ids, link := page("?limit=2&before=4"); !slices.Equal(ids, []string{"1", "3"}) || !strings.Contains(link, `after=3`) {
+		t.Errorf("expected the page before to be the first, got %v %v", ids, link)
+	}
+	if w := request(t, s, http.MethodGet, "/channels/2/approvals?after=1&before=4", "streamer-token", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for both cursors, got %v", w.Code)
+	}
+}
+
+func TestNumberAPI(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(t, s, http.MethodPut, "/channels/2/numbers/deaths", "viewer-token", `{"value": 3}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/numbers/deaths", "streamer-token", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a value, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/numbers/deaths", "streamer-token", `{"value": 3}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+
+	w := request(t, s, http.MethodGet, "/channels/2/numbers", "", "")
+	numbers := []Number{}
+	if err := json.Unmarshal(w.Body.Bytes(), &numbers); err != nil {
+		t.Fatal(err)
+	}
+	if len(numbers) != 1 || numbers[0].Name != "deaths" || numbers[0].Value != 3 {
+		t.Errorf("unexpected numbers %+v", numbers)
+	}
+
+	if w := request(t, s, http.MethodDelete, "/channels/2/numbers/deaths", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	w = request(t, s, http.MethodGet, "/channels/2/numbers/deaths", "", "")
+	number := Number{}
+	if err := json.Unmarshal(w.Body.Bytes(), &number); err != nil {
+		t.Fatal(err)
+	}
+	if number.Value != 0 {
+		t.Errorf("expected the number to be reset, got %+v", number)
+	}
+	if w := request(t, s, http.MethodDelete, "/channels/2/numbers/deaths", "streamer-token", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a number that is not set, got %v", w.Code)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/samber/lo"
+)
+
+// The changes of configuration recorded in the audit log.
+const (
+	AuditChannelRegistered      = "channel.registered"
+	AuditChannelUnregistered    = "channel.unregistered"
+	AuditChannelUpdated         = "channel.updated"
+	AuditCommandSet             = "command.set"
+	AuditCommandDeleted         = "command.deleted"
+	AuditApprovalGranted        = "approval.granted"
+	AuditApprovalRevoked        = "approval.revoked"
+	AuditNumberSet              = "number.set"
+	AuditNumberDeleted          = "number.deleted"
+	AuditGrantSet               = "grant.set"
+	AuditGrantDeleted           = "grant.deleted"
+	AuditWebhookCreated         = "webhook.created"
+	AuditWebhookDeleted         = "webhook.deleted"
+	AuditIncomingWebhookCreated = "incoming_webhook.created"
+	AuditIncomingWebhookDeleted = "incoming_webhook.deleted"
+)
+
+// AuditEntry is a change of configuration as returned by the API. Before and
+// after are what the API returns for what changed, null when it did not exist.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	ActorID    string          `json:"actor_id"`
+	ActorLogin string          `json:"actor_login"`
+	Action     string          `json:"action"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func newAuditEntry(entry db.AuditLog) AuditEntry {
+	state := func(s sql.NullString) json.RawMessage {
+		if !s.Valid {
+			return json.RawMessage("null")
+		}
+		return json.RawMessage(s.String)
+	}
+	return AuditEntry{
+		ID:         entry.ID,
+		ActorID:    entry.ActorID,
+		ActorLogin: entry.ActorLogin,
+		Action:     entry.Action,
+		Before:     state(entry.Before),
+		After:      state(entry.After),
+		CreatedAt:  entry.CreatedAt,
+	}
+}
+
+// audit records a change of configuration made by the actor in a channel,
+// where before and after are nil if what changed did not exist. The change
+// itself was already made, so failures are only logged.
+func (s *Server) audit(ctx context.Context, channelID, actorID, actorLogin, action string, before, after any) {
+	state := func(v any) sql.NullString {
+		if v == nil {
+			return sql.NullString{}
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			dbLog.Error("unable to encode audited state", "action", action, "err", err)
+			return sql.NullString{}
+		}
+		return sql.NullString{String: string(b), Valid: true}
+	}
+
+	if err := s.q.RecordAudit(ctx, db.RecordAuditParams{
+		ChannelID:  channelID,
+		ActorID:    actorID,
+		ActorLogin: actorLogin,
+		Action:     action,
+		Before:     state(before),
+		After:      state(after),
+		CreatedAt:  time.Now().UTC(),
+	}); err != nil {
+		dbLog.Error("unable to record audit log", "channel_id", channelID, "action", action, "err", err)
+	}
+}
+
+// auditRequest records a change made through the API by the user of the
+// request.
+func (s *Server) auditRequest(r *http.Request, channelID, action string, before, after any) {
+	user, _ := requestUser(r)
+	s.audit(r.Context(), channelID, user.ID, user.Login, action, before, after)
+}
+
+// channelState returns the settings of a channel to be audited, or nil if it
+// is not registered.
+func (s *Server) channelState(ctx context.Context, channelID string) any {
+	channel, err := s.loadChannel(ctx, channelID)
+	if err != nil {
+		return nil
+	}
+	return channel
+}
+
+// commandState returns a command to be audited, or nil if it is not set.
+func (s *Server) commandState(ctx context.Context, channelID, name string) any {
+	command, err := s.q.GetCommandDetails(ctx, db.GetCommandDetailsParams{ChannelID: channelID, Name: name})
+	if err != nil {
+		return nil
+	}
+	return newCommand(command)
+}
+
+// numberState returns a number to be audited, or nil if it is not set.
+func (s *Server) numberState(ctx context.Context, channelID, name string) any {
+	number, err := s.q.GetNumber(ctx, db.GetNumberParams{ChannelID: channelID, Name: name})
+	if err != nil {
+		return nil
+	}
+	return newNumber(number)
+}
+
+// approvalState returns the user of an approval to be audited, or nil if they
+// are not approved.
+func (s *Server) approvalState(ctx context.Context, d Data) any {
+	approved, err := s.q.IsApproved(ctx, db.IsApprovedParams{ChannelID: d.ChannelID, UserID: d.UserID})
+	if err != nil || approved == 0 {
+		return nil
+	}
+	return TwitchUser{ID: d.UserID, Login: d.User}
+}
+
+// listAudit lists the changes of configuration in a channel, newest first.
+// Older pages are listed with ?before= the smallest id of the last page.
+func (s *Server) listAudit() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := int64(50)
+		if str := r.URL.Query().Get("limit"); str != "" {
+			var err error
+			limit, err = strconv.ParseInt(str, 10, 64)
+			if err != nil || limit < 1 || limit > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+		}
+
+		before := int64(math.MaxInt64)
+		if str := r.URL.Query().Get("before"); str != "" {
+			var err error
+			before, err = strconv.ParseInt(str, 10, 64)
+			if err != nil || before < 1 {
+				http.Error(w, "before must be the id of an entry", http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries, err := s.q.GetAuditLog(r.Context(), db.GetAuditLogParams{
+			ChannelID: requestChannel(r),
+			ID:        before,
+			Limit:     limit,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res, err := json.Marshal(lo.Map(entries, func(e db.AuditLog, _ int) AuditEntry { return newAuditEntry(e) }))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
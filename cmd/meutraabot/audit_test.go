@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+
+	if w := request(t, s, http.MethodPut, "/channels/2", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodPatch, "/channels/2", "streamer-token", `{"listed": true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	s.handleCommand(ctx, chatMessage(testViewer, "+set ^!hi hello", map[string]string{"mod": "1"}))
+	if w := request(t, s, http.MethodDelete, "/channels/2/commands/^!hi", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	s.handleCommand(ctx, chatMessage(testBroadcaster, "+approve spambot", nil))
+	if w := request(t, s, http.MethodDelete, "/channels/2/approvals/4", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	s.handleCommand(ctx, chatMessage(testViewer, "+setnum deaths 3", map[string]string{"mod": "1"}))
+	if w := request(t, s, http.MethodPut, "/channels/2/numbers/deaths", "streamer-token", `{"value": 5}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodDelete, "/channels/2/numbers/deaths", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+
+	if w := request(t, s, http.MethodGet, "/channels/2/audit", "viewer-token", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer, got %v", w.Code)
+	}
+
+	list := func(query string) []AuditEntry {
+		t.Helper()
+		w := request(t, s, http.MethodGet, "/channels/2/audit"+query, "streamer-token", "")
+		entries := []AuditEntry{}
+		if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	entries := list("")
+	actions := []string{AuditNumberDeleted, AuditNumberSet, AuditNumberSet, AuditApprovalRevoked, AuditApprovalGranted, AuditCommandDeleted, AuditCommandSet, AuditChannelUpdated, AuditChannelRegistered}
+	if len(entries) != len(actions) {
+		t.Fatalf("expected %v entries, got %+v", len(actions), entries)
+	}
+	for i, action := range actions {
+		if entries[i].Action != action {
+			t.Errorf("expected entry %v to be %v, got %v", i, action, entries[i].Action)
+		}
+	}
+
+	number := func(state json.RawMessage) Number {
+		t.Helper()
+		n := Number{}
+		if err := json.Unmarshal(state, &n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if set := entries[2]; set.ActorID != testViewer.ID || string(set.Before) != "null" || number(set.After).Value != 3 {
+		t.Errorf("expected the number set in chat to be recorded, got %+v", set)
+	}
+	if set := entries[1]; set.ActorID != testBroadcaster.ID || number(set.Before).Value != 3 || number(set.After).Value != 5 {
+		t.Errorf("expected the number set through the API to be recorded, got %+v", set)
+	}
+	if deleted := entries[0]; number(deleted.Before).Value != 5 || string(deleted.After) != "null" {
+		t.Errorf("expected the number deleted through the API to be recorded, got %+v", deleted)
+	}
+
+	granted, approved := entries[4], TwitchUser{}
+	if err := json.Unmarshal(granted.After, &approved); err != nil {
+		t.Fatal(err)
+	}
+	if granted.ActorID != testBroadcaster.ID || string(granted.Before) != "null" || approved.ID != testSpamBot.ID {
+		t.Errorf("expected the approval in chat to be recorded, got %+v", granted)
+	}
+	if revoked := entries[3]; revoked.ActorLogin != testBroadcaster.Login || string(revoked.Before) == "null" || string(revoked.After) != "null" {
+		t.Errorf("expected the approval removed through the API to be recorded, got %+v", revoked)
+	}
+
+	set := entries[6]
+	after := Command{}
+	if err := json.Unmarshal(set.After, &after); err != nil {
+		t.Fatal(err)
+	}
+	if set.ActorID != testViewer.ID || string(set.Before) != "null" || after.Template != "hello" {
+		t.Errorf("expected the command set in chat to be recorded, got %+v", set)
+	}
+
+	updated := entries[7]
+	before, now := Channel{}, Channel{}
+	if err := json.Unmarshal(updated.Before, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(updated.After, &now); err != nil {
+		t.Fatal(err)
+	}
+	if updated.ActorLogin != testBroadcaster.Login || before.Listed || !now.Listed {
+		t.Errorf("expected the settings before and after to be recorded, got %+v", updated)
+	}
+
+	page := list("?limit=7")
+	next := list("?limit=2&before=" + strconv.FormatInt(page[6].ID, 10))
+	if len(page) != 7 || len(next) != 2 || next[0].Action != AuditChannelUpdated || next[1].Action != AuditChannelRegistered {
+		t.Errorf("expected the second page to have the older entries, got %+v", next)
+	}
+}
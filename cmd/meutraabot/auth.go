@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/samber/lo"
+)
+
+// Role is what a user may do with a channel through the API. Each role may do
+// everything the roles before it may.
+type Role int
+
+const (
+	RoleNone Role = iota
+	// Granted by the broadcaster, may manage commands and read the chat log
+	RoleModerator
+	// Granted by the broadcaster, may also change the channel's settings
+	RoleEditor
+	// The channel's own account, may also grant roles and unregister
+	RoleBroadcaster
+	// The bot owner, may do anything in any channel and change global commands
+	RoleOwner
+)
+
+var roleNames = map[Role]string{
+	RoleNone:        "none",
+	RoleModerator:   "moderator",
+	RoleEditor:      "editor",
+	RoleBroadcaster: "broadcaster",
+	RoleOwner:       "owner",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// parseGrantableRole parses the name of a role that a broadcaster may grant.
+func parseGrantableRole(name string) (Role, bool) {
+	switch name {
+	case RoleModerator.String():
+		return RoleModerator, true
+	case RoleEditor.String():
+		return RoleEditor, true
+	}
+	return RoleNone, false
+}
+
+// tokenUserTTL is how long a validated token is trusted before Twitch is asked
+// about it again.
+const tokenUserTTL = 5 * time.Minute
+
+// tokenUserCache remembers the users of access tokens, keyed by a hash of the
+// token. The zero value is ready to use.
+type tokenUserCache struct {
+	mu    sync.Mutex
+	users map[string]cachedTokenUser
+}
+
+type cachedTokenUser struct {
+	user    helix.User
+	expires time.Time
+}
+
+// hashToken returns the hex encoded SHA-256 of a token, so that tokens are
+// never kept themselves.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// userForToken returns the user of an access token, asking Twitch at most
+// once every tokenUserTTL.
+func (s *Server) userForToken(token string) (helix.User, error) {
+	key := hashToken(token)
+	now := time.Now()
+
+	c := &s.tokenUsers
+	c.mu.Lock()
+	cached, ok := c.users[key]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.user, nil
+	}
+
+	user, err := s.getUserFromToken(token)
+	if err != nil {
+		return helix.User{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.users == nil {
+		c.users = map[string]cachedTokenUser{}
+	}
+	for k, u := range c.users {
+		if now.After(u.expires) {
+			delete(c.users, k)
+		}
+	}
+	c.users[key] = cachedTokenUser{user: user, expires: now.Add(tokenUserTTL)}
+	return user, nil
+}
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	channelContextKey
+)
+
+// authenticate identifies the user of a request from its Authorization
+// header, a Twitch user access token with or without a Bearer prefix.
+// EventSource cannot set headers, so event streams may instead pass the token
+// as the access_token query parameter. Requests without a token continue
+// anonymously.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := requestToken(r)
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := s.userForToken(token)
+		if err != nil {
+			http.Error(w, "Invalid authorization token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	})
+}
+
+// requestToken returns the access token of a request, or an empty string if
+// it has none.
+func requestToken(r *http.Request) string {
+	token := strings.TrimSpace(r.Header.Get("Authorization"))
+	if token == "" && isEventStream(r) {
+		token = strings.TrimSpace(r.URL.Query().Get("access_token"))
+	}
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	return token
+}
+
+// requestUser returns the user authenticated for a request, if any.
+func requestUser(r *http.Request) (helix.User, bool) {
+	user, ok := r.Context().Value(userContextKey).(helix.User)
+	return user, ok
+}
+
+// channelParam checks that the {id} of the route is a channel id, and makes
+// it available to handlers through requestChannel.
+func channelParam(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx := context.WithValue(r.Context(), channelContextKey, strconv.FormatInt(id, 10))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestChannel returns the channel id of a request, or an empty string for
+// routes that are not about a channel.
+func requestChannel(r *http.Request) string {
+	id, _ := r.Context().Value(channelContextKey).(string)
+	return id
+}
+
+// roleFor returns the role of a user in a channel, or in no channel at all
+// when the id is empty.
+func (s *Server) roleFor(ctx context.Context, user helix.User, channelID string) (Role, error) {
+	switch {
+	case user.ID == s.config.Twitch.OwnerID:
+		return RoleOwner, nil
+	case channelID == "":
+		return RoleNone, nil
+	case user.ID == channelID:
+		return RoleBroadcaster, nil
+	}
+
+	grant, err := s.q.GetDashboardGrant(ctx, db.GetDashboardGrantParams{ChannelID: channelID, UserID: user.ID})
+	if err == sql.ErrNoRows {
+		return RoleNone, nil
+	} else if err != nil {
+		return RoleNone, err
+	}
+	role, _ := parseGrantableRole(grant.Role)
+	return role, nil
+}
+
+// requireRole responds with 401 to anonymous requests, and with 403 to users
+// without at least the given role in the request's channel.
+func (s *Server) requireRole(min Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := requestUser(r)
+			if !ok {
+				http.Error(w, "Missing authorization header", http.StatusUnauthorized)
+				return
+			}
+
+			role, err := s.roleFor(r.Context(), user, requestChannel(r))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if role < min {
+				http.Error(w, "Requires the "+min.String()+" role", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// channelUser returns the Twitch user of the request's channel.
+func (s *Server) channelUser(r *http.Request) (helix.User, error) {
+	if user, ok := requestUser(r); ok && user.ID == requestChannel(r) {
+		return user, nil
+	}
+	return User(s.twitch, requestChannel(r), "")
+}
+
+// ChannelAccess is a channel a user may manage and their role in it.
+type ChannelAccess struct {
+	ChannelID string `json:"channel_id"`
+	Role      string `json:"role"`
+}
+
+// Me is the authenticated user and the channels they may manage.
+type Me struct {
+	User     TwitchUser      `json:"user"`
+	Owner    bool            `json:"owner"`
+	Channels []ChannelAccess `json:"channels"`
+}
+
+func (s *Server) getMe() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := requestUser(r)
+		me := Me{User: newTwitchUser(user), Owner: user.ID == s.config.Twitch.OwnerID, Channels: []ChannelAccess{}}
+
+		if _, err := s.q.GetChannel(r.Context(), user.ID); err == nil {
+			me.Channels = append(me.Channels, ChannelAccess{ChannelID: user.ID, Role: RoleBroadcaster.String()})
+		} else if err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		grants, err := s.q.GetDashboardGrantsForUser(r.Context(), user.ID)
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, g := range grants {
+			me.Channels = append(me.Channels, ChannelAccess{ChannelID: g.ChannelID, Role: g.Role})
+		}
+
+		res, err := json.Marshal(me)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
+
+// Grant is a role granted in a channel as returned by the API.
+type Grant struct {
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	GrantedBy string    `json:"granted_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newGrant(grant db.DashboardGrant) Grant {
+	return Grant{
+		UserID:    grant.UserID,
+		Role:      grant.Role,
+		GrantedBy: grant.GrantedBy,
+		CreatedAt: grant.CreatedAt,
+	}
+}
+
+func (s *Server) listGrants() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grants, err := s.q.GetDashboardGrants(r.Context(), requestChannel(r))
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res, err := json.Marshal(lo.Map(grants, func(g db.DashboardGrant, _ int) Grant { return newGrant(g) }))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
+
+// GrantBody is the body of a request granting a role.
+type GrantBody struct {
+	Role string `json:"role"`
+}
+
+// grantUser returns the user id of the grant in the request, writing an
+// error and returning false if it is not a user id.
+func grantUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "user"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return strconv.FormatInt(id, 10), true
+}
+
+// putGrant gives a user the moderator or editor role in the channel.
+func (s *Server) putGrant() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := grantUser(w, r)
+		if !ok {
+			return
+		}
+		if userID == requestChannel(r) {
+			http.Error(w, "The broadcaster can not be granted a role", http.StatusBadRequest)
+			return
+		}
+
+		body := GrantBody{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := parseGrantableRole(body.Role); !ok {
+			http.Error(w, "role must be "+RoleModerator.String()+" or "+RoleEditor.String(), http.StatusBadRequest)
+			return
+		}
+
+		var before any
+		if existing, err := s.q.GetDashboardGrant(r.Context(), db.GetDashboardGrantParams{ChannelID: requestChannel(r), UserID: userID}); err == nil {
+			before = newGrant(existing)
+		} else if err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		granter, _ := requestUser(r)
+		grant := db.DashboardGrant{
+			ChannelID: requestChannel(r),
+			UserID:    userID,
+			Role:      body.Role,
+			GrantedBy: granter.ID,
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := s.q.SetDashboardGrant(r.Context(), db.SetDashboardGrantParams(grant)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, grant.ChannelID, AuditGrantSet, before, newGrant(grant))
+
+		res, err := json.Marshal(newGrant(grant))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
+
+func (s *Server) deleteGrant() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := grantUser(w, r)
+		if !ok {
+			return
+		}
+
+		params := db.GetDashboardGrantParams{ChannelID: requestChannel(r), UserID: userID}
+		grant, err := s.q.GetDashboardGrant(r.Context(), params)
+		if err == sql.ErrNoRows {
+			http.Error(w, "grant not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := s.q.DeleteDashboardGrant(r.Context(), db.DeleteDashboardGrantParams(params)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, grant.ChannelID, AuditGrantDeleted, newGrant(grant), nil)
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// requireVisibleCommands lets anyone read the commands and numbers of
+// channels that made their commands public, and only moderators those of
+// other channels.
+func (s *Server) requireVisibleCommands(next http.Handler) http.Handler {
+	moderators := s.requireRole(RoleModerator)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		channel, err := s.q.GetChannel(r.Context(), requestChannel(r))
+		switch {
+		case err == nil && channel.CommandsPublic:
+			next.ServeHTTP(w, r)
+		case err == nil || err == sql.ErrNoRows:
+			moderators.ServeHTTP(w, r)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardGrants(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	command := `{"template": "hi"}`
+	settings := `{"autoreply_enabled": true}`
+
+	if w := request(t, s, http.MethodPut, "/channels/2/commands/!hi", "", command); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/commands/!hi", "bad-token", command); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an invalid token, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/commands/!hi", "viewer-token", command); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without a grant, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/grants/3", "viewer-token", `{"role": "editor"}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 granting a role in another channel, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/grants/3", "streamer-token", `{"role": "owner"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 granting the owner role, got %v", w.Code)
+	}
+
+	if w := request(t, s, http.MethodPut, "/channels/2/grants/3", "Bearer streamer-token", `{"role": "moderator"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodPut, "/channels/2/commands/!hi", "viewer-token", command); w.Code != http.StatusOK {
+		t.Errorf("expected a moderator to set commands, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodPatch, "/channels/2", "viewer-token", settings); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a moderator changing settings, got %v", w.Code)
+	}
+
+	if w := request(t, s, http.MethodPut, "/channels/2/grants/3", "streamer-token", `{"role": "editor"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodPatch, "/channels/2", "viewer-token", settings); w.Code != http.StatusOK {
+		t.Errorf("expected an editor to change settings, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodDelete, "/channels/2", "viewer-token", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for an editor unregistering, got %v", w.Code)
+	}
+
+	w := request(t, s, http.MethodGet, "/me", "viewer-token", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	me := Me{}
+	if err := json.Unmarshal(w.Body.Bytes(), &me); err != nil {
+		t.Fatal(err)
+	}
+	if me.User.ID != testViewer.ID || len(me.Channels) != 1 || me.Channels[0] != (ChannelAccess{ChannelID: "2", Role: "editor"}) {
+		t.Errorf("unexpected /me %+v", me)
+	}
+
+	if w := request(t, s, http.MethodDelete, "/channels/2/grants/3", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodDelete, "/channels/2/commands/!hi", "viewer-token", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 once the grant is removed, got %v", w.Code)
+	}
+}
+
+func TestTokenUserCache(t *testing.T) {
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["viewer-token"] = testViewer.ID
+
+	if w := request(t, s, http.MethodGet, "/me", "viewer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	// Twitch is not asked again while the token is cached
+	delete(twitch.Tokens, "viewer-token")
+	if w := request(t, s, http.MethodGet, "/me", "viewer-token", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the cached user, got %v", w.Code)
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["owner-token"] = testOwner.ID
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(t, s, http.MethodGet, "/channels/2", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected settings to need a token, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodGet, "/channels/2/approvals", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected approvals to need a token, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodGet, "/channels/2/commands", "", ""); w.Code != http.StatusOK {
+		t.Errorf("expected public commands, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodGet, "/channels", "", ""); w.Body.String() != "[]" {
+		t.Errorf("expected no listed channels, got %v", w.Body)
+	}
+	if w := request(t, s, http.MethodGet, "/channels", "owner-token", ""); !strings.Contains(w.Body.String(), testBroadcaster.Login) {
+		t.Errorf("expected the owner to see every channel, got %v", w.Body)
+	}
+
+	body := `{"commands_public": false, "listed": true}`
+	if w := request(t, s, http.MethodPatch, "/channels/2", "streamer-token", body); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodGet, "/channels/2/commands", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected private commands to need a token, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodGet, "/channels/2/commands", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the broadcaster to see private commands, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodGet, "/channels", "", ""); !strings.Contains(w.Body.String(), testBroadcaster.Login) {
+		t.Errorf("expected the channel to be listed, got %v", w.Body)
+	}
+}
+
+func TestLimitAnonymous(t *testing.T) {
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["viewer-token"] = testViewer.ID
+	s.config.API.AnonymousRateLimit = 2
+	router := s.apiRouter()
+
+	get := func(token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/commands", nil)
+		if token != "" {
+			r.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := get(""); code != http.StatusOK {
+			t.Fatalf("expected 200, got %v", code)
+		}
+	}
+	if code := get(""); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 over the limit, got %v", code)
+	}
+	if code := get("viewer-token"); code != http.StatusOK {
+		t.Errorf("expected requests with a token not to be limited, got %v", code)
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	limiter := newRateLimiter(1, time.Minute)
+	proxy := &APIConfig{Mode: ListenTLS, TrustedProxies: []string{"10.0.0.0/8"}}
+	handler := realIP(proxy.TrustsProxy)(limitAnonymous(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	get := func(peer, forwarded string) int {
+		r := httptest.NewRequest(http.MethodGet, "/commands", nil)
+		r.RemoteAddr = peer
+		r.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Clients can not escape the limit by claiming another address
+	if code := get("203.0.113.1:1234", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %v", code)
+	}
+	if code := get("203.0.113.1:1234", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("expected forwarding headers of clients to be ignored, got %v", code)
+	}
+
+	// The clients of a trusted proxy are limited on their own
+	if code := get("10.0.0.1:1234", "198.51.100.3"); code != http.StatusOK {
+		t.Errorf("expected 200, got %v", code)
+	}
+	if code := get("10.0.0.1:1234", "198.51.100.4"); code != http.StatusOK {
+		t.Errorf("expected the clients of a trusted proxy to be limited apart, got %v", code)
+	}
+
+	for _, c := range []struct {
+		config  APIConfig
+		addr    string
+		trusted bool
+	}{
+		{APIConfig{Mode: ListenHTTP}, "203.0.113.1", true},
+		{APIConfig{Mode: ListenAutocert}, "203.0.113.1", false},
+		{APIConfig{Mode: ListenHTTP, TrustedProxies: []string{"127.0.0.1"}}, "203.0.113.1", false},
+		{APIConfig{Mode: ListenHTTP, TrustedProxies: []string{"127.0.0.1"}}, "127.0.0.1", true},
+		{APIConfig{Mode: ListenTLS, TrustedProxies: []string{"fd00::/8"}}, "fd00::1", true},
+	} {
+		if trusted := c.config.TrustsProxy(netip.MustParseAddr(c.addr)); trusted != c.trusted {
+			t.Errorf("expected %v to be trusted: %v with %+v, got %v", c.addr, c.trusted, c.config, trusted)
+		}
+	}
+}
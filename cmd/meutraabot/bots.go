@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/json"
 	"io/ioutil"
-	l "log"
 	"net/http"
 	"strconv"
 )
@@ -39,20 +38,20 @@ func getBotList() ([]Bot, error) {
 	for _, bot := range bots.Bots {
 		username, ok := bot[0].(string)
 		if !ok {
-			l.Println("unable to cast bot name")
+			twitchLog.Warn("unable to cast bot name", "bot", bot)
 			continue
 		}
 
 		userIDVal, ok := bot[2].(float64)
 		if !ok {
-			l.Println("unable to cast bot id")
+			twitchLog.Warn("unable to cast bot id", "bot", bot)
 			continue
 		}
 		userID := strconv.Itoa(int(userIDVal))
 
 		channelCount, ok := bot[1].(float64)
 		if !ok {
-			l.Println("unable to cast bot channel count")
+			twitchLog.Warn("unable to cast bot channel count", "bot", bot)
 			continue
 		}
 
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	irc "github.com/gempir/go-twitch-irc/v3"
+	"github.com/meutraa/meutraabot/pkg/db"
+)
+
+// The number of logged messages loaded back into memory when a channel is
+// first seen after a restart.
+const historySeedSize = 50
+
+// chatlogTTL is how long whether a channel logs chat is remembered, so that
+// changes made with the administration commands are seen by a running bot.
+const chatlogTTL = time.Minute
+
+// chatlogCache remembers whether channels log chat, so that their settings
+// are not read for every message. The zero value is ready to use.
+type chatlogCache struct {
+	mu       sync.Mutex
+	channels map[string]cachedChatlog
+}
+
+type cachedChatlog struct {
+	enabled bool
+	expires time.Time
+}
+
+// chatlogEnabled reports whether a channel logs chat, reading its settings at
+// most once every chatlogTTL. Unregistered channels do not.
+func (s *Server) chatlogEnabled(ctx context.Context, channelID string) (bool, error) {
+	now := time.Now()
+
+	c := &s.chatlogs
+	c.mu.Lock()
+	cached, ok := c.channels[channelID]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.enabled, nil
+	}
+
+	settings, err := s.q.GetChannel(ctx, channelID)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.channels == nil {
+		c.channels = map[string]cachedChatlog{}
+	}
+	for k, l := range c.channels {
+		if now.After(l.expires) {
+			delete(c.channels, k)
+		}
+	}
+	c.channels[channelID] = cachedChatlog{enabled: settings.ChatlogEnabled, expires: now.Add(chatlogTTL)}
+	return settings.ChatlogEnabled, nil
+}
+
+// channelChanged forgets what is remembered of a channel's settings, after it
+// was registered, updated or unregistered.
+func (s *Server) channelChanged(channelID string) {
+	s.chatlogs.mu.Lock()
+	defer s.chatlogs.mu.Unlock()
+	delete(s.chatlogs.channels, channelID)
+}
+
+// logMessage persists a chat message for channels that have opted in to the
+// chat log.
+func (s *Server) logMessage(ctx context.Context, channelID string, m *irc.PrivateMessage) {
+	enabled, err := s.chatlogEnabled(ctx, channelID)
+	if err != nil {
+		messageLog(dbLog, m).Error("unable to get channel settings", "err", err)
+		return
+	}
+
+	if !enabled {
+		return
+	}
+
+	tags, err := json.Marshal(m.Tags)
+	if err != nil {
+		messageLog(ircLog, m).Error("unable to marshal message tags", "err", err)
+		return
+	}
+
+	createdAt := m.Time
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	if err := s.q.InsertMessage(ctx, db.InsertMessageParams{
+		MessageID:       m.ID,
+		ChannelID:       channelID,
+		UserID:          m.User.ID,
+		UserLogin:       m.User.Name,
+		UserDisplayName: m.User.DisplayName,
+		Message:         m.Message,
+		Tags:            string(tags),
+		CreatedAt:       createdAt.UTC(),
+	}); err != nil {
+		messageLog(dbLog, m).Error("unable to log message", "err", err)
+	}
+}
+
+// loadHistory returns the most recent logged messages for a channel, oldest
+// first, so the in memory history survives a restart.
+func (s *Server) loadHistory(ctx context.Context, channel, channelID string) []*irc.PrivateMessage {
+	messages, err := s.q.GetRecentMessages(ctx, db.GetRecentMessagesParams{
+		ChannelID: channelID,
+		Limit:     historySeedSize,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		dbLog.Error("unable to load chat log", "channel", channel, "err", err)
+		return []*irc.PrivateMessage{}
+	}
+
+	history := make([]*irc.PrivateMessage, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		tags := map[string]string{}
+		if err := json.Unmarshal([]byte(m.Tags), &tags); err != nil {
+			ircLog.Error("unable to unmarshal message tags", "channel", channel, "user", m.UserLogin, "err", err)
+		}
+		history = append(history, &irc.PrivateMessage{
+			ID:      m.MessageID,
+			Channel: channel,
+			RoomID:  m.ChannelID,
+			Message: m.Message,
+			Tags:    tags,
+			Time:    m.CreatedAt,
+			User: irc.User{
+				ID:          m.UserID,
+				Name:        m.UserLogin,
+				DisplayName: m.UserDisplayName,
+			},
+		})
+	}
+	return history
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, so that searches of the
+// chat log match text as it is written.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// funcHistory formats a user's recent logged messages for chat.
+func (s *Server) funcHistory(ctx context.Context, d Data, username, search string) string {
+	messages, err := s.q.SearchUserMessages(ctx, db.SearchUserMessagesParams{
+		ChannelID: d.ChannelID,
+		UserLogin: username,
+		Message:   likeEscaper.Replace(search),
+		Limit:     5,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		d.log(dbLog).Error("unable to search chat log", "err", err)
+		return "unable to search chat log"
+	}
+
+	if len(messages) == 0 {
+		return "no logged messages for " + username
+	}
+
+	str := ""
+	for i, m := range messages {
+		if i > 0 {
+			str += " | "
+		}
+		str += fmt.Sprintf("[%v] %v", m.CreatedAt.Format("Jan 2 15:04"), m.Message)
+	}
+	return str
+}
+
+// pruneMessages deletes logged messages older than each channel's retention
+// period.
+func (s *Server) pruneMessages() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	channels, err := s.q.GetChannels(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		dbLog.Error("unable to get channels", "err", err)
+		return
+	}
+
+	for _, channelID := range channels {
+		settings, err := s.q.GetChannel(ctx, channelID)
+		if err != nil {
+			dbLog.Error("unable to get channel settings", "channel_id", channelID, "err", err)
+			continue
+		}
+
+		retention := time.Duration(settings.ChatlogRetentionDays) * 24 * time.Hour
+		if err := s.q.DeleteMessagesBefore(ctx, db.DeleteMessagesBeforeParams{
+			ChannelID: channelID,
+			CreatedAt: time.Now().UTC().Add(-retention),
+		}); err != nil {
+			dbLog.Error("unable to prune chat log", "channel_id", channelID, "err", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+)
+
+func TestChatLogSetting(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	if w := request(t, s, http.MethodPut, "/channels/2", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+
+	logged := func() int {
+		t.Helper()
+		messages, err := s.q.GetRecentMessages(ctx, db.GetRecentMessagesParams{ChannelID: testBroadcaster.ID, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(messages)
+	}
+
+	first := chatMessage(testViewer, "hello", nil)
+	first.ID = "first"
+	s.logMessage(ctx, testBroadcaster.ID, first)
+	if n := logged(); n != 0 {
+		t.Fatalf("expected no messages logged without opting in, got %v", n)
+	}
+
+	// The setting is remembered until the channel is updated
+	if w := request(t, s, http.MethodPatch, "/channels/2", "streamer-token", `{"chatlog_enabled": true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	second := chatMessage(testViewer, "hello again", nil)
+	second.ID = "second"
+	s.logMessage(ctx, testBroadcaster.ID, second)
+	if n := logged(); n != 1 {
+		t.Errorf("expected the message to be logged once opted in, got %v", n)
+	}
+}
+
+func TestChatLogSearchWildcards(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestServer(t)
+
+	for i, text := range []string{"50% off", "500 off", "snake_case", "snakeXcase"} {
+		if err := s.q.InsertMessage(ctx, db.InsertMessageParams{
+			MessageID: strconv.Itoa(i),
+			ChannelID: testBroadcaster.ID,
+			UserID:    testViewer.ID,
+			UserLogin: testViewer.Login,
+			Message:   text,
+			Tags:      "{}",
+			CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := Data{ChannelID: testBroadcaster.ID}
+	for search, expected := range map[string]string{"0%": "50% off", "e_c": "snake_case"} {
+		res := s.funcHistory(ctx, d, testViewer.Login, search)
+		if !strings.Contains(res, expected) || strings.Contains(res, " | ") {
+			t.Errorf("expected %q to only find %q, got %q", search, expected, res)
+		}
+	}
+}
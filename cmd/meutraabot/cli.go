@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `usage: %v [flags] [command]
+
+Runs the bot when no command is given.
+
+commands:
+  migrate [status|up]  report or apply database migrations
+  config check         validate and print the configuration
+  secrets generate-key print a new key for secrets.key
+  secrets rotate       re-encrypt secrets with the current key
+  replay TRANSCRIPT    replay a chat transcript against fake backends
+  console              chat with the bot from a terminal
+  channels             list, add or remove channels
+  commands             list, set, unset or export a channel's commands
+  approvals            list, add or remove a channel's approved users
+  settings             get or set a channel's settings
+
+flags:
+`, os.Args[0])
+	flag.PrintDefaults()
+}
+
+// runCommand runs a subcommand instead of the bot.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "migrate":
+		return runMigrate(args)
+	case "config":
+		return runConfig(args)
+	case "secrets":
+		return runSecrets(args)
+	case "replay":
+		return runReplay(args)
+	case "console":
+		return runConsole(args)
+	case "channels":
+		return runChannels(args)
+	case "commands":
+		return runCommands(args)
+	case "approvals":
+		return runApprovals(args)
+	case "settings":
+		return runSettings(args)
+	}
+	return fmt.Errorf("unknown command %v", name)
+}
+
+// runConfig validates the configuration and prints it with secrets redacted.
+func runConfig(args []string) error {
+	if len(args) != 1 || args[0] != "check" {
+		return fmt.Errorf("usage: config check")
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(config.Redacted())
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	fmt.Println("configuration is valid")
+	return nil
+}
+
+// runMigrate reports the status of every migration, applying pending ones
+// unless called as "migrate status".
+func runMigrate(args []string) error {
+	apply := true
+	if len(args) > 0 {
+		switch args[0] {
+		case "status":
+			apply = false
+		case "up":
+		default:
+			return fmt.Errorf("usage: migrate [status|up]")
+		}
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := config.ValidateDatabase(); err != nil {
+		return err
+	}
+
+	s := Server{config: config}
+	if err := s.OpenDatabase(); nil != err {
+		return err
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if apply {
+		if _, err := db.Migrate(ctx, s.config.Database.Engine, s.conn); err != nil {
+			return errors.Wrap(err, "unable to migrate database")
+		}
+	}
+
+	statuses, err := db.Status(ctx, s.config.Database.Engine, s.conn)
+	if err != nil {
+		return errors.Wrap(err, "unable to get migration status")
+	}
+
+	for _, status := range statuses {
+		if status.Applied {
+			fmt.Printf("%v\tapplied %v\n", status.Name, status.AppliedAt.Format(time.RFC3339))
+		} else {
+			fmt.Printf("%v\tpending\n", status.Name)
+		}
+	}
+	return nil
+}
+
+// runSecrets generates secret keys and rotates stored secrets to the current
+// key. Rotation also happens whenever the bot starts.
+func runSecrets(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: secrets [generate-key|rotate]")
+	}
+
+	switch args[0] {
+	case "generate-key":
+		key, err := generateSecretKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(key)
+		return nil
+	case "rotate":
+	default:
+		return fmt.Errorf("usage: secrets [generate-key|rotate]")
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := config.ValidateDatabase(); err != nil {
+		return err
+	}
+
+	s := Server{config: config}
+	if err := s.PrepareDatabase(); nil != err {
+		return err
+	}
+	defer s.Close()
+
+	if err := s.PrepareSecrets(); nil != err {
+		return err
+	}
+	fmt.Println("secrets are encrypted with key", s.secrets.current.id)
+	return nil
+}
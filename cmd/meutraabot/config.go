@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// The directory holding the sqlite database and certificates
+	DataDir string `yaml:"data_dir"`
+	// The name the bot uses for itself in AI prompts
+	Persona  string         `yaml:"persona"`
+	Twitch   TwitchConfig   `yaml:"twitch"`
+	Database DatabaseConfig `yaml:"database"`
+	API      APIConfig      `yaml:"api"`
+	Secrets  SecretsConfig  `yaml:"secrets"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+type TwitchConfig struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"`
+	OwnerID      string `yaml:"owner_id"`
+	// How the bot account is authorized, AuthFlowCode or AuthFlowDevice
+	AuthFlow string `yaml:"auth_flow"`
+}
+
+type SecretsConfig struct {
+	// The base64 encoded 32 byte key secrets are encrypted with
+	Key string `yaml:"key"`
+	// Keys that secrets may still be encrypted with, which are re-encrypted
+	// with Key on startup
+	PreviousKeys []string `yaml:"previous_keys"`
+}
+
+type DatabaseConfig struct {
+	Engine db.Engine `yaml:"engine"`
+	// Defaults to db.sql in the data directory for sqlite
+	URL string `yaml:"url"`
+}
+
+type APIConfig struct {
+	// How the API server listens, one of ListenAutocert, ListenTLS or ListenHTTP
+	Mode string `yaml:"mode"`
+	// Defaults to :https, or :http in ListenHTTP mode
+	Addr string `yaml:"addr"`
+	// The certificate and key used in ListenTLS mode
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// How requests reach the API and OAuth handlers, RoutingHost or RoutingPath
+	Routing     string   `yaml:"routing"`
+	Host        string   `yaml:"host"`
+	OAuthHost   string   `yaml:"oauth_host"`
+	CORSOrigins []string `yaml:"cors_origins"`
+	// Defaults to certs in the data directory
+	CertDir string `yaml:"cert_dir"`
+	// Requests a minute allowed from each address without a token, 0 for no limit
+	AnonymousRateLimit int `yaml:"anonymous_rate_limit"`
+	// Addresses or CIDR ranges of reverse proxies whose X-Real-IP and
+	// X-Forwarded-For headers give the address of the client. When empty,
+	// every peer is trusted in ListenHTTP mode and none otherwise.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// The address Prometheus metrics are served on, without TLS, or none if
+	// empty
+	MetricsAddr string `yaml:"metrics_addr"`
+}
+
+const (
+	// Fetch certificates for the API and OAuth hosts from Let's Encrypt
+	ListenAutocert = "autocert"
+	// Serve TLS with the configured certificate and key files
+	ListenTLS = "tls"
+	// Serve plain HTTP, for use behind a reverse proxy
+	ListenHTTP = "http"
+
+	// Route by host name, API.Host to the API and API.OAuthHost to OAuth
+	RoutingHost = "host"
+	// Route by path, /api to the API and /oauth to OAuth, on any host
+	RoutingPath = "path"
+)
+
+func defaultConfig() *Config {
+	return &Config{
+		DataDir: ".",
+		Persona: "meuua",
+		Twitch: TwitchConfig{
+			AuthFlow: AuthFlowCode,
+		},
+		Database: DatabaseConfig{
+			Engine: db.SQLite,
+		},
+		API: APIConfig{
+			Mode:               ListenAutocert,
+			Routing:            RoutingHost,
+			Host:               "api.meuua.com",
+			OAuthHost:          "oauth.meuua.com",
+			CORSOrigins:        []string{"https://meuua.com"},
+			AnonymousRateLimit: 60,
+		},
+		Log: LogConfig{
+			Format: LogText,
+			Level:  "info",
+		},
+	}
+}
+
+// Flags that override the configuration file and environment.
+var (
+	configFlag         = flag.String("config", "", "path to the configuration file (env MEUTRAABOT_CONFIG)")
+	dataDirFlag        = flag.String("data-dir", "", "directory for the database and certificates")
+	personaFlag        = flag.String("persona", "", "name the bot uses for itself in AI prompts")
+	databaseEngineFlag = flag.String("database-engine", "", "database engine, sqlite or postgres")
+	databaseURLFlag    = flag.String("database-url", "", "database connection string")
+	apiModeFlag        = flag.String("api-mode", "", "how the API listens, autocert, tls or http")
+	apiAddrFlag        = flag.String("api-addr", "", "address the API listens on")
+	apiRoutingFlag     = flag.String("api-routing", "", "route the API and OAuth by host or path")
+	apiHostFlag        = flag.String("api-host", "", "host name serving the API")
+	oauthHostFlag      = flag.String("oauth-host", "", "host name serving the OAuth redirect")
+	metricsAddrFlag    = flag.String("metrics-addr", "", "address Prometheus metrics are served on")
+	logFormatFlag      = flag.String("log-format", "", "log format, text or json")
+	logLevelFlag       = flag.String("log-level", "", "log level, debug, info, warn or error")
+)
+
+// LoadConfig builds the configuration from the defaults, the configuration
+// file, environment variables and flags, with each overriding the last.
+// Flags must already be parsed.
+func LoadConfig() (*Config, error) {
+	c := defaultConfig()
+
+	path := os.Getenv("MEUTRAABOT_CONFIG")
+	if *configFlag != "" {
+		path = *configFlag
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read config file")
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil {
+			return nil, errors.Wrap(err, "unable to parse config file "+path)
+		}
+	}
+
+	override := func(value *string, values ...string) {
+		for _, v := range values {
+			if v != "" {
+				*value = v
+			}
+		}
+	}
+
+	override(&c.DataDir, os.Getenv("MEUTRAABOT_DATA_DIR"), *dataDirFlag)
+	override(&c.Persona, os.Getenv("MEUTRAABOT_PERSONA"), *personaFlag)
+	override(&c.Twitch.ClientID, os.Getenv("TWITCH_CLIENT_ID"))
+	override(&c.Twitch.ClientSecret, os.Getenv("TWITCH_CLIENT_SECRET"))
+	override(&c.Twitch.RedirectURL, os.Getenv("TWITCH_REDIRECT_URL"))
+	override(&c.Twitch.OwnerID, os.Getenv("TWITCH_OWNER_ID"))
+	override(&c.Twitch.AuthFlow, os.Getenv("TWITCH_AUTH_FLOW"))
+	override((*string)(&c.Database.Engine), os.Getenv("DATABASE_ENGINE"), *databaseEngineFlag)
+	override(&c.Database.URL, os.Getenv("DATABASE_URL"), *databaseURLFlag)
+	override(&c.API.Mode, os.Getenv("MEUTRAABOT_API_MODE"), *apiModeFlag)
+	override(&c.API.Addr, os.Getenv("MEUTRAABOT_API_ADDR"), *apiAddrFlag)
+	override(&c.API.CertFile, os.Getenv("MEUTRAABOT_API_CERT_FILE"))
+	override(&c.API.KeyFile, os.Getenv("MEUTRAABOT_API_KEY_FILE"))
+	override(&c.API.Routing, os.Getenv("MEUTRAABOT_API_ROUTING"), *apiRoutingFlag)
+	override(&c.API.Host, os.Getenv("MEUTRAABOT_API_HOST"), *apiHostFlag)
+	override(&c.API.OAuthHost, os.Getenv("MEUTRAABOT_OAUTH_HOST"), *oauthHostFlag)
+	override(&c.API.CertDir, os.Getenv("MEUTRAABOT_CERT_DIR"))
+	override(&c.API.MetricsAddr, os.Getenv("MEUTRAABOT_METRICS_ADDR"), *metricsAddrFlag)
+	if limit := os.Getenv("MEUTRAABOT_API_ANONYMOUS_RATE_LIMIT"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid MEUTRAABOT_API_ANONYMOUS_RATE_LIMIT")
+		}
+		c.API.AnonymousRateLimit = n
+	}
+	if proxies := os.Getenv("MEUTRAABOT_TRUSTED_PROXIES"); proxies != "" {
+		c.API.TrustedProxies = strings.Split(proxies, ",")
+	}
+	if origins := os.Getenv("MEUTRAABOT_CORS_ORIGINS"); origins != "" {
+		c.API.CORSOrigins = strings.Split(origins, ",")
+	}
+	override(&c.Secrets.Key, os.Getenv("MEUTRAABOT_SECRET_KEY"))
+	if keys := os.Getenv("MEUTRAABOT_PREVIOUS_SECRET_KEYS"); keys != "" {
+		c.Secrets.PreviousKeys = strings.Split(keys, ",")
+	}
+
+	override(&c.Log.Format, os.Getenv("MEUTRAABOT_LOG_FORMAT"), *logFormatFlag)
+	override(&c.Log.Level, os.Getenv("MEUTRAABOT_LOG_LEVEL"), *logLevelFlag)
+	if levels := os.Getenv("MEUTRAABOT_LOG_LEVELS"); levels != "" {
+		if c.Log.Levels == nil {
+			c.Log.Levels = map[string]string{}
+		}
+		for name, level := range parseLogLevels(levels) {
+			c.Log.Levels[name] = level
+		}
+	}
+
+	if c.Database.URL == "" && c.Database.Engine == db.SQLite {
+		c.Database.URL = "file:" + c.Path("db.sql") + "?mode=rwc"
+	}
+	if c.API.Addr == "" {
+		c.API.Addr = ":https"
+		if c.API.Mode == ListenHTTP {
+			c.API.Addr = ":http"
+		}
+	}
+	if c.API.CertDir == "" {
+		c.API.CertDir = c.Path("certs")
+	}
+
+	return c, nil
+}
+
+// Path returns the location of a file in the data directory.
+func (c *Config) Path(name string) string {
+	return filepath.Join(c.DataDir, name)
+}
+
+// ValidateDatabase checks only the settings needed to open the database.
+func (c *Config) ValidateDatabase() error {
+	if c.Database.Engine != db.SQLite && c.Database.Engine != db.Postgres {
+		return fmt.Errorf("database.engine must be sqlite or postgres, not %q", c.Database.Engine)
+	}
+	if c.Database.URL == "" {
+		return errors.New("database.url is required")
+	}
+	return nil
+}
+
+// Validate checks that the configuration is complete enough to run the bot,
+// reporting every problem found.
+func (c *Config) Validate() error {
+	problems := []string{}
+	if err := c.ValidateDatabase(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"data_dir", c.DataDir},
+		{"persona", c.Persona},
+		{"twitch.client_id", c.Twitch.ClientID},
+		{"twitch.client_secret", c.Twitch.ClientSecret},
+		{"twitch.owner_id", c.Twitch.OwnerID},
+		{"api.addr", c.API.Addr},
+	} {
+		if field.value == "" {
+			problems = append(problems, field.name+" is required")
+		}
+	}
+
+	if info, err := os.Stat(c.DataDir); err != nil {
+		problems = append(problems, "data_dir: "+err.Error())
+	} else if !info.IsDir() {
+		problems = append(problems, "data_dir is not a directory")
+	}
+
+	switch c.Twitch.AuthFlow {
+	case AuthFlowCode:
+		// The device flow does not redirect
+		if c.Twitch.RedirectURL == "" {
+			problems = append(problems, "twitch.redirect_url is required")
+		}
+	case AuthFlowDevice:
+	default:
+		problems = append(problems, fmt.Sprintf("twitch.auth_flow must be %v or %v", AuthFlowCode, AuthFlowDevice))
+	}
+
+	switch c.API.Mode {
+	case ListenAutocert, ListenTLS, ListenHTTP:
+	default:
+		problems = append(problems, fmt.Sprintf("api.mode must be %v, %v or %v", ListenAutocert, ListenTLS, ListenHTTP))
+	}
+
+	if c.API.Mode == ListenTLS {
+		for _, file := range []struct{ name, path string }{
+			{"api.cert_file", c.API.CertFile},
+			{"api.key_file", c.API.KeyFile},
+		} {
+			if file.path == "" {
+				problems = append(problems, file.name+" is required in tls mode")
+			} else if _, err := os.Stat(file.path); err != nil {
+				problems = append(problems, file.name+": "+err.Error())
+			}
+		}
+	}
+
+	switch c.API.Routing {
+	case RoutingHost, RoutingPath:
+	default:
+		problems = append(problems, fmt.Sprintf("api.routing must be %v or %v", RoutingHost, RoutingPath))
+	}
+
+	// Autocert needs host names to request certificates for
+	if c.API.Routing == RoutingHost || c.API.Mode == ListenAutocert {
+		if c.API.Host == "" {
+			problems = append(problems, "api.host is required")
+		}
+		if c.API.OAuthHost == "" {
+			problems = append(problems, "api.oauth_host is required")
+		}
+	}
+
+	if c.Secrets.Key == "" {
+		problems = append(problems, "secrets.key is required, generate one with: meutraabot secrets generate-key")
+	} else if _, err := parseSecretKey(c.Secrets.Key); err != nil {
+		problems = append(problems, "secrets.key: "+err.Error())
+	}
+	for _, key := range c.Secrets.PreviousKeys {
+		if _, err := parseSecretKey(key); err != nil {
+			problems = append(problems, "secrets.previous_keys: "+err.Error())
+		}
+	}
+
+	if c.Twitch.RedirectURL != "" {
+		if u, err := url.Parse(c.Twitch.RedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, "twitch.redirect_url must be an absolute url")
+		}
+	}
+
+	if c.API.MetricsAddr != "" && c.API.MetricsAddr == c.API.Addr {
+		problems = append(problems, "api.metrics_addr must not be api.addr")
+	}
+
+	if c.API.AnonymousRateLimit < 0 {
+		problems = append(problems, "api.anonymous_rate_limit must not be negative")
+	}
+
+	for _, proxy := range c.API.TrustedProxies {
+		if _, err := parseProxy(proxy); err != nil {
+			problems = append(problems, fmt.Sprintf("api.trusted_proxies: %q is not an address or CIDR range", proxy))
+		}
+	}
+
+	for _, origin := range c.API.CORSOrigins {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("api.cors_origins: %q is not an origin", origin))
+		}
+	}
+	problems = append(problems, c.Log.validate()...)
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// TrustsProxy reports whether requests from an address may give the address
+// of the client they were forwarded for.
+func (c *APIConfig) TrustsProxy(addr netip.Addr) bool {
+	if len(c.TrustedProxies) == 0 {
+		return c.Mode == ListenHTTP
+	}
+	for _, proxy := range c.TrustedProxies {
+		if prefix, err := parseProxy(proxy); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxy parses a trusted proxy, an address or a CIDR range.
+func parseProxy(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		return netip.ParsePrefix(proxy)
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Redacted returns a copy of the configuration that is safe to print.
+func (c *Config) Redacted() Config {
+	r := *c
+	if r.Twitch.ClientSecret != "" {
+		r.Twitch.ClientSecret = "******"
+	}
+	if r.Secrets.Key != "" {
+		r.Secrets.Key = "******"
+	}
+	r.Secrets.PreviousKeys = lo.Map(r.Secrets.PreviousKeys, func(string, int) string { return "******" })
+	r.Database.URL = redactDatabaseURL(r.Database.URL)
+	return r
+}
+
+// The password of a keyword/value connection string, quoted or not.
+var dsnPassword = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|\S*)`)
+
+// redactDatabaseURL masks the password of a database connection string,
+// given either as a URL or as keyword/value pairs. URLs that do not parse are
+// masked entirely.
+func redactDatabaseURL(dsn string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsnPassword.ReplaceAllString(dsn, "${1}******")
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "******"
+	}
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", "xxxxx")
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
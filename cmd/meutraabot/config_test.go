@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestRedactDatabaseURL(t *testing.T) {
+	for dsn, expected := range map[string]string{
+		"file:db.sql?mode=rwc":                                    "file:db.sql?mode=rwc",
+		"postgres://bot:hunter2@db/meutraabot?sslmode=disable":    "postgres://bot:xxxxx@db/meutraabot?sslmode=disable",
+		"postgresql://bot@db/meutraabot?password=hunter2":         "postgresql://bot@db/meutraabot?password=xxxxx",
+		"host=db user=bot password=hunter2 dbname=meutraabot":     "host=db user=bot password=****** dbname=meutraabot",
+		"host=db PASSWORD = 'hunter 2\\' here' dbname=meutraabot": "host=db PASSWORD = ****** dbname=meutraabot",
+		"postgres://bot:hunter2@db:port/meutraabot":               "******",
+	} {
+		if redacted := redactDatabaseURL(dsn); redacted != expected {
+			t.Errorf("expected %q to be redacted as %q, got %q", dsn, expected, redacted)
+		}
+	}
+
+	c := defaultConfig()
+	c.Database.URL = "host=db user=bot password=hunter2"
+	if r := c.Redacted(); r.Database.URL != "host=db user=bot password=******" || c.Database.URL != "host=db user=bot password=hunter2" {
+		t.Errorf("expected a redacted copy, got %q", r.Database.URL)
+	}
+}
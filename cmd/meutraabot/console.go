@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	irc "github.com/gempir/go-twitch-irc/v3"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/pkg/errors"
+)
+
+const consoleHelp = `Lines are sent to the channel as the current user, except for these commands:
+  /user NAME [ID]     speak as another user
+  /channel NAME [ID]  speak in another channel
+  /mod, /sub, /owner  toggle a role of the current user
+  /join NAME          have a user join the channel
+  /whoami             show the current user, channel and roles
+  /help               show this help
+  /quit               exit the console
+`
+
+// console is the state of an interactive session.
+type console struct {
+	s      *Server
+	twitch *FakeTwitch
+	chat   *FakeChat
+	out    io.Writer
+
+	channel   string
+	channelID string
+	user      string
+	userID    string
+	mod       bool
+	sub       bool
+	owner     bool
+	sent      int
+}
+
+// userIDFor returns the id of a user, adding them to the fake Twitch API with
+// the given id, or the next free one, if they are unknown.
+func (c *console) userIDFor(login, id string) string {
+	for _, u := range c.twitch.Users {
+		if u.Login == login {
+			return u.ID
+		}
+	}
+	if id == "" {
+		id = strconv.Itoa(1000 + len(c.twitch.Users))
+	}
+	c.twitch.Users = append(c.twitch.Users, helix.User{ID: id, Login: login, DisplayName: login})
+	return id
+}
+
+func (c *console) whoami() {
+	roles := []string{}
+	if c.owner {
+		roles = append(roles, "owner")
+	}
+	if c.user == c.channel {
+		roles = append(roles, "broadcaster")
+	}
+	if c.mod {
+		roles = append(roles, "mod")
+	}
+	if c.sub {
+		roles = append(roles, "sub")
+	}
+	if len(roles) == 0 {
+		roles = append(roles, "viewer")
+	}
+	fmt.Fprintf(c.out, "%v (%v) in #%v (%v) as %v\n", c.user, c.userID, c.channel, c.channelID, strings.Join(roles, ", "))
+}
+
+// entry returns a message sent by the current user.
+func (c *console) entry(message string) TranscriptEntry {
+	c.sent++
+	userID := c.userID
+	if c.owner {
+		userID = c.s.config.Twitch.OwnerID
+	}
+	tags := map[string]string{}
+	if c.mod {
+		tags["mod"] = "1"
+	}
+	if c.sub {
+		tags["subscriber"] = "1"
+	}
+	return TranscriptEntry{
+		Channel:   c.channel,
+		ChannelID: c.channelID,
+		User:      c.user,
+		UserID:    userID,
+		Tags:      tags,
+		Message:   message,
+		ID:        "console-" + strconv.Itoa(c.sent),
+	}
+}
+
+// handle runs a line of input, returning false when the console should exit.
+func (c *console) handle(line string) bool {
+	if !strings.HasPrefix(line, "/") {
+		c.s.feed(c.entry(line))
+		writeEffects(c.out, c.twitch, c.chat)
+		return true
+	}
+
+	args := strings.Fields(line)
+	switch {
+	case args[0] == "/quit":
+		return false
+	case args[0] == "/user" && (len(args) == 2 || len(args) == 3):
+		c.user = strings.ToLower(args[1])
+		c.userID = c.userIDFor(c.user, strings.Join(args[2:], ""))
+		c.mod, c.sub, c.owner = false, false, false
+		c.whoami()
+	case args[0] == "/channel" && (len(args) == 2 || len(args) == 3):
+		c.channel = strings.ToLower(args[1])
+		c.channelID = c.userIDFor(c.channel, strings.Join(args[2:], ""))
+		c.whoami()
+	case args[0] == "/join" && len(args) == 2:
+		login := strings.ToLower(args[1])
+		c.userIDFor(login, "")
+		c.s.feed(TranscriptEntry{Channel: c.channel, User: login, Join: true})
+		writeEffects(c.out, c.twitch, c.chat)
+	case args[0] == "/mod":
+		c.mod = !c.mod
+		c.whoami()
+	case args[0] == "/sub":
+		c.sub = !c.sub
+		c.whoami()
+	case args[0] == "/owner":
+		c.owner = !c.owner
+		c.whoami()
+	case args[0] == "/whoami":
+		c.whoami()
+	default:
+		fmt.Fprint(c.out, consoleHelp)
+	}
+	return true
+}
+
+// runConsole reads messages from stdin and prints what the bot would send
+// and do in response, using the configured database and a fake Twitch API.
+func runConsole(args []string) error {
+	fs := flag.NewFlagSet("console", flag.ContinueOnError)
+	channel := fs.String("channel", "console", "name of the channel")
+	channelID := fs.String("channel-id", "", "id of the channel, to use its commands and settings")
+	user := fs.String("user", "viewer", "name of the user to speak as")
+	userID := fs.String("user-id", "", "id of the user")
+	mod := fs.Bool("mod", false, "speak as a moderator")
+	sub := fs.Bool("sub", false, "speak as a subscriber")
+	owner := fs.Bool("owner", false, "speak as the bot owner")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: console [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := config.ValidateDatabase(); err != nil {
+		return err
+	}
+	if err := SetupLogging(os.Stderr, config.Log); err != nil {
+		return err
+	}
+	if config.Secrets.Key == "" {
+		// Stored secrets can not be read, but the console still works
+		if config.Secrets.Key, err = generateSecretKey(); err != nil {
+			return err
+		}
+	}
+
+	s := &Server{config: config}
+	if err := s.PrepareDatabase(); err != nil {
+		return err
+	}
+	defer s.Close()
+	if s.secrets, err = NewSecretStore(s.q, config.Secrets); err != nil {
+		return errors.Wrap(err, "unable to prepare secrets")
+	}
+
+	twitch := NewFakeTwitch(replayBot)
+	chat := &FakeChat{}
+	s.twitch = twitch
+	s.twitchForUser = twitch.ForUser
+	s.knownBots = func() ([]Bot, error) { return nil, nil }
+	s.irc = chat
+	s.client = &http.Client{}
+	s.selfLogin = replayBot.Login
+	s.selfID = replayBot.ID
+	s.history = map[string][]*irc.PrivateMessage{}
+	s.conversations = map[string][]*irc.PrivateMessage{}
+	s.noDelay = true
+
+	c := &console{s: s, twitch: twitch, chat: chat, out: os.Stdout, mod: *mod, sub: *sub, owner: *owner}
+	c.channel = strings.ToLower(*channel)
+	c.channelID = c.userIDFor(c.channel, *channelID)
+	c.user = strings.ToLower(*user)
+	c.userID = c.userIDFor(c.user, *userID)
+
+	c.whoami()
+	fmt.Fprintln(c.out, "type /help for commands")
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(c.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(c.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !c.handle(line) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConsole(t *testing.T) {
+	s, twitch, chat := newTestServer(t)
+	s.noDelay = true
+	out := &strings.Builder{}
+	c := &console{s: s, twitch: twitch, chat: chat, out: out}
+	c.channel, c.channelID = testBroadcaster.Login, testBroadcaster.ID
+	c.user, c.userID = testViewer.Login, testViewer.ID
+
+	for _, line := range []string{"+set ^!hi hi", "/mod", "+set ^!hi hi {{.User}}", "!hi", "/user spambot", "!hi"} {
+		if !c.handle(line) {
+			t.Fatalf("expected %q not to exit", line)
+		}
+	}
+	if c.handle("/quit") {
+		t.Error("expected /quit to exit")
+	}
+
+	expected := `viewer (3) in #streamer (2) as mod
+< #streamer: command ^!hi set
+< #streamer: hi viewer
+spambot (4) in #streamer (2) as viewer
+< #streamer: hi spambot
+`
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n%v", out)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	irc "github.com/gempir/go-twitch-irc/v3"
+)
+
+// The types of events streamed to dashboards and overlays.
+const (
+	EventMessage    = "message"
+	EventCommand    = "command"
+	EventModeration = "moderation"
+	EventNumber     = "number"
+)
+
+// Event is something the bot saw or did in a channel.
+type Event struct {
+	Type      string    `json:"type"`
+	ChannelID string    `json:"channel_id"`
+	Time      time.Time `json:"time"`
+	Data      any       `json:"data"`
+}
+
+// MessageEvent is a chat message, including the bot's own.
+type MessageEvent struct {
+	ID          string `json:"id,omitempty"`
+	UserID      string `json:"user_id"`
+	User        string `json:"user"`
+	DisplayName string `json:"display_name"`
+	Text        string `json:"text"`
+}
+
+// CommandEvent is a stored command that a message triggered, and its output.
+type CommandEvent struct {
+	Name   string `json:"name"`
+	UserID string `json:"user_id"`
+	User   string `json:"user"`
+	Output string `json:"output"`
+}
+
+// ModerationEvent is a ban, timeout, unban or deletion made by the bot.
+type ModerationEvent struct {
+	Action    string `json:"action"`
+	UserID    string `json:"user_id,omitempty"`
+	User      string `json:"user,omitempty"`
+	Duration  int    `json:"duration,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+}
+
+// NumberEvent is the new value of a number.
+type NumberEvent struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+// The number of events buffered for each subscriber, after which events are
+// dropped rather than holding up chat.
+const eventBufferSize = 64
+
+// How often a comment is sent on idle streams, so that proxies keep them open,
+// and access to the stream is checked again.
+var eventKeepAlive = 30 * time.Second
+
+// eventHub fans the events of each channel out to its subscribers. The zero
+// value is ready to use.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]struct{}
+}
+
+// subscribe returns the events of a channel until the returned function is
+// called.
+func (h *eventHub) subscribe(channelID string) (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs == nil {
+		h.subs = map[string]map[chan Event]struct{}{}
+	}
+	if h.subs[channelID] == nil {
+		h.subs[channelID] = map[chan Event]struct{}{}
+	}
+	h.subs[channelID][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[channelID], ch)
+		if len(h.subs[channelID]) == 0 {
+			delete(h.subs, channelID)
+		}
+	}
+}
+
+// watched returns whether a channel has any subscribers.
+func (h *eventHub) watched(channelID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[channelID]) > 0
+}
+
+// publish sends an event to the subscribers of its channel, skipping those
+// whose buffers are full.
+func (h *eventHub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[e.ChannelID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// publish sends an event about a channel to its subscribers.
+func (s *Server) publish(channelID, eventType string, data any) {
+	s.events.publish(Event{
+		Type:      eventType,
+		ChannelID: channelID,
+		Time:      time.Now().UTC(),
+		Data:      data,
+	})
+}
+
+// publishMessage sends a chat message to the subscribers of its channel.
+func (s *Server) publishMessage(channelID string, m *irc.PrivateMessage) {
+	s.publish(channelID, EventMessage, MessageEvent{
+		ID:          m.ID,
+		UserID:      m.User.ID,
+		User:        m.User.Name,
+		DisplayName: m.User.DisplayName,
+		Text:        m.Message,
+	})
+}
+
+// isEventStream returns whether a request is for a stream of server-sent
+// events, as made by EventSource.
+func isEventStream(r *http.Request) bool {
+	return r.Header.Get("Accept") == "text/event-stream"
+}
+
+// unlessEventStream applies a middleware to every request except event
+// streams, which stay open for as long as the client wants.
+func unlessEventStream(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// streamEvents streams the events of a channel as server-sent events, each
+// named by its type with the event as JSON data. The stream is closed once
+// its user is no longer a moderator of the channel.
+func (s *Server) streamEvents() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		events, unsubscribe := s.events.subscribe(requestChannel(r))
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if !s.canStream(r) {
+					return
+				}
+				fmt.Fprint(w, ": keepalive\n\n")
+			case e := <-events:
+				data, err := json.Marshal(e)
+				if err != nil {
+					apiLog.Error("unable to marshal event", "type", e.Type, "err", err)
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			}
+			flusher.Flush()
+		}
+	})
+}
+
+// canStream reports whether the user of an event stream may still read it,
+// as their token may have been revoked or their grant taken away since it was
+// opened. Tokens are checked with Twitch again once their cached user expires.
+func (s *Server) canStream(r *http.Request) bool {
+	user, err := s.userForToken(requestToken(r))
+	if err != nil {
+		return false
+	}
+	role, err := s.roleFor(r.Context(), user, requestChannel(r))
+	if err != nil {
+		apiLog.Error("unable to check the role of an event stream", "user_id", user.ID, "err", err)
+		return false
+	}
+	return role >= RoleModerator
+}
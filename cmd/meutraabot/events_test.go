@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStream(t *testing.T) {
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+
+	// Cleanups run last first, so the streams are closed before the server
+	server := httptest.NewServer(s.apiRouter())
+	t.Cleanup(server.Close)
+
+	stream := func(token string) *http.Response {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/channels/2/events?access_token="+token, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept", "text/event-stream")
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	if res := stream("viewer-token"); res.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer, got %v", res.StatusCode)
+	}
+	res := stream("streamer-token")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %v", res.StatusCode)
+	}
+
+	s.processMessage(chatMessage(testViewer, "+test {{addnum \"deaths\" 2}}{{timeout 60 \"spam\"}}ok", map[string]string{"mod": "1"}))
+
+	expected := []string{
+		`message {"id":"message-3","user_id":"3","user":"viewer","display_name":"Viewer","text":"+test {{addnum \"deaths\" 2}}{{timeout 60 \"spam\"}}ok"}`,
+		`number {"name":"deaths","value":2}`,
+		`moderation {"action":"timeout","user_id":"3","user":"viewer","duration":60,"reason":"spam"}`,
+		`command {"name":"test","user_id":"3","user":"viewer","output":"ok"}`,
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	for _, want := range expected {
+		name := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "event: ") {
+				name = strings.TrimPrefix(line, "event: ")
+			} else if strings.HasPrefix(line, "data: ") {
+				e := struct {
+					ChannelID string          `json:"channel_id"`
+					Data      json.RawMessage `json:"data"`
+				}{}
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+					t.Fatal(err)
+				}
+				if e.ChannelID != testBroadcaster.ID {
+					t.Errorf("expected the event to be for channel 2, got %q", e.ChannelID)
+				}
+				if got := name + " " + string(e.Data); got != want {
+					t.Errorf("expected %v, got %v", want, got)
+				}
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEventStreamRevoked(t *testing.T) {
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+	keepAlive := eventKeepAlive
+	eventKeepAlive = 10 * time.Millisecond
+	t.Cleanup(func() { eventKeepAlive = keepAlive })
+
+	server := httptest.NewServer(s.apiRouter())
+	t.Cleanup(server.Close)
+
+	if w := request(t, s, http.MethodPut, "/channels/2/grants/3", "streamer-token", `{"role": "moderator"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/channels/2/events?access_token=viewer-token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Accept", "text/event-stream")
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %v", res.StatusCode)
+	}
+
+	// The stream is kept open while the grant lasts
+	scanner := bufio.NewScanner(res.Body)
+	if !scanner.Scan() || scanner.Text() != ": keepalive" {
+		t.Fatalf("expected a keepalive, got %q, %v", scanner.Text(), scanner.Err())
+	}
+
+	if w := request(t, s, http.MethodDelete, "/channels/2/grants/3", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	for scanner.Scan() {
+	}
+	if err := scanner.Err(); err != nil {
+		t.Errorf("expected the stream to be closed once the grant is deleted, got %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/nicklaw5/helix/v2"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+)
+
+// ModerationAction is a moderation call made through FakeTwitch.
+type ModerationAction struct {
+	Action    string
+	ChannelID string
+	UserID    string
+	MessageID string
+	Duration  int
+	Reason    string
+}
+
+func (a ModerationAction) String() string {
+	switch a.Action {
+	case "ban":
+		return fmt.Sprintf("ban %v in %v for %vs: %v", a.UserID, a.ChannelID, a.Duration, a.Reason)
+	case "delete":
+		if a.MessageID == "" {
+			return fmt.Sprintf("delete all messages in %v", a.ChannelID)
+		}
+		return fmt.Sprintf("delete %v in %v", a.MessageID, a.ChannelID)
+	}
+	return fmt.Sprintf("%v %v in %v", a.Action, a.UserID, a.ChannelID)
+}
+
+// FakeTwitch is an in-memory TwitchAPI. Users and streams are looked up from
+// its fields, and moderation calls are recorded instead of made.
+type FakeTwitch struct {
+	mu sync.Mutex
+	// Users known to the API, looked up by id or login
+	Users   []helix.User
+	Streams []helix.Stream
+	// Chatters holds the logins in each channel, by channel id
+	Chatters map[string][]string
+	// Tokens maps user access tokens to the id of the user they belong to
+	Tokens  map[string]string
+	Actions []ModerationAction
+
+	token string
+}
+
+var _ TwitchAPI = (*FakeTwitch)(nil)
+
+func NewFakeTwitch(users ...helix.User) *FakeTwitch {
+	return &FakeTwitch{
+		Users:    users,
+		Chatters: map[string][]string{},
+		Tokens:   map[string]string{},
+	}
+}
+
+// ForUser returns a client sharing the fake's state that makes requests with
+// the given user access token.
+func (f *FakeTwitch) ForUser(accessToken string) (TwitchAPI, error) {
+	return &fakeTwitchUser{FakeTwitch: f, token: accessToken}, nil
+}
+
+// TakeActions returns the recorded moderation actions and forgets them.
+func (f *FakeTwitch) TakeActions() []ModerationAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	actions := f.Actions
+	f.Actions = nil
+	return actions
+}
+
+func (f *FakeTwitch) record(action ModerationAction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Actions = append(f.Actions, action)
+}
+
+func (f *FakeTwitch) usersFor(token string, params *helix.UsersParams) []helix.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(params.IDs) == 0 && len(params.Logins) == 0 {
+		// Without parameters the user the token belongs to is returned
+		id, ok := f.Tokens[token]
+		return lo.Filter(f.Users, func(u helix.User, _ int) bool { return ok && u.ID == id })
+	}
+	return lo.Filter(f.Users, func(u helix.User, _ int) bool {
+		return lo.Contains(params.IDs, u.ID) || lo.Contains(params.Logins, u.Login)
+	})
+}
+
+func (f *FakeTwitch) GetUsers(params *helix.UsersParams) (*helix.UsersResponse, error) {
+	res := &helix.UsersResponse{}
+	res.StatusCode = 200
+	res.Data.Users = f.usersFor(f.GetUserAccessToken(), params)
+	return res, nil
+}
+
+func (f *FakeTwitch) GetUsersFollows(params *helix.UsersFollowsParams) (*helix.UsersFollowsResponse, error) {
+	res := &helix.UsersFollowsResponse{}
+	res.StatusCode = 200
+	return res, nil
+}
+
+func (f *FakeTwitch) GetChannelChatChatters(params *helix.GetChatChattersParams) (*helix.GetChatChattersResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	res := &helix.GetChatChattersResponse{}
+	res.StatusCode = 200
+	res.Data.Chatters = lo.Map(f.Chatters[params.BroadcasterID], func(login string, _ int) helix.ChatChatter {
+		return helix.ChatChatter{UserLogin: login}
+	})
+	return res, nil
+}
+
+func (f *FakeTwitch) GetStreams(params *helix.StreamsParams) (*helix.StreamsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	res := &helix.StreamsResponse{}
+	res.StatusCode = 200
+	res.Data.Streams = lo.Filter(f.Streams, func(s helix.Stream, _ int) bool {
+		return lo.Contains(params.UserIDs, s.UserID)
+	})
+	return res, nil
+}
+
+func (f *FakeTwitch) BanUser(params *helix.BanUserParams) (*helix.BanUserResponse, error) {
+	f.record(ModerationAction{
+		Action:    "ban",
+		ChannelID: params.BroadcasterID,
+		UserID:    params.Body.UserId,
+		Duration:  params.Body.Duration,
+		Reason:    params.Body.Reason,
+	})
+	res := &helix.BanUserResponse{}
+	res.StatusCode = 200
+	return res, nil
+}
+
+func (f *FakeTwitch) UnbanUser(params *helix.UnbanUserParams) (*helix.UnbanUserResponse, error) {
+	f.record(ModerationAction{
+		Action:    "unban",
+		ChannelID: params.BroadcasterID,
+		UserID:    params.UserID,
+	})
+	res := &helix.UnbanUserResponse{}
+	res.StatusCode = 204
+	return res, nil
+}
+
+func (f *FakeTwitch) DeleteChatMessage(broadcasterID, moderatorID, messageID string) (int, error) {
+	f.record(ModerationAction{
+		Action:    "delete",
+		ChannelID: broadcasterID,
+		MessageID: messageID,
+	})
+	return 204, nil
+}
+
+func (f *FakeTwitch) GetAuthorizationURL(params *helix.AuthorizationURLParams) string {
+	return "https://id.twitch.tv/oauth2/authorize?state=" + params.State
+}
+
+func (f *FakeTwitch) RequestUserAccessToken(code string) (*helix.UserAccessTokenResponse, error) {
+	return nil, errors.New("authorization is not supported by the fake twitch api")
+}
+
+func (f *FakeTwitch) RefreshUserAccessToken(refreshToken string) (*helix.RefreshTokenResponse, error) {
+	return nil, errors.New("authorization is not supported by the fake twitch api")
+}
+
+// ValidateToken accepts the tokens in Tokens.
+func (f *FakeTwitch) ValidateToken(accessToken string) (bool, *helix.ValidateTokenResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	res := &helix.ValidateTokenResponse{}
+	id, ok := f.Tokens[accessToken]
+	if !ok {
+		res.StatusCode = 401
+		return false, res, nil
+	}
+	res.StatusCode = 200
+	res.Data.UserID = id
+	res.Data.ExpiresIn = 4 * 60 * 60
+	return true, res, nil
+}
+
+func (f *FakeTwitch) GetUserAccessToken() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.token
+}
+
+func (f *FakeTwitch) SetUserAccessToken(accessToken string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.token = accessToken
+}
+
+// fakeTwitchUser is a FakeTwitch using its own user access token.
+type fakeTwitchUser struct {
+	*FakeTwitch
+	token string
+}
+
+func (f *fakeTwitchUser) GetUsers(params *helix.UsersParams) (*helix.UsersResponse, error) {
+	res := &helix.UsersResponse{}
+	res.StatusCode = 200
+	res.Data.Users = f.usersFor(f.token, params)
+	return res, nil
+}
+
+func (f *fakeTwitchUser) GetUserAccessToken() string {
+	return f.token
+}
+
+func (f *fakeTwitchUser) SetUserAccessToken(accessToken string) {
+	f.token = accessToken
+}
+
+// ChatMessage is a message sent through FakeChat.
+type ChatMessage struct {
+	Channel string
+	// The id of the message replied to, if any
+	ReplyTo string
+	Text    string
+}
+
+func (m ChatMessage) String() string {
+	if m.ReplyTo != "" {
+		return fmt.Sprintf("#%v (reply to %v): %v", m.Channel, m.ReplyTo, m.Text)
+	}
+	return fmt.Sprintf("#%v: %v", m.Channel, m.Text)
+}
+
+// FakeChat is an in-memory Chat that records what is sent.
+type FakeChat struct {
+	mu       sync.Mutex
+	Messages []ChatMessage
+	Joined   []string
+	// Sent is notified of every message, if set
+	Sent chan ChatMessage
+}
+
+var _ Chat = (*FakeChat)(nil)
+
+func (c *FakeChat) send(m ChatMessage) {
+	c.mu.Lock()
+	c.Messages = append(c.Messages, m)
+	sent := c.Sent
+	c.mu.Unlock()
+
+	if sent != nil {
+		sent <- m
+	}
+}
+
+func (c *FakeChat) Say(channel, text string) {
+	c.send(ChatMessage{Channel: channel, Text: text})
+}
+
+func (c *FakeChat) Reply(channel, parentMsgID, text string) {
+	c.send(ChatMessage{Channel: channel, ReplyTo: parentMsgID, Text: text})
+}
+
+// TakeMessages returns the sent messages and forgets them.
+func (c *FakeChat) TakeMessages() []ChatMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	messages := c.Messages
+	c.Messages = nil
+	return messages
+}
+
+func (c *FakeChat) Join(channels ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Joined = lo.Uniq(append(c.Joined, channels...))
+}
+
+func (c *FakeChat) Depart(channel string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Joined = lo.Without(c.Joined, channel)
+}
+
+func (c *FakeChat) SetIRCToken(ircToken string) {}
+
+func (c *FakeChat) Disconnect() error {
+	return nil
+}
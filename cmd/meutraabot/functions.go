@@ -3,11 +3,9 @@ package main
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
-	"fmt"
-	"io"
 	"io/ioutil"
-	l "log"
 	"math/rand"
 	"net/http"
 	"regexp"
@@ -66,7 +64,7 @@ func (s *Server) FuncMap(ctx context.Context, d Data, e *irc.PrivateMessage) tem
 	}
 }
 
-func User(client *helix.Client, userID, username string) (helix.User, error) {
+func User(client TwitchAPI, userID, username string) (helix.User, error) {
 	ids := []string{}
 	usernames := []string{}
 	if userID != "" {
@@ -84,7 +82,7 @@ func User(client *helix.Client, userID, username string) (helix.User, error) {
 	return res[0], nil
 }
 
-func Users(client *helix.Client, userIDs []string, usernames []string) ([]helix.User, error) {
+func Users(client TwitchAPI, userIDs []string, usernames []string) ([]helix.User, error) {
 	req := helix.UsersParams{}
 	if len(userIDs) > 0 {
 		req.IDs = userIDs
@@ -94,7 +92,7 @@ func Users(client *helix.Client, userIDs []string, usernames []string) ([]helix.
 
 	resp, err := client.GetUsers(&req)
 	if err != nil {
-		fmt.Println("unable to get users by id", err)
+		twitchLog.Error("unable to get users", "ids", userIDs, "logins", usernames, "err", err)
 		return []helix.User{}, err
 	}
 
@@ -108,18 +106,18 @@ func (s *Server) funcUserFollow(ctx context.Context, d Data) string {
 		ToID:   d.ChannelID,
 	})
 	if err != nil {
-		log(d.Channel, d.User, "unable to get user follow information for "+d.SelectedUser, err)
+		d.log(twitchLog).Error("unable to get user follow information", "selected_user", d.SelectedUser, "err", err)
 		return ""
 	}
 
 	if len(resp.Data.Follows) == 0 {
-		log(d.Channel, d.User, "no follow information for "+d.SelectedUser, nil)
+		d.log(twitchLog).Debug("no follow information", "selected_user", d.SelectedUser)
 		return ""
 	}
 
 	data, err := json.Marshal(resp.Data.Follows[0])
 	if err != nil {
-		log(d.Channel, d.User, "unable to marshal user follow "+d.SelectedUser, err)
+		d.log(commandLog).Error("unable to marshal user follow", "selected_user", d.SelectedUser, "err", err)
 		return ""
 	}
 	return string(data)
@@ -128,13 +126,13 @@ func (s *Server) funcUserFollow(ctx context.Context, d Data) string {
 func (s *Server) funcUser(ctx context.Context, d Data) string {
 	user, err := User(s.twitch, d.SelectedUserID, "")
 	if err != nil {
-		log(d.Channel, d.User, "unable to get user "+d.SelectedUser, err)
+		d.log(twitchLog).Error("unable to get user", "selected_user", d.SelectedUser, "err", err)
 		return ""
 	}
 
 	data, err := json.Marshal(user)
 	if err != nil {
-		log(d.Channel, d.User, "unable to marshal user "+d.SelectedUser, err)
+		d.log(commandLog).Error("unable to marshal user", "selected_user", d.SelectedUser, "err", err)
 		return ""
 	}
 	return string(data)
@@ -149,46 +147,23 @@ func (s *Server) Chatters(ctx context.Context, channelID string) ([]string, erro
 	if nil != err {
 		return []string{}, errors.Wrap(err, "unable to get channel chatters")
 	} else if res.Error != "" {
-		l.Println(channelID, res.Error+": "+res.ErrorMessage, nil)
+		twitchLog.Warn("unable to get channel chatters", "channel_id", channelID, "status", res.StatusCode, "error", res.Error, "message", res.ErrorMessage)
 	}
 
 	return lo.Map(res.Data.Chatters, func(data helix.ChatChatter, _ int) string { return data.UserLogin }), nil
 }
 
-// https://dev.twitch.tv/docs/api/reference#delete-chat-messages
 func (s *Server) funcDelete(ctx context.Context, d Data, messageID string) string {
-	req, err := http.NewRequest(http.MethodDelete, "https://api.twitch.tv/helix/moderation/chat", nil)
+	status, err := s.twitch.DeleteChatMessage(d.ChannelID, s.selfID, messageID)
 	if err != nil {
-		log(d.Channel, d.User, "unable to create request", err)
+		d.log(twitchLog).Error("unable to delete message", "message_id", messageID, "err", err)
 		return ""
 	}
+	countModeration("delete", status)
 
-	req.Header.Add("Authorization", "Bearer "+s.twitch.GetUserAccessToken())
-	req.Header.Add("Client-Id", s.env.twitchClientID)
-
-	q := req.URL.Query()
-	q.Add("broadcaster_id", d.ChannelID)
-	q.Add("moderator_id", s.selfID)
-	if "" != messageID {
-		q.Add("message_id", messageID)
-	}
-	req.URL.RawQuery = q.Encode()
-	resp, err := s.client.Do(req)
-	if err != nil {
-		log(d.Channel, d.User, "unable to do request", err)
-		return ""
-	}
-
-	l.Println(req.Method, req.URL)
-	l.Println(resp.StatusCode)
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err == nil {
-		l.Println(string(body))
-	}
-
-	switch resp.StatusCode {
+	switch status {
 	case 204: // Success
+		s.publish(d.ChannelID, EventModeration, ModerationEvent{Action: "delete", MessageID: messageID})
 	case 400: // Not allowed to delete mod/broadcaster messages
 	case 403: // Not a mod
 	case 404: // Message not found, or too old
@@ -204,8 +179,10 @@ func (s *Server) funcGetNumber(ctx context.Context, d Data, name string) string
 		ChannelID: d.ChannelID,
 		Name:      name,
 	})
-	if nil != err {
-		l.Println(err)
+	if err == sql.ErrNoRows {
+		return "0"
+	} else if nil != err {
+		d.log(commandLog).Error("unable to get number", "name", name, "err", err)
 		return "0"
 	}
 
@@ -219,7 +196,18 @@ func (s *Server) funcAddToNumber(ctx context.Context, d Data, name string, value
 		Value:     int64(value),
 	})
 	if nil != err {
-		l.Println(err)
+		d.log(commandLog).Error("unable to add to number", "name", name, "err", err)
+		return ""
+	}
+
+	// Only read the new value back when someone is watching
+	if s.events.watched(d.ChannelID) {
+		num, err := s.q.GetNumber(ctx, db.GetNumberParams{ChannelID: d.ChannelID, Name: name})
+		if nil != err {
+			d.log(commandLog).Error("unable to get number", "name", name, "err", err)
+			return ""
+		}
+		s.publish(d.ChannelID, EventNumber, NumberEvent{Name: name, Value: num.Value})
 	}
 
 	return ""
@@ -232,15 +220,34 @@ func (s *Server) funcUnban(ctx context.Context, d Data) string {
 		UserID:        d.UserID,
 	})
 	if err != nil {
-		log(d.Channel, d.User, "unable to unban user", err)
-	} else if res.Error != "" {
-		log(d.Channel, d.User, res.Error+": "+res.ErrorMessage, nil)
+		d.log(twitchLog).Error("unable to unban user", "err", err)
+		return ""
+	}
+	countModeration("unban", res.StatusCode)
+	if res.Error != "" {
+		d.log(twitchLog).Warn("twitch rejected the request", "status", res.StatusCode, "error", res.Error, "message", res.ErrorMessage)
+		return ""
+	}
+
+	if err := s.q.LiftBans(ctx, db.LiftBansParams{
+		LiftedAt:  sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		ChannelID: d.ChannelID,
+		UserID:    d.UserID,
+	}); err != nil {
+		d.log(dbLog).Error("unable to record unban", "err", err)
 	}
+	s.publish(d.ChannelID, EventModeration, ModerationEvent{Action: "unban", UserID: d.UserID, User: d.User})
 
 	return ""
 }
 
 func (s *Server) funcBan(ctx context.Context, d Data, duration int, reason string) string {
+	s.ban(ctx, d, duration, reason)
+	return ""
+}
+
+// ban bans or times out the user of d, returning whether Twitch accepted it.
+func (s *Server) ban(ctx context.Context, d Data, duration int, reason string) bool {
 	res, err := s.twitch.BanUser(&helix.BanUserParams{
 		BroadcasterID: d.ChannelID,
 		ModeratorId:   s.selfID,
@@ -252,12 +259,42 @@ func (s *Server) funcBan(ctx context.Context, d Data, duration int, reason strin
 	})
 
 	if err != nil {
-		log(d.Channel, d.User, "unable to ban user", err)
-	} else if res.Error != "" {
-		log(d.Channel, d.User, res.Error+": "+res.ErrorMessage, nil)
+		d.log(twitchLog).Error("unable to ban user", "duration", duration, "err", err)
+		return false
+	}
+	if duration > 0 {
+		countModeration("timeout", res.StatusCode)
+	} else {
+		countModeration("ban", res.StatusCode)
+	}
+	if res.Error != "" {
+		d.log(twitchLog).Warn("twitch rejected the request", "status", res.StatusCode, "error", res.Error, "message", res.ErrorMessage)
+		return false
 	}
 
-	return ""
+	if err := s.q.RecordBan(ctx, db.RecordBanParams{
+		ChannelID: d.ChannelID,
+		UserID:    d.UserID,
+		UserLogin: d.User,
+		Duration:  int64(duration),
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		d.log(dbLog).Error("unable to record ban", "err", err)
+	}
+	action := "ban"
+	if duration > 0 {
+		action = "timeout"
+	}
+	s.publish(d.ChannelID, EventModeration, ModerationEvent{
+		Action:   action,
+		UserID:   d.UserID,
+		User:     d.User,
+		Duration: duration,
+		Reason:   reason,
+	})
+
+	return true
 }
 
 func (s *Server) funcRandom(ctx context.Context, d Data, max int) string {
@@ -269,26 +306,31 @@ func (s *Server) funcReply(ctx context.Context, d Data, message string) string {
 }
 
 func (s *Server) funcReplyAuto(ctx context.Context, d Data, message string, useCustomPrompt bool, prompt func() string) string {
-	if _, ok := s.conversations[d.Event.Channel]; !ok {
-		s.conversations[d.Event.Channel] = make([]*irc.PrivateMessage, 0)
-	}
-	s.conversations[d.Event.Channel] = append(s.conversations[d.Event.Channel], d.Event)
+	s.addConversation(d.Event)
 
 	// get the channel settings
 	settings, err := s.q.GetChannel(ctx, d.ChannelID)
 	if err != nil {
-		log(d.Channel, d.User, "unable to get channel settings", err)
+		d.log(aiLog).Error("unable to get channel settings", "err", err)
+		return ""
+	}
+
+	token, err := s.secrets.Get(ctx, channelTokenSecret(d.ChannelID))
+	if err == sql.ErrNoRows {
+		return ""
+	} else if err != nil {
+		d.log(aiLog).Error("unable to get openai token", "err", err)
 		return ""
 	}
 
 	// Check if token matches valid regex
-	if !settings.OpenaiToken.Valid || !regexp.MustCompile(`^sk-\w{48}$`).MatchString(settings.OpenaiToken.String) {
+	if !regexp.MustCompile(`^sk-\w{48}$`).MatchString(token) {
 		return ""
 	}
 
 	channelData, err := s.Stream(ctx, d.ChannelID)
 	if err != nil {
-		log(d.Channel, d.User, "unable to get stream data", err)
+		d.log(aiLog).Warn("unable to get stream data", "err", err)
 	}
 
 	safety := ""
@@ -303,7 +345,8 @@ func (s *Server) funcReplyAuto(ctx context.Context, d Data, message string, useC
 		safety = "an opinionated, sassy viewer"
 	}
 
-	p := "Meuua is " + safety + " in " + d.Channel + "'s twitch stream"
+	persona := s.config.Persona
+	p := strings.ToUpper(persona[:1]) + persona[1:] + " is " + safety + " in " + d.Channel + "'s twitch stream"
 	if err == nil {
 		p += " (" + channelData.GameName + " - " + channelData.Title + ")"
 	}
@@ -314,7 +357,7 @@ func (s *Server) funcReplyAuto(ctx context.Context, d Data, message string, useC
 	} else {
 		if d.ReplyingToMessage != "" {
 			// find all the messages in this thread
-			history, ok := s.conversations[d.Channel]
+			history, ok := s.conversation(d.Channel)
 			if ok {
 				if len(history) > 15 {
 					history = history[len(history)-15:]
@@ -336,9 +379,9 @@ func (s *Server) funcReplyAuto(ctx context.Context, d Data, message string, useC
 			p += d.User + ": " + d.Message + "\n"
 		}
 	}
-	p += "meuua:"
+	p += persona + ":"
 
-	log(d.Channel, d.User, p, nil)
+	d.log(aiLog).Debug("built prompt", "prompt", p)
 
 	data := CompletionRequest{
 		Prompt:           p,
@@ -367,52 +410,61 @@ func (s *Server) funcReplyAuto(ctx context.Context, d Data, message string, useC
 		User: d.Channel,
 	}
 	jsonData, err := json.Marshal(data)
-	log(d.Channel, d.User, "sending completion request"+string(jsonData), nil)
 	if nil != err {
-		log(d.Channel, d.User, "unable to do marshal ai request", err)
+		d.log(aiLog).Error("unable to marshal ai request", "err", err)
 		return ""
 	}
 
-	// last chance to check that meuua has not already replied
-	hist, okay := s.history[d.Channel]
+	// last chance to check that the bot has not already replied
+	hist, okay := s.channelHistory(d.Channel)
 	if okay && len(hist) > 0 && hist[len(hist)-1].User.ID == s.selfID {
-		log(d.Channel, d.User, "was last person to respond, so not doing completion request", nil)
+		d.log(aiLog).Debug("was last person to respond, so not doing completion request")
 		return ""
 	}
 
 	req, err := http.NewRequest("POST", "https://api.openai.com/v1/engines/text-davinci-003/completions", bytes.NewBuffer(jsonData))
 	if nil != err {
-		log(d.Channel, d.User, "unable to create completion request", err)
+		d.log(aiLog).Error("unable to create completion request", "err", err)
 		return ""
 	}
 
-	req.Header.Set("Authorization", "Bearer "+settings.OpenaiToken.String)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
+	d.log(aiLog).Info("sending completion request", "max_tokens", data.MaxTokens)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		log(d.Channel, d.User, "unable to do completion request", err)
+		aiRequests.WithLabelValues("error").Inc()
+		d.log(aiLog).Error("unable to do completion request", "err", err)
 		return ""
 	}
 	defer resp.Body.Close()
 	res, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log(d.Channel, d.User, "unable to read body of ai response", err)
+		aiRequests.WithLabelValues("error").Inc()
+		d.log(aiLog).Error("unable to read body of ai response", "err", err)
 		return ""
 	}
 
 	var completion CompletionResponse
 	if err := json.Unmarshal(res, &completion); nil != err {
-		log(d.Channel, d.User, "unable to unmarshal ai response", err)
+		aiRequests.WithLabelValues("error").Inc()
+		d.log(aiLog).Error("unable to unmarshal ai response", "status", resp.StatusCode, "err", err)
 		return ""
 	}
 
+	aiTokens.WithLabelValues("prompt").Add(float64(completion.Usage.PromptTokens))
+	aiTokens.WithLabelValues("completion").Add(float64(completion.Usage.CompletionTokens))
+
 	if len(completion.Choices) == 0 {
-		log(d.Channel, d.User, "ai has no responses", nil)
+		aiRequests.WithLabelValues("empty").Inc()
+		d.log(aiLog).Warn("ai has no responses", "status", resp.StatusCode)
 		return ""
 	}
 
+	aiRequests.WithLabelValues("ok").Inc()
 	str := completion.Choices[0].Text
+	d.log(aiLog).Debug("received completion", "completion", str)
 
 	// remove the last line of the string
 	lines := strings.Split(strings.TrimSpace(str), "\n")
@@ -427,13 +479,13 @@ func (s *Server) funcReplyAuto(ctx context.Context, d Data, message string, useC
 func (s *Server) funcStream(ctx context.Context, d Data) string {
 	stream, err := s.Stream(ctx, d.ChannelID)
 	if err != nil {
-		log(d.Channel, d.User, "unable to get stream information for "+d.Channel, err)
+		d.log(twitchLog).Error("unable to get stream information", "err", err)
 		return ""
 	}
 
 	data, err := json.Marshal(stream)
 	if err != nil {
-		log(d.Channel, d.User, "unable to marshal stream "+d.SelectedUser, err)
+		d.log(commandLog).Error("unable to marshal stream", "err", err)
 		return ""
 	}
 	return string(data)
@@ -456,7 +508,7 @@ func (s *Server) Stream(ctx context.Context, userId string) (helix.Stream, error
 func (s *Server) funcDuration(ctx context.Context, d Data, startTime string) string {
 	t, err := time.Parse(time.RFC3339, startTime)
 	if err != nil {
-		log(d.Channel, d.User, "unable to parse time "+startTime, err)
+		d.log(commandLog).Error("unable to parse time", "time", startTime, "err", err)
 		return ""
 	}
 
@@ -466,20 +518,20 @@ func (s *Server) funcDuration(ctx context.Context, d Data, startTime string) str
 func (s *Server) funcGet(ctx context.Context, d Data, url string) string {
 	req, err := http.NewRequest("GET", url, nil)
 	if nil != err {
-		log(d.Channel, d.User, "unable to create request for "+url, err)
+		d.log(commandLog).Error("unable to create request", "url", url, "err", err)
 		return ""
 	}
 	req = req.WithContext(ctx)
 	req.Header.Add("Accept", "text/plain")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		log(d.Channel, d.User, "unable to get "+url, err)
+		d.log(commandLog).Error("unable to get url", "url", url, "err", err)
 		return ""
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if nil != err {
-		log(d.Channel, d.User, "unable to read body of "+url, err)
+		d.log(commandLog).Error("unable to read body", "url", url, "err", err)
 		return ""
 	}
 	str := strings.ReplaceAll(string(body), "\n", " ")
@@ -490,7 +542,7 @@ func (s *Server) funcGet(ctx context.Context, d Data, url string) string {
 func (s *Server) funcJsonParse(ctx context.Context, d Data, key, str string) string {
 	m := map[string]interface{}{}
 	if err := json.Unmarshal([]byte(str), &m); err != nil {
-		log(d.Channel, d.User, "unable to unmarshal", err)
+		d.log(commandLog).Error("unable to unmarshal", "err", err)
 		return ""
 	}
 	v := m[key]
@@ -498,7 +550,7 @@ func (s *Server) funcJsonParse(ctx context.Context, d Data, key, str string) str
 	if !ok {
 		data, err := json.Marshal(v)
 		if nil != err {
-			log(d.Channel, d.User, "unable to marshal", err)
+			d.log(commandLog).Error("unable to marshal", "err", err)
 			return ""
 		}
 		return string(data)
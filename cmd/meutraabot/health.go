@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ChatStatus describes the connection to chat.
+type ChatStatus struct {
+	Connected bool `json:"connected"`
+	// Channels joined, not counting the bot's own
+	JoinedChannels int `json:"joined_channels"`
+	// Channels registered in the database
+	Channels      int        `json:"channels"`
+	LastMessageAt *time.Time `json:"last_message_at,omitempty"`
+}
+
+// DatabaseStatus describes the connection to the database.
+type DatabaseStatus struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// HealthStatus is the body of the health and readiness endpoints. The token
+// and the error of the database are only shown to the operator.
+type HealthStatus struct {
+	Healthy  bool           `json:"healthy"`
+	Ready    bool           `json:"ready"`
+	Chat     ChatStatus     `json:"chat"`
+	Token    *TokenStatus   `json:"token,omitempty"`
+	Database DatabaseStatus `json:"database"`
+}
+
+// The error of the database shown to everyone but the operator, as the error
+// itself may name hosts and files.
+const databaseUnavailable = "unable to reach the database"
+
+// setConnected records whether the bot is connected to chat. Channels are
+// joined again after connecting.
+func (s *Server) setConnected(connected bool) {
+	s.chatMu.Lock()
+	defer s.chatMu.Unlock()
+	s.connected = connected
+	s.joined = map[string]bool{}
+}
+
+// setJoined records the bot joining or leaving a channel.
+func (s *Server) setJoined(channel string, joined bool) {
+	s.chatMu.Lock()
+	defer s.chatMu.Unlock()
+	if s.joined == nil {
+		s.joined = map[string]bool{}
+	}
+	if joined {
+		s.joined[channel] = true
+	} else {
+		delete(s.joined, channel)
+	}
+}
+
+// receivedMessage records the time a chat message was last received.
+func (s *Server) receivedMessage() {
+	s.chatMu.Lock()
+	defer s.chatMu.Unlock()
+	s.lastMessage = time.Now().UTC()
+}
+
+// HealthStatus checks the chat connection, token and database.
+func (s *Server) HealthStatus(ctx context.Context) HealthStatus {
+	token := s.TokenStatus()
+	status := HealthStatus{Token: &token}
+
+	s.chatMu.Lock()
+	status.Chat.Connected = s.connected
+	for channel := range s.joined {
+		if channel != s.selfLogin {
+			status.Chat.JoinedChannels++
+		}
+	}
+	if !s.lastMessage.IsZero() {
+		last := s.lastMessage
+		status.Chat.LastMessageAt = &last
+	}
+	s.chatMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := s.conn.PingContext(ctx); err != nil {
+		status.Database.Error = err.Error()
+		dbLog.Warn("unable to ping the database", "err", err)
+	} else if channels, err := s.q.GetChannels(ctx); err != nil {
+		status.Database.Error = err.Error()
+		dbLog.Warn("unable to get channels", "err", err)
+	} else {
+		status.Database.OK = true
+		status.Chat.Channels = len(channels)
+	}
+
+	// Waiting to be authorized is not a reason to restart the bot
+	status.Healthy = status.Database.OK
+	status.Ready = status.Healthy && token.Valid && status.Chat.Connected
+	return status
+}
+
+// public returns the status without the details only the operator may see.
+func (status HealthStatus) public() HealthStatus {
+	status.Token = nil
+	if status.Database.Error != "" {
+		status.Database.Error = databaseUnavailable
+	}
+	return status
+}
+
+// healthz responds with 503 while the bot can not reach the database, which
+// needs an operator or a restart to fix.
+func (s *Server) healthz() http.HandlerFunc {
+	return s.writeHealth(func(status HealthStatus) bool { return status.Healthy })
+}
+
+// readyz responds with 503 until the bot also has a valid token and is
+// connected to chat.
+func (s *Server) readyz() http.HandlerFunc {
+	return s.writeHealth(func(status HealthStatus) bool { return status.Ready })
+}
+
+func (s *Server) writeHealth(ok func(status HealthStatus) bool) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := s.HealthStatus(r.Context())
+		if user, ok := requestUser(r); !ok || user.ID != s.config.Twitch.OwnerID {
+			status = status.public()
+		}
+
+		res, err := json.Marshal(status)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ok(status) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(res)
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	check := func(handler http.HandlerFunc, code int) HealthStatus {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != code {
+			t.Fatalf("expected %v, got %v: %v", code, w.Code, w.Body)
+		}
+		status := HealthStatus{}
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+	checkOwner := func(handler http.HandlerFunc, code int) HealthStatus {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler(w, r.WithContext(context.WithValue(r.Context(), userContextKey, testOwner)))
+		if w.Code != code {
+			t.Fatalf("expected %v, got %v: %v", code, w.Code, w.Body)
+		}
+		status := HealthStatus{}
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+
+	// The bot is alive but not ready while it waits to be authorized
+	check(s.healthz(), http.StatusOK)
+	check(s.readyz(), http.StatusServiceUnavailable)
+
+	s.token.Valid = true
+	check(s.readyz(), http.StatusServiceUnavailable)
+
+	if err := s.q.CreateChannel(context.Background(), testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.setConnected(true)
+	s.setJoined(testBot.Login, true)
+	s.setJoined(testBroadcaster.Login, true)
+	s.receivedMessage()
+	status := check(s.readyz(), http.StatusOK)
+	if status.Token != nil {
+		t.Errorf("expected the token to only be shown to the operator, got %+v", status.Token)
+	}
+	if status := checkOwner(s.readyz(), http.StatusOK); status.Token == nil || !status.Token.Valid {
+		t.Errorf("expected the operator to see the token, got %+v", status.Token)
+	}
+	if !status.Chat.Connected || status.Chat.JoinedChannels != 1 || status.Chat.Channels != 1 || status.Chat.LastMessageAt == nil {
+		t.Errorf("unexpected chat status %+v", status.Chat)
+	}
+
+	s.setConnected(false)
+	status = check(s.readyz(), http.StatusServiceUnavailable)
+	if status.Chat.JoinedChannels != 0 {
+		t.Errorf("expected no joined channels after disconnecting, got %v", status.Chat.JoinedChannels)
+	}
+
+	s.conn.Close()
+	status = check(s.healthz(), http.StatusServiceUnavailable)
+	if status.Database.OK || status.Database.Error != databaseUnavailable {
+		t.Errorf("expected a database error without details, got %+v", status.Database)
+	}
+	status = checkOwner(s.healthz(), http.StatusServiceUnavailable)
+	if status.Database.Error == databaseUnavailable || status.Database.Error == "" {
+		t.Errorf("expected the operator to see the database error, got %+v", status.Database)
+	}
+}
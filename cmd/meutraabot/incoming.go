@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/samber/lo"
+)
+
+// The largest payload accepted by an incoming webhook.
+const maxIncomingPayload = 64 * 1024
+
+// IncomingData is what the command of an incoming webhook is run with, the
+// usual data of a command sent by the bot itself, and the payload.
+type IncomingData struct {
+	Data
+	Payload any
+}
+
+// IncomingWebhook is an incoming webhook as returned by the API. The token is
+// only returned when the webhook is created.
+type IncomingWebhook struct {
+	ID        int64     `json:"id"`
+	Command   string    `json:"command"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Token     string    `json:"token,omitempty"`
+}
+
+func newIncomingWebhook(hook db.IncomingWebhook) IncomingWebhook {
+	return IncomingWebhook{
+		ID:        hook.ID,
+		Command:   hook.Command,
+		CreatedBy: hook.CreatedBy,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+type IncomingWebhookBody struct {
+	// The name of the channel's command whose template is run
+	Command string `json:"command"`
+}
+
+// IncomingWebhookResult is the response to a payload, what was sent to chat.
+type IncomingWebhookResult struct {
+	Message string `json:"message"`
+}
+
+func (s *Server) listIncomingWebhooks() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hooks, err := s.q.GetIncomingWebhooks(r.Context(), requestChannel(r))
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res, err := json.Marshal(lo.Map(hooks, func(hook db.IncomingWebhook, _ int) IncomingWebhook { return newIncomingWebhook(hook) }))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
+
+// createIncomingWebhook creates a webhook that runs one of the channel's
+// commands, responding with the token that must be posted to.
+func (s *Server) createIncomingWebhook() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := IncomingWebhookBody{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params := db.GetCommandParams{ChannelID: requestChannel(r), Name: body.Command}
+		if _, err := s.q.GetCommand(r.Context(), params); err == sql.ErrNoRows {
+			http.Error(w, "command not found", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		token, err := generateToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		user, _ := requestUser(r)
+		hook, err := s.q.CreateIncomingWebhook(r.Context(), db.CreateIncomingWebhookParams{
+			ChannelID: requestChannel(r),
+			Command:   body.Command,
+			TokenHash: hashToken(token),
+			CreatedBy: user.ID,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, hook.ChannelID, AuditIncomingWebhookCreated, nil, newIncomingWebhook(hook))
+
+		created := newIncomingWebhook(hook)
+		created.Token = token
+		res, err := json.Marshal(created)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	})
+}
+
+func (s *Server) deleteIncomingWebhook() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "webhook"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params := db.GetIncomingWebhookParams{ChannelID: requestChannel(r), ID: id}
+		hook, err := s.q.GetIncomingWebhook(r.Context(), params)
+		if err == sql.ErrNoRows {
+			http.Error(w, "webhook not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := s.q.DeleteIncomingWebhook(r.Context(), db.DeleteIncomingWebhookParams(params)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, hook.ChannelID, AuditIncomingWebhookDeleted, newIncomingWebhook(hook), nil)
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// deleteIncomingWebhooks deletes the incoming webhooks of a channel, so that
+// none outlive it being unregistered.
+func deleteIncomingWebhooks(ctx context.Context, q db.Querier, channelID string) error {
+	hooks, err := q.GetIncomingWebhooks(ctx, channelID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := q.DeleteIncomingWebhook(ctx, db.DeleteIncomingWebhookParams{ChannelID: channelID, ID: hook.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiveWebhook runs the command of the incoming webhook with the {token} of
+// the route, with the JSON body as the payload, and sends the output to the
+// channel's chat.
+func (s *Server) receiveWebhook() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		hook, err := s.q.GetIncomingWebhookByToken(ctx, hashToken(chi.URLParam(r, "token")))
+		if err == sql.ErrNoRows {
+			http.Error(w, "webhook not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Webhooks are deleted with their channel, but not in the same transaction
+		if _, err := s.q.GetChannel(ctx, hook.ChannelID); err == sql.ErrNoRows {
+			http.Error(w, "webhook not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var payload any
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIncomingPayload)).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if s.chat() == nil {
+			http.Error(w, "The bot is not connected to chat", http.StatusServiceUnavailable)
+			return
+		}
+
+		tmpl, err := s.q.GetCommand(ctx, db.GetCommandParams{ChannelID: hook.ChannelID, Name: hook.Command})
+		if err == sql.ErrNoRows {
+			http.Error(w, fmt.Sprintf("command %v not found", hook.Command), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		channel, err := User(s.twitch, hook.ChannelID, "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		e := s.selfMessage(channel.Login, hook.ChannelID)
+		data := Data{
+			Channel:   channel.Login,
+			ChannelID: hook.ChannelID,
+			User:      s.selfLogin,
+			UserID:    s.selfID,
+			BotID:     s.selfID,
+			Event:     e,
+			Command:   hook.Command,
+		}
+		log := data.log(apiLog).With("webhook_id", hook.ID, "name", hook.Command)
+		out, err := executeTemplate(log, hook.Command, tmpl, s.FuncMap(ctx, data, e), IncomingData{Data: data, Payload: payload})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		s.publish(hook.ChannelID, EventCommand, CommandEvent{
+			Name:   hook.Command,
+			UserID: s.selfID,
+			User:   s.selfLogin,
+			Output: out,
+		})
+		go s.sendResponse(e, strings.ReplaceAll(out, "\\n", "\n"))
+
+		res, err := json.Marshal(IncomingWebhookResult{Message: out})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
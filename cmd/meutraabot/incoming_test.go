@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+)
+
+func TestIncomingWebhook(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, chat := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+	chat.Sent = make(chan ChatMessage, 1)
+
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.q.SaveCommand(ctx, db.SaveCommandParams{
+		ChannelID: testBroadcaster.ID,
+		Name:      "^!donation",
+		Template:  `{{addnum "donations" 1}}{{.Payload.name}} donated {{.Payload.amount}} in #{{.Channel}}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"command": "^!donation"}`
+	if w := request(t, s, http.MethodPost, "/channels/2/incoming-webhooks", "viewer-token", body); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPost, "/channels/2/incoming-webhooks", "streamer-token", `{"command": "^!missing"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a missing command, got %v", w.Code)
+	}
+
+	w := request(t, s, http.MethodPost, "/channels/2/incoming-webhooks", "streamer-token", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %v: %v", w.Code, w.Body)
+	}
+	hook := IncomingWebhook{}
+	if err := json.Unmarshal(w.Body.Bytes(), &hook); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(t, s, http.MethodPost, "/hooks/wrong", "", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown token, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPost, "/hooks/"+hook.Token, "", `{"name":`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid payload, got %v", w.Code)
+	}
+
+	if w := request(t, s, http.MethodPost, "/hooks/"+hook.Token, "", `{"name": "ada", "amount": 5}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	select {
+	case m := <-chat.Sent:
+		if m.String() != "#streamer: ada donated 5 in #streamer" {
+			t.Errorf("unexpected message %v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the payload to be sent to chat")
+	}
+	if res := s.funcGetNumber(ctx, Data{ChannelID: testBroadcaster.ID}, "donations"); res != "1" {
+		t.Errorf("expected the template to run as a command, got %v", res)
+	}
+
+	if w := request(t, s, http.MethodDelete, "/channels/2/incoming-webhooks/"+strconv.FormatInt(hook.ID, 10), "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodPost, "/hooks/"+hook.Token, "", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after the webhook is deleted, got %v", w.Code)
+	}
+}
+
+func TestIncomingWebhookUnregistered(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+
+	if w := request(t, s, http.MethodPut, "/channels/2", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if err := s.q.SaveCommand(ctx, db.SaveCommandParams{ChannelID: testBroadcaster.ID, Name: "^!donation", Template: "thanks"}); err != nil {
+		t.Fatal(err)
+	}
+	w := request(t, s, http.MethodPost, "/channels/2/incoming-webhooks", "streamer-token", `{"command": "^!donation"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %v: %v", w.Code, w.Body)
+	}
+	hook := IncomingWebhook{}
+	if err := json.Unmarshal(w.Body.Bytes(), &hook); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(t, s, http.MethodDelete, "/channels/2", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodPost, "/hooks/"+hook.Token, "", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after the channel is unregistered, got %v", w.Code)
+	}
+	if hooks, err := s.q.GetIncomingWebhooks(ctx, testBroadcaster.ID); err != nil || len(hooks) != 0 {
+		t.Errorf("expected the incoming webhooks to be deleted, got %v, %v", hooks, err)
+	}
+
+	// A webhook left behind by a channel removed elsewhere is not run
+	if _, err := s.q.CreateIncomingWebhook(ctx, db.CreateIncomingWebhookParams{
+		ChannelID: testBroadcaster.ID,
+		Command:   "^!donation",
+		TokenHash: hashToken("left-behind"),
+		CreatedBy: testBroadcaster.ID,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if w := request(t, s, http.MethodPost, "/hooks/left-behind", "", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for the webhook of an unregistered channel, got %v", w.Code)
+	}
+
+	// Leaving from chat deletes them too
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.handleCommand(ctx, chatMessage(testBroadcaster, "+leave", nil))
+	if hooks, err := s.q.GetIncomingWebhooks(ctx, testBroadcaster.ID); err != nil || len(hooks) != 0 {
+		t.Errorf("expected +leave to delete the incoming webhooks, got %v, %v", hooks, err)
+	}
+}
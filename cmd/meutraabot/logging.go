@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	irc "github.com/gempir/go-twitch-irc/v3"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/samber/lo"
+)
+
+// The subsystems that log, each of which can log at its own level.
+const (
+	LogIRC      = "irc"
+	LogCommands = "commands"
+	LogTwitch   = "twitch"
+	LogAI       = "ai"
+	LogAPI      = "api"
+	LogAuth     = "auth"
+	LogDB       = "db"
+)
+
+var logSubsystems = []string{LogIRC, LogCommands, LogTwitch, LogAI, LogAPI, LogAuth, LogDB}
+
+// Log formats.
+const (
+	LogText = "text"
+	LogJSON = "json"
+)
+
+type LogConfig struct {
+	// LogText for logfmt or LogJSON
+	Format string `yaml:"format"`
+	// debug, info, warn or error
+	Level string `yaml:"level"`
+	// Levels overrides Level for the named subsystems
+	Levels map[string]string `yaml:"levels"`
+	// Log AI prompts and responses at debug level instead of redacting them
+	Prompts bool `yaml:"prompts"`
+}
+
+func (c LogConfig) validate() []string {
+	problems := []string{}
+	if c.Format != LogText && c.Format != LogJSON {
+		problems = append(problems, fmt.Sprintf("log.format must be %v or %v", LogText, LogJSON))
+	}
+	if _, err := parseLogLevel(c.Level); err != nil {
+		problems = append(problems, "log.level: "+err.Error())
+	}
+	for name, level := range c.Levels {
+		if !lo.Contains(logSubsystems, name) {
+			problems = append(problems, fmt.Sprintf("log.levels: unknown subsystem %v, must be one of %v", name, strings.Join(logSubsystems, ", ")))
+		} else if _, err := parseLogLevel(level); err != nil {
+			problems = append(problems, "log.levels."+name+": "+err.Error())
+		}
+	}
+	return problems
+}
+
+// parseLogLevels parses subsystem levels written as "ai=debug,twitch=warn".
+func parseLogLevels(levels string) map[string]string {
+	parsed := map[string]string{}
+	for _, pair := range strings.Split(levels, ",") {
+		name, level, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		if name != "" {
+			parsed[name] = level
+		}
+	}
+	return parsed
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return l, fmt.Errorf("unknown level %q, must be debug, info, warn or error", level)
+	}
+	return l, nil
+}
+
+// Loggers for each subsystem. They write through the handler set by
+// SetupLogging, and to stderr as text at info level until then.
+var (
+	ircLog     = newSubsystemLogger(LogIRC)
+	commandLog = newSubsystemLogger(LogCommands)
+	twitchLog  = newSubsystemLogger(LogTwitch)
+	aiLog      = newSubsystemLogger(LogAI)
+	apiLog     = newSubsystemLogger(LogAPI)
+	authLog    = newSubsystemLogger(LogAuth)
+	dbLog      = newSubsystemLogger(LogDB)
+)
+
+var logging = struct {
+	mu      sync.RWMutex
+	handler slog.Handler
+	levels  map[string]*slog.LevelVar
+}{
+	handler: newLogHandler(os.Stderr, LogConfig{Format: LogText}),
+	levels:  map[string]*slog.LevelVar{},
+}
+
+func newSubsystemLogger(name string) *slog.Logger {
+	level := &slog.LevelVar{}
+	logging.levels[name] = level
+	return slog.New(&subsystemHandler{level: level}).With("subsystem", name)
+}
+
+// SetupLogging sets the format and levels of every logger.
+func SetupLogging(w io.Writer, config LogConfig) error {
+	if problems := config.validate(); len(problems) > 0 {
+		return fmt.Errorf("invalid logging configuration:\n  %v", strings.Join(problems, "\n  "))
+	}
+
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	logging.handler = newLogHandler(w, config)
+	for name, level := range logging.levels {
+		l, _ := parseLogLevel(config.Level)
+		if override, ok := config.Levels[name]; ok {
+			l, _ = parseLogLevel(override)
+		}
+		level.Set(l)
+	}
+	return nil
+}
+
+// Attributes that are never logged, and those only logged with
+// LogConfig.Prompts.
+var (
+	secretLogKeys = []string{"token", "access_token", "refresh_token", "authorization", "api_key", "secret"}
+	promptLogKeys = []string{"prompt", "completion"}
+	// Tokens that may appear inside other values, such as error messages
+	secretLogPattern = regexp.MustCompile(`sk-[A-Za-z0-9_-]{8,}|oauth:[A-Za-z0-9]+|Bearer [A-Za-z0-9._-]+`)
+)
+
+func newLogHandler(w io.Writer, config LogConfig) slog.Handler {
+	options := &slog.HandlerOptions{
+		// Filtering is done by subsystemHandler
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch {
+			case lo.Contains(secretLogKeys, a.Key):
+				return slog.String(a.Key, "[redacted]")
+			case lo.Contains(promptLogKeys, a.Key) && !config.Prompts:
+				return slog.String(a.Key, fmt.Sprintf("[redacted %v bytes]", len(a.Value.String())))
+			}
+			switch v := a.Value.Any().(type) {
+			case string:
+				return slog.String(a.Key, secretLogPattern.ReplaceAllString(v, "[redacted]"))
+			case error:
+				return slog.String(a.Key, secretLogPattern.ReplaceAllString(v.Error(), "[redacted]"))
+			}
+			return a
+		},
+	}
+	if config.Format == LogJSON {
+		return slog.NewJSONHandler(w, options)
+	}
+	return slog.NewTextHandler(w, options)
+}
+
+// subsystemHandler filters records by its subsystem's level and passes them
+// to the current handler.
+type subsystemHandler struct {
+	level *slog.LevelVar
+	// Applied to the current handler, from WithAttrs and WithGroup
+	with []func(slog.Handler) slog.Handler
+}
+
+func (h *subsystemHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	logging.mu.RLock()
+	handler := logging.handler
+	logging.mu.RUnlock()
+
+	for _, with := range h.with {
+		handler = with(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.extend(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.extend(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *subsystemHandler) extend(with func(slog.Handler) slog.Handler) slog.Handler {
+	return &subsystemHandler{level: h.level, with: append(h.with[:len(h.with):len(h.with)], with)}
+}
+
+// log returns a logger for a command, with the channel, user and command as
+// fields.
+func (d *Data) log(logger *slog.Logger) *slog.Logger {
+	return logger.With("channel", d.Channel, "user", d.User, "command", d.Command)
+}
+
+// messageLog returns a logger for a chat message, with the channel and user
+// as fields.
+func messageLog(logger *slog.Logger, e *irc.PrivateMessage) *slog.Logger {
+	return logger.With("channel", e.Channel, "user", e.User.Name)
+}
+
+// logRequests logs each API request once it has been served. The pattern of
+// the route is logged rather than the path, which may hold secrets such as the
+// tokens of incoming webhooks.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		apiLog.Info("served request",
+			"method", r.Method,
+			"host", r.Host,
+			"route", chi.RouteContext(r.Context()).RoutePattern(),
+			"status", ww.Status(),
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start),
+			"remote", r.RemoteAddr,
+		)
+	})
+}
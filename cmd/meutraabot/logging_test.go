@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestLogging(t *testing.T) {
+	out := &bytes.Buffer{}
+	t.Cleanup(func() { SetupLogging(os.Stderr, defaultConfig().Log) })
+
+	if err := SetupLogging(out, LogConfig{Format: LogJSON, Level: "info", Levels: map[string]string{LogAI: "debug", LogTwitch: "error"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Data{Channel: "streamer", User: "viewer", Command: "!ask"}
+	d.log(aiLog).Debug("built prompt", "prompt", "secret prompt", "token", "abc")
+	twitchLog.Warn("filtered out")
+	commandLog.Debug("filtered out")
+	commandLog.Error("failed", "err", errors.New("bad key sk-"+strings.Repeat("a", 48)))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %v", out)
+	}
+
+	entry := map[string]any{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"subsystem": LogAI,
+		"channel":   "streamer",
+		"user":      "viewer",
+		"command":   "!ask",
+		"prompt":    "[redacted 13 bytes]",
+		"token":     "[redacted]",
+	} {
+		if entry[key] != value {
+			t.Errorf("expected %v to be %q, got %q", key, value, entry[key])
+		}
+	}
+	if strings.Contains(lines[1], "sk-") {
+		t.Errorf("expected the key in the error to be redacted, got %v", lines[1])
+	}
+
+	if err := SetupLogging(out, LogConfig{Format: LogText, Level: "loud"}); err == nil {
+		t.Error("expected an unknown level to be an error")
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	out := &bytes.Buffer{}
+	t.Cleanup(func() { SetupLogging(os.Stderr, defaultConfig().Log) })
+	if err := SetupLogging(out, LogConfig{Format: LogText, Level: "info"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(logRequests)
+	r.Post("/hooks/{token}", func(w http.ResponseWriter, r *http.Request) {})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/hooks/secret-token", nil))
+
+	if !strings.Contains(out.String(), "route=/hooks/{token}") || strings.Contains(out.String(), "secret-token") {
+		t.Errorf("expected the route to be logged without the token, got %v", out)
+	}
+}
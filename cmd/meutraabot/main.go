@@ -5,12 +5,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"flag"
 	"fmt"
-	l "log"
+	"log/slog"
 	"math"
 	"math/rand"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"text/template"
@@ -19,6 +21,7 @@ import (
 	irc "github.com/gempir/go-twitch-irc/v3"
 	"github.com/meutraa/meutraabot/pkg/db"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 )
 
 type Message struct {
@@ -26,23 +29,19 @@ type Message struct {
 	Body    string
 }
 
-func log(channel, username, message string, err error) {
-	fmt.Printf("[%v]", channel)
-	if username != "" {
-		fmt.Printf(" %v", username)
-	}
-	if message != "" {
-		fmt.Printf(": %v", message)
-	}
-	if err != nil {
-		fmt.Printf(": %v", err.Error())
-	}
-	fmt.Printf("\n")
-}
-
 func main() {
 	rand.Seed(time.Now().UnixNano())
-	if err := run(); err != nil {
+	flag.Usage = usage
+	flag.Parse()
+
+	var err error
+	if flag.NArg() > 0 {
+		err = runCommand(flag.Arg(0), flag.Args()[1:])
+	} else {
+		err = run()
+	}
+
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -53,9 +52,19 @@ func run() error {
 
 	s.history = make(map[string][]*irc.PrivateMessage)
 	s.conversations = make(map[string][]*irc.PrivateMessage)
-	s.oauth = make(chan string)
+	s.tokenChanged = make(chan struct{})
+	s.tokenCheck = make(chan struct{}, 1)
 
-	if err := s.ReadEnvironmentVariables(); nil != err {
+	config, err := LoadConfig()
+	if nil != err {
+		return err
+	}
+	if err := config.Validate(); nil != err {
+		return err
+	}
+	s.config = config
+
+	if err := SetupLogging(os.Stderr, config.Log); nil != err {
 		return err
 	}
 
@@ -66,51 +75,99 @@ func run() error {
 		s.Close()
 	}()
 
+	if err := s.PrepareSecrets(); nil != err {
+		return errors.Wrap(err, "unable to prepare secrets")
+	}
+
 	s.PrepareAPI()
+	s.serveMetrics()
 
 	if err := s.PrepareTwitchClient(); nil != err {
 		return err
 	}
 
-	s.EnsureValidUserToken()
-
-	// Get self info
-	bot, err := User(s.twitch, "", "")
-	if nil != err {
-		return errors.Wrap(err, "unable to find user for bot account")
-	}
-	s.selfLogin = bot.Login
-	s.selfID = bot.ID
+	go s.superviseToken()
 
+	// The API keeps serving while the bot waits to be authorized
+	errs := make(chan error, 1)
 	go func() {
+		s.WaitForAuthorization()
+
+		// Get self info
+		bot, err := User(s.twitch, "", "")
+		if nil != err {
+			errs <- errors.Wrap(err, "unable to find user for bot account")
+			return
+		}
+		s.selfLogin = bot.Login
+		s.selfID = bot.ID
+
+		failures := 0
 		for {
-			if err := s.PrepareIRC(); nil != err {
-				l.Println("unable to connect to irc", err)
+			s.WaitForAuthorization()
+
+			connected := time.Now()
+			err := s.PrepareIRC()
+			if err == irc.ErrLoginAuthenticationFailed {
+				s.checkToken()
 			}
-			s.EnsureValidUserToken()
-			time.Sleep(30 * time.Second)
+
+			// A connection that lasted a while was not a failure to connect
+			if time.Since(connected) > 10*time.Minute {
+				failures = 0
+			}
+			failures++
+			wait := backoff(failures, 5*time.Second, 5*time.Minute)
+			ircLog.Warn("disconnected from irc", "retry_in", wait, "err", err)
+			time.Sleep(wait)
 		}
 	}()
 
 	go func() {
 		for {
-			l.Println("waiting one hour to validate again")
+			s.pruneMessages()
+			s.pruneWebhookDeliveries()
 			time.Sleep(time.Hour)
-			s.EnsureValidUserToken()
 		}
 	}()
 
 	// Create a channel for the OS to notify us of interrupts/signals
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
-	<-interrupt
-	return nil
+	select {
+	case <-interrupt:
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
+// builtins are the commands handled by handleCommand itself.
+var builtins = []string{
+	"+join",
+	"+leave",
+	"+approve",
+	"+unapprove",
+	"+get",
+	"+set",
+	"+unset",
+	"+list",
+	"+gget",
+	"+gset",
+	"+gunset",
+	"+glist",
+	"+functions",
+	"+data",
+	"+test",
+	"+history",
+	"+setnum",
+	"+builtins",
 }
 
 func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) string {
 	text := e.Message
 
-	isOwner := e.User.ID == s.env.twitchOwnerID || e.User.ID == ""
+	isOwner := e.User.ID == s.config.Twitch.OwnerID || e.User.ID == ""
 	isAdmin := (e.User.Name == e.Channel) || isOwner
 	isMod := e.Tags["mod"] == "1" || isAdmin
 	isSub := e.Tags["subscriber"] == "1"
@@ -163,46 +220,59 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 	functions := s.FuncMap(ctx, data, e)
 	templates := make(map[string]string)
 
+	// Builtins are counted whether or not the user may run them
+	if lo.Contains(builtins, command) {
+		commandsMatched.WithLabelValues(e.Channel, "builtin").Inc()
+	}
+
 	// Built-in commands
 	switch {
 	case command == "+approve" && isAdmin && argCount == 1:
-		if err := s.q.Approve(ctx, db.ApproveParams{
-			ChannelID: e.RoomID,
-			UserID:    selectedUserID,
-			Manual:    true,
-		}); nil != err {
-			log(data.Channel, data.User, "unable to approve", err)
+		// The selected user is the one approved
+		target := data
+		target.User = data.SelectedUser
+		target.UserID = data.SelectedUserID
+
+		before := s.approvalState(ctx, target)
+		if err := s.approveUser(ctx, target); nil != err {
+			data.log(commandLog).Error("unable to approve", "err", err)
 			return "failed to approve user"
 		}
-
-		// A little hack
-		data.User = data.SelectedUser
-		data.UserID = data.SelectedUserID
-
-		s.funcUnban(ctx, data)
+		s.audit(ctx, target.ChannelID, e.User.ID, e.User.Name, AuditApprovalGranted, before, s.approvalState(ctx, target))
 		return ""
 	case command == "+unapprove" && isAdmin && argCount == 1:
-		if err := s.q.Unapprove(ctx, db.UnapproveParams{
-			ChannelID: e.RoomID, UserID: selectedUserID,
-		}); nil != err {
-			log(data.Channel, data.User, "unable to unapprove", err)
+		// The selected user is the one approved
+		target := data
+		target.User = data.SelectedUser
+		target.UserID = data.SelectedUserID
+
+		before := s.approvalState(ctx, target)
+		if err := s.unapproveUser(ctx, target); nil != err {
+			data.log(commandLog).Error("unable to unapprove", "err", err)
 			return "failed to approve user"
 		}
-
-		// A little hack
-		data.User = data.SelectedUser
-		data.UserID = data.SelectedUserID
-
-		s.funcBan(ctx, data, 0, "bot unapproved")
+		s.audit(ctx, target.ChannelID, e.User.ID, e.User.Name, AuditApprovalRevoked, before, nil)
 		return ""
 	case command == "+leave":
+		before := s.channelState(ctx, e.User.ID)
 		if err := s.q.DeleteChannel(ctx, e.User.ID); nil != err {
 			return "failed to leave channel"
 		}
+		s.channelChanged(e.User.ID)
+		s.audit(ctx, e.User.ID, e.User.ID, e.User.Name, AuditChannelUnregistered, before, nil)
+		if err := s.notifyLeft(ctx, e.User.ID, ChannelEvent{Login: e.User.Name}); nil != err {
+			messageLog(commandLog, e).Error("unable to delete webhooks", "err", err)
+		}
+		if err := s.secrets.Delete(ctx, channelTokenSecret(e.User.ID)); nil != err {
+			messageLog(commandLog, e).Error("unable to delete openai token", "err", err)
+		}
+		if err := deleteIncomingWebhooks(ctx, s.q, e.User.ID); nil != err {
+			messageLog(commandLog, e).Error("unable to delete incoming webhooks", "err", err)
+		}
 
 		go func() {
 			time.Sleep(1 * time.Second)
-			s.irc.Depart(e.User.Name)
+			s.chat().Depart(e.User.Name)
 		}()
 		return "Bye " + e.User.Name + "👋"
 	case command == "+join":
@@ -212,29 +282,37 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 		if argCount == 1 && !isOwner {
 			return ""
 		} else if argCount == 1 {
+			before := s.channelState(ctx, selectedUserID)
 			if err := s.q.CreateChannel(ctx, selectedUserID); nil != err {
-				log(data.Channel, data.User, "unable to add channel", err)
+				data.log(commandLog).Error("unable to add channel", "err", err)
 				return "unable to join channel"
 			}
+			s.channelChanged(selectedUserID)
+			s.audit(ctx, selectedUserID, e.User.ID, e.User.Name, AuditChannelRegistered, before, s.channelState(ctx, selectedUserID))
+			s.notify(selectedUserID, WebhookChannelJoined, ChannelEvent{Login: selectedUser})
 			s.JoinChannels([]string{selectedUser}, []string{selectedUserID})
 			msg := "Hi " + selectedUser + " 👋"
 			func() {
 				time.Sleep(time.Second * 2)
-				s.irc.Say(selectedUser, msg)
+				s.chat().Say(selectedUser, msg)
 			}()
 			return msg
 		}
 
+		before := s.channelState(ctx, e.User.ID)
 		if err := s.q.CreateChannel(ctx, e.User.ID); nil != err {
-			log(data.Channel, data.User, "unable to add channel", err)
+			data.log(commandLog).Error("unable to add channel", "err", err)
 			return "unable to join channel"
 		}
+		s.channelChanged(e.User.ID)
+		s.audit(ctx, e.User.ID, e.User.ID, e.User.Name, AuditChannelRegistered, before, s.channelState(ctx, e.User.ID))
+		s.notify(e.User.ID, WebhookChannelJoined, ChannelEvent{Login: e.User.Name})
 
 		s.JoinChannels([]string{e.User.Name}, []string{e.User.ID})
 		msg := "Hi " + e.User.Name + " 👋"
 		func() {
 			time.Sleep(time.Second * 2)
-			s.irc.Say(e.User.Name, msg)
+			s.chat().Say(e.User.Name, msg)
 		}()
 		return msg
 	case command == "+data":
@@ -258,7 +336,7 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 			Name:      args[0],
 		})
 		if nil != err {
-			log(data.Channel, data.User, "unable to gget "+args[0], err)
+			data.log(commandLog).Error("unable to get global command", "name", args[0], "err", err)
 			return ""
 		}
 		return "command: " + tmpl
@@ -268,29 +346,47 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 			Name:      args[0],
 		})
 		if nil != err {
-			log(data.Channel, data.User, "unable to get "+args[0], err)
+			data.log(commandLog).Error("unable to get command", "name", args[0], "err", err)
 			return ""
 		}
 		return "command: " + tmpl
+	case command == "+history" && isMod && argCount > 0:
+		username := strings.ToLower(strings.TrimPrefix(args[0], "@"))
+		return s.funcHistory(ctx, data, username, strings.Join(args[1:], " "))
+	case command == "+setnum" && isMod && argCount == 1:
+		before := s.numberState(ctx, e.RoomID, args[0])
+		if err := s.q.DeleteNumber(ctx, db.DeleteNumberParams{
+			ChannelID: e.RoomID,
+			Name:      args[0],
+		}); nil != err {
+			data.log(commandLog).Error("unable to reset number", "name", args[0], "err", err)
+			return "unable to reset number"
+		}
+		s.audit(ctx, e.RoomID, e.User.ID, e.User.Name, AuditNumberDeleted, before, nil)
+		s.publish(e.RoomID, EventNumber, NumberEvent{Name: args[0]})
+		return fmt.Sprintf("number %v reset", args[0])
+	case command == "+setnum" && isMod && argCount == 2:
+		value, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return "the value must be a whole number"
+		}
+		if err := validateNumberName(args[0]); err != nil {
+			return err.Error()
+		}
+		before := s.numberState(ctx, e.RoomID, args[0])
+		if err := s.q.SetNumber(ctx, db.SetNumberParams{
+			ChannelID: e.RoomID,
+			Name:      args[0],
+			Value:     value,
+		}); nil != err {
+			data.log(commandLog).Error("unable to set number", "name", args[0], "err", err)
+			return "unable to set number"
+		}
+		s.audit(ctx, e.RoomID, e.User.ID, e.User.Name, AuditNumberSet, before, s.numberState(ctx, e.RoomID, args[0]))
+		s.publish(e.RoomID, EventNumber, NumberEvent{Name: args[0], Value: value})
+		return fmt.Sprintf("number %v set to %v", args[0], value)
 	case command == "+builtins":
-		return strings.Join([]string{
-			"+join",
-			"+leave",
-			"+approve",
-			"+unapprove",
-			"+get",
-			"+set",
-			"+unset",
-			"+list",
-			"+gget",
-			"+gset",
-			"+gunset",
-			"+glist",
-			"+functions",
-			"+data",
-			"+test",
-			"+builtins",
-		}, " ")
+		return strings.Join(builtins, " ")
 	case command == "+functions":
 		return strings.Join([]string{
 			"reply(message)",
@@ -307,31 +403,42 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 			"json(key, json)",
 		}, " ")
 	case command == "+gunset" && isOwner && argCount == 1:
+		before := s.commandState(ctx, "0", args[0])
 		if err := s.q.DeleteCommand(ctx, db.DeleteCommandParams{
 			ChannelID: "0",
 			Name:      args[0],
 		}); nil != err {
-			log(data.Channel, data.User, "unable to gunset "+args[0], err)
+			data.log(commandLog).Error("unable to delete global command", "name", args[0], "err", err)
 			return "unable to delete global command"
 		}
+		s.audit(ctx, "0", e.User.ID, e.User.Name, AuditCommandDeleted, before, nil)
+		s.notify("0", WebhookCommandChanged, CommandChangedEvent{Name: args[0], Action: "deleted", UpdatedBy: e.User.ID})
 	case command == "+unset" && isMod && argCount == 1:
+		before := s.commandState(ctx, e.RoomID, args[0])
 		if err := s.q.DeleteCommand(ctx, db.DeleteCommandParams{
 			ChannelID: e.RoomID,
 			Name:      args[0],
 		}); nil != err {
-			log(data.Channel, data.User, "unable to unset "+args[0], err)
+			data.log(commandLog).Error("unable to delete command", "name", args[0], "err", err)
 			return "unable to delete command"
 		}
+		s.audit(ctx, e.RoomID, e.User.ID, e.User.Name, AuditCommandDeleted, before, nil)
+		s.notify(e.RoomID, WebhookCommandChanged, CommandChangedEvent{Name: args[0], Action: "deleted", UpdatedBy: e.User.ID})
 	case command == "+gset" && isOwner && argCount > 1:
 		strs := strings.SplitN(text, " ", 3)[1:]
+		before := s.commandState(ctx, "0", strs[0])
 		if err := s.q.SetCommand(ctx, db.SetCommandParams{
 			ChannelID: "0",
 			Name:      strs[0],
 			Template:  strs[1],
+			UpdatedBy: e.User.ID,
+			UpdatedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
 		}); nil != err {
-			log(data.Channel, data.User, "unable to gset "+strs[0]+" "+strs[1], err)
+			data.log(commandLog).Error("unable to set global command", "name", strs[0], "err", err)
 			return "unable to set global command"
 		}
+		s.audit(ctx, "0", e.User.ID, e.User.Name, AuditCommandSet, before, s.commandState(ctx, "0", strs[0]))
+		s.notify("0", WebhookCommandChanged, CommandChangedEvent{Name: strs[0], Action: "set", Template: strs[1], UpdatedBy: e.User.ID})
 		return "command set"
 	case command == "+set" && isMod && argCount > 0:
 		strs := strings.SplitN(text, " ", 3)[1:]
@@ -339,25 +446,30 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 		if argCount > 1 {
 			template = strs[1]
 		}
+		before := s.commandState(ctx, e.RoomID, strs[0])
 		if err := s.q.SetCommand(ctx, db.SetCommandParams{
 			ChannelID: e.RoomID,
 			Name:      strs[0],
 			Template:  template,
+			UpdatedBy: e.User.ID,
+			UpdatedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
 		}); nil != err {
-			log(data.Channel, data.User, "unable to set "+strs[0]+" "+strs[1], err)
+			data.log(commandLog).Error("unable to set command", "name", strs[0], "err", err)
 			return "unable to set command"
 		}
+		s.audit(ctx, e.RoomID, e.User.ID, e.User.Name, AuditCommandSet, before, s.commandState(ctx, e.RoomID, strs[0]))
+		s.notify(e.RoomID, WebhookCommandChanged, CommandChangedEvent{Name: strs[0], Action: "set", Template: template, UpdatedBy: e.User.ID})
 		return fmt.Sprintf("command %v set", strs[0])
 	case command == "+test" && isMod:
 		templates["test"] = strings.Join(args, " ")
 	default:
 		message := strings.ToLower(text)
-		commands, err := s.q.GetMatchingCommands(ctx,
-			e.RoomID,
-			message,
-		)
+		commands, err := s.q.GetMatchingCommands(ctx, db.GetMatchingCommandsParams{
+			ChannelID: e.RoomID,
+			Message:   message,
+		})
 		if nil != err && err != sql.ErrNoRows {
-			log(data.Channel, data.User, "unable to get for "+message, err)
+			data.log(commandLog).Error("unable to get matching commands", "err", err)
 			return ""
 		}
 
@@ -379,17 +491,17 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 		}
 	}
 
-	// log(data.Channel, data.User, "Matched templates len "+fmt.Sprint(len(templates)), nil)
+	data.log(commandLog).Debug("matched commands", "count", len(templates))
 	if len(templates) == 0 {
 		// get the channel settings
 		settings, err := s.q.GetChannel(ctx, data.ChannelID)
 		if err != nil {
-			log(data.Channel, data.User, "unable to get channel settings", err)
+			data.log(commandLog).Error("unable to get channel settings", "err", err)
 			return ""
 		}
 
 		// Not responding, but might send random message
-		history, ok := s.history[e.Channel]
+		history, ok := s.channelHistory(e.Channel)
 		if !ok {
 			return ""
 		}
@@ -408,7 +520,7 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 			return ""
 		}
 
-		// find how many messages it has been since meuua last said something
+		// find how many messages it has been since the bot last said something
 		count := 0
 		totalMessages := 0
 		botMessages := 0
@@ -457,26 +569,50 @@ func (s *Server) handleCommand(ctx context.Context, e *irc.PrivateMessage) strin
 	str := strings.Builder{}
 	i := 0
 	// Execute command
-	for _, tplt := range templates {
+	for name, tplt := range templates {
 		if i > 0 {
 			str.WriteByte('\n')
 		}
-		tmpl, err := template.New(text).Funcs(functions).Parse(tplt)
+		commandsMatched.WithLabelValues(e.Channel, "custom").Inc()
+		out, err := executeTemplate(data.log(commandLog).With("name", name), text, tplt, functions, data)
 		if err != nil {
-			return "command template is broken: " + err.Error()
-		}
-
-		out := bytes.Buffer{}
-		if err := tmpl.Execute(&out, data); nil != err {
-			return "command executed wrongly: " + err.Error()
-		}
-		str.WriteString(out.String())
+			return err.Error()
+		}
+		str.WriteString(out)
+		s.publish(data.ChannelID, EventCommand, CommandEvent{
+			Name:   name,
+			UserID: data.UserID,
+			User:   data.User,
+			Output: out,
+		})
 		i++
 	}
 
 	return str.String()
 }
 
+// executeTemplate parses and runs a command template, counting its errors
+// and duration. Errors are fit to be sent to chat.
+func executeTemplate(log *slog.Logger, name, tplt string, functions template.FuncMap, data any) (string, error) {
+	start := time.Now()
+	tmpl, err := template.New(name).Funcs(functions).Parse(tplt)
+	if err != nil {
+		templateErrors.WithLabelValues("parse").Inc()
+		log.Warn("unable to parse template", "err", err)
+		return "", errors.Wrap(err, "command template is broken")
+	}
+
+	out := bytes.Buffer{}
+	err = tmpl.Execute(&out, data)
+	templateDuration.Observe(time.Since(start).Seconds())
+	if nil != err {
+		templateErrors.WithLabelValues("execute").Inc()
+		log.Warn("unable to execute template", "err", err)
+		return "", errors.Wrap(err, "command executed wrongly")
+	}
+	return out.String(), nil
+}
+
 func splitRecursive(str string) []string {
 	if len(str) == 0 {
 		return []string{}
@@ -487,78 +623,118 @@ func splitRecursive(str string) []string {
 	return append([]string{string(str[0:480] + "…")}, splitRecursive(str[480:])...)
 }
 
+// handleMessage responds to a message from chat.
 func (s *Server) handleMessage(e irc.PrivateMessage) {
-	// Add event to history
-	if _, ok := s.history[e.Channel]; !ok {
-		s.history[e.Channel] = make([]*irc.PrivateMessage, 0)
+	res := s.processMessage(&e)
+	go s.sendResponse(&e, res)
+}
+
+// processMessage records a message in the history and chat log, returning the
+// bot's response to it.
+func (s *Server) processMessage(e *irc.PrivateMessage) string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	s.receivedMessage()
+
+	// Add event to history, seeding it from the chat log after a restart
+	if _, ok := s.channelHistory(e.Channel); !ok {
+		s.seedHistory(e.Channel, s.loadHistory(ctx, e.Channel, e.RoomID))
 	}
-	s.history[e.Channel] = append(s.history[e.Channel], &e)
+	s.addHistory(e)
+	s.logMessage(ctx, e.RoomID, e)
+	s.publishMessage(e.RoomID, e)
 
 	if s.selfID == e.User.ID {
-		return
+		return ""
 	}
+	messagesHandled.WithLabelValues(e.Channel).Inc()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
-
-	res := s.handleCommand(ctx, &e)
-	log(e.Channel, e.User.Name, e.Message, nil)
+	res := s.handleCommand(ctx, e)
+	messageLog(ircLog, e).Info("received message", "text", e.Message)
 	if res != "" {
-		log(e.Channel, "self", res, nil)
+		messageLog(ircLog, e).Info("responding", "text", res)
 	}
-	res = strings.ReplaceAll(res, "\\n", "\n")
-	go func(res string) {
-		for _, message := range strings.Split(res, "\n") {
-			for _, parts := range splitRecursive(strings.TrimSpace(message)) {
-				if parts == "" {
-					continue
-				}
-				reply := false
-				if strings.HasPrefix(parts, "reply::") {
-					parts = strings.TrimPrefix(parts, "reply::")
-					reply = true
-				}
-				if strings.HasPrefix(parts, "delay::") {
-					parts = strings.TrimPrefix(parts, "delay::")
-					// calculate time to type parts in seconds at 80wpm
-					chars := len(parts)
-					seconds := int(math.Round(float64(chars) / 5))
+	return strings.ReplaceAll(res, "\\n", "\n")
+}
+
+// selfMessage returns a message from the bot in a channel, for responses that
+// are not to anything said in chat.
+func (s *Server) selfMessage(channel, channelID string) *irc.PrivateMessage {
+	return &irc.PrivateMessage{
+		Channel: channel,
+		RoomID:  channelID,
+		User: irc.User{
+			Name:        s.selfLogin,
+			DisplayName: s.selfLogin,
+			ID:          s.selfID,
+		},
+	}
+}
+
+// sendResponse sends each line of a response to a message, splitting long
+// lines and handling the reply:: and delay:: prefixes.
+func (s *Server) sendResponse(e *irc.PrivateMessage, res string) {
+	chat := s.chat()
+	lines := strings.Split(res, "\n")
+	outboundQueue.Add(float64(len(lines)))
+	for _, message := range lines {
+		for _, parts := range splitRecursive(strings.TrimSpace(message)) {
+			if parts == "" {
+				continue
+			}
+			reply := false
+			if strings.HasPrefix(parts, "reply::") {
+				parts = strings.TrimPrefix(parts, "reply::")
+				// Messages the bot sends of its own accord have nothing to reply to
+				reply = e.ID != ""
+			}
+			if strings.HasPrefix(parts, "delay::") {
+				parts = strings.TrimPrefix(parts, "delay::")
+				// calculate time to type parts in seconds at 80wpm
+				chars := len(parts)
+				seconds := int(math.Round(float64(chars) / 5))
 
+				if !s.noDelay {
 					time.Sleep(time.Second * time.Duration(seconds))
 				}
-				if reply {
-					if strings.HasPrefix(parts, "@") && strings.Contains(parts, " ") {
-						// remove the first word from parts
-						parts = strings.SplitN(parts, " ", 2)[1]
-					}
-				}
-				add := irc.PrivateMessage{
-					Channel: e.Channel,
-					Reply:   &irc.Reply{},
-					Message: parts,
-					User: irc.User{
-						Name:        s.selfLogin,
-						DisplayName: s.selfLogin,
-						ID:          s.selfID,
-					},
-				}
-				if reply {
-					add.Reply = &irc.Reply{
-						ParentMsgID:       e.ID,
-						ParentUserID:      e.User.ID,
-						ParentUserLogin:   e.User.Name,
-						ParentDisplayName: e.User.DisplayName,
-						ParentMsgBody:     e.Message,
-					}
+			}
+			if reply {
+				if strings.HasPrefix(parts, "@") && strings.Contains(parts, " ") {
+					// remove the first word from parts
+					parts = strings.SplitN(parts, " ", 2)[1]
 				}
-				s.history[e.Channel] = append(s.history[e.Channel], &add)
-				if reply {
-					s.conversations[e.Channel] = append(s.conversations[e.Channel], &add)
-					s.irc.Reply(e.Channel, e.ID, parts)
-				} else {
-					s.irc.Say(e.Channel, parts)
+			}
+			add := irc.PrivateMessage{
+				Channel: e.Channel,
+				Reply:   &irc.Reply{},
+				Message: parts,
+				User: irc.User{
+					Name:        s.selfLogin,
+					DisplayName: s.selfLogin,
+					ID:          s.selfID,
+				},
+			}
+			if reply {
+				add.Reply = &irc.Reply{
+					ParentMsgID:       e.ID,
+					ParentUserID:      e.User.ID,
+					ParentUserLogin:   e.User.Name,
+					ParentDisplayName: e.User.DisplayName,
+					ParentMsgBody:     e.Message,
 				}
 			}
+			s.addHistory(&add)
+			s.logMessage(context.Background(), e.RoomID, &add)
+			s.publishMessage(e.RoomID, &add)
+			if reply {
+				s.addConversation(&add)
+				chat.Reply(e.Channel, e.ID, parts)
+			} else {
+				chat.Say(e.Channel, parts)
+			}
 		}
-	}(res)
+		// A line is waiting until all of it, including its delay, was sent
+		outboundQueue.Dec()
+	}
 }
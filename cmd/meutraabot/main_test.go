@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	irc "github.com/gempir/go-twitch-irc/v3"
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// Users known to the fake Twitch API in every test.
+var (
+	testBot         = helix.User{ID: "100", Login: "meuua", DisplayName: "meuua"}
+	testOwner       = helix.User{ID: "1", Login: "owner", DisplayName: "Owner"}
+	testBroadcaster = helix.User{ID: "2", Login: "streamer", DisplayName: "Streamer"}
+	testViewer      = helix.User{ID: "3", Login: "viewer", DisplayName: "Viewer"}
+	testSpamBot     = helix.User{ID: "4", Login: "spambot", DisplayName: "spambot"}
+)
+
+// newTestServer returns a server with a fresh sqlite database and fake Twitch
+// and chat backends.
+func newTestServer(t *testing.T) (*Server, *FakeTwitch, *FakeChat) {
+	t.Helper()
+
+	ctx := context.Background()
+	conn, err := db.Open(ctx, db.SQLite, "file:"+filepath.Join(t.TempDir(), "db.sql")+"?mode=rwc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if _, err := db.Migrate(ctx, db.SQLite, conn); err != nil {
+		t.Fatal(err)
+	}
+	q, err := db.PrepareQuerier(ctx, db.SQLite, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := generateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets, err := NewSecretStore(q, SecretsConfig{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := defaultConfig()
+	config.Twitch.OwnerID = testOwner.ID
+
+	twitch := NewFakeTwitch(testBot, testOwner, testBroadcaster, testViewer, testSpamBot)
+	chat := &FakeChat{}
+	s := &Server{
+		conn:          conn,
+		q:             q,
+		config:        config,
+		secrets:       secrets,
+		twitch:        twitch,
+		irc:           chat,
+		twitchForUser: twitch.ForUser,
+		knownBots:     func() ([]Bot, error) { return nil, nil },
+		selfLogin:     testBot.Login,
+		selfID:        testBot.ID,
+		history:       map[string][]*irc.PrivateMessage{},
+		conversations: map[string][]*irc.PrivateMessage{},
+	}
+	return s, twitch, chat
+}
+
+// chatMessage returns a message sent by user in the broadcaster's channel.
+func chatMessage(user helix.User, text string, tags map[string]string) *irc.PrivateMessage {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return &irc.PrivateMessage{
+		User:    irc.User{ID: user.ID, Name: user.Login, DisplayName: user.DisplayName},
+		Tags:    tags,
+		Message: text,
+		Channel: testBroadcaster.Login,
+		RoomID:  testBroadcaster.ID,
+		ID:      "message-" + user.ID,
+	}
+}
+
+func TestHandleCommandSetAndRun(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestServer(t)
+
+	mod := map[string]string{"mod": "1"}
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+set ^!hi hello {{.User}}", mod)); res != "command ^!hi set" {
+		t.Errorf("unexpected response to +set: %q", res)
+	}
+
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "!hi", nil)); res != "hello viewer" {
+		t.Errorf("unexpected response to command: %q", res)
+	}
+
+	// Only mods can set commands
+	s.handleCommand(ctx, chatMessage(testViewer, "+set ^!bye bye", nil))
+	if _, err := s.q.GetCommand(ctx, db.GetCommandParams{ChannelID: testBroadcaster.ID, Name: "^!bye"}); err == nil {
+		t.Error("expected a viewer to be unable to set a command")
+	}
+
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+list", nil)); res != "^!hi" {
+		t.Errorf("unexpected response to +list: %q", res)
+	}
+}
+
+func TestHandleCommandModeration(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+test {{delete}}{{timeout 60 \"spam\"}}", map[string]string{"mod": "1"})); res != "" {
+		t.Errorf("unexpected response to +test: %q", res)
+	}
+
+	actions := twitch.TakeActions()
+	expected := []ModerationAction{
+		{Action: "delete", ChannelID: testBroadcaster.ID, MessageID: "message-" + testViewer.ID},
+		{Action: "ban", ChannelID: testBroadcaster.ID, UserID: testViewer.ID, Duration: 60, Reason: "spam"},
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, actions)
+	}
+	for i := range expected {
+		if actions[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], actions[i])
+		}
+	}
+}
+
+func TestHandleCommandApprove(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+
+	s.handleCommand(ctx, chatMessage(testBroadcaster, "+approve spambot", nil))
+	approved, err := s.q.IsApproved(ctx, db.IsApprovedParams{ChannelID: testBroadcaster.ID, UserID: testSpamBot.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved != 1 {
+		t.Error("expected spambot to be approved")
+	}
+
+	s.handleCommand(ctx, chatMessage(testBroadcaster, "+unapprove spambot", nil))
+	actions := twitch.TakeActions()
+	if len(actions) != 2 || actions[0].Action != "unban" || actions[1].Action != "ban" || actions[1].UserID != testSpamBot.ID {
+		t.Errorf("expected an unban and a ban, got %v", actions)
+	}
+
+	// Viewers can not approve anyone
+	s.handleCommand(ctx, chatMessage(testViewer, "+approve spambot", nil))
+	if actions := twitch.TakeActions(); len(actions) != 0 {
+		t.Errorf("expected no moderation, got %v", actions)
+	}
+}
+
+func TestHandleCommandSetNumber(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestServer(t)
+	mod := map[string]string{"mod": "1"}
+
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+setnum deaths 5", mod)); res != "number deaths set to 5" {
+		t.Errorf("unexpected response to +setnum: %q", res)
+	}
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+test {{addnum \"deaths\" 1}}{{getnum \"deaths\"}}", mod)); res != "6" {
+		t.Errorf("expected the number to be set, got %q", res)
+	}
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+setnum deaths many", mod)); res != "the value must be a whole number" {
+		t.Errorf("unexpected response to an invalid value: %q", res)
+	}
+
+	// Only mods can set numbers
+	s.handleCommand(ctx, chatMessage(testViewer, "+setnum deaths", nil))
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+test {{getnum \"deaths\"}}", mod)); res != "6" {
+		t.Errorf("expected a viewer to be unable to reset a number, got %q", res)
+	}
+
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+setnum deaths", mod)); res != "number deaths reset" {
+		t.Errorf("unexpected response to +setnum: %q", res)
+	}
+	if res := s.handleCommand(ctx, chatMessage(testViewer, "+test {{getnum \"deaths\"}}", mod)); res != "0" {
+		t.Errorf("expected the number to be reset, got %q", res)
+	}
+}
+
+func TestResponsesWhileReadingChat(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	// Responses to webhooks are sent while chat is being read
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.sendResponse(s.selfMessage(testBroadcaster.Login, testBroadcaster.ID), "hello")
+		}()
+		go func() {
+			defer wg.Done()
+			s.processMessage(chatMessage(testViewer, "hi", nil))
+		}()
+	}
+	wg.Wait()
+
+	if history, _ := s.channelHistory(testBroadcaster.Login); len(history) != 20 {
+		t.Errorf("expected 20 messages in the history, got %v", len(history))
+	}
+}
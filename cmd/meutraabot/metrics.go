@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	messagesHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meutraabot_messages_handled_total",
+		Help: "Chat messages handled, by channel.",
+	}, []string{"channel"})
+
+	commandsMatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meutraabot_commands_matched_total",
+		Help: "Commands run, by channel and kind (builtin or custom).",
+	}, []string{"channel", "kind"})
+
+	templateDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "meutraabot_template_duration_seconds",
+		Help:    "Time taken to parse and execute a command template.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	})
+
+	templateErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meutraabot_template_errors_total",
+		Help: "Command templates that failed, by stage (parse or execute).",
+	}, []string{"stage"})
+
+	twitchRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "meutraabot_twitch_request_duration_seconds",
+		Help:    "Latency of requests to Twitch, by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	twitchRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meutraabot_twitch_request_errors_total",
+		Help: "Requests to Twitch that failed or returned an error status, by endpoint.",
+	}, []string{"endpoint"})
+
+	moderationActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meutraabot_moderation_actions_total",
+		Help: "Bans, timeouts, unbans and message deletions issued, by action.",
+	}, []string{"action"})
+
+	aiRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meutraabot_ai_requests_total",
+		Help: "Completion requests sent to OpenAI, by result.",
+	}, []string{"result"})
+
+	aiTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "meutraabot_ai_tokens_total",
+		Help: "OpenAI tokens used, by type (prompt or completion).",
+	}, []string{"type"})
+
+	outboundQueue = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "meutraabot_outbound_queue_depth",
+		Help: "Chat messages waiting to be sent.",
+	})
+)
+
+// twitchEndpoint names a request to Twitch for metrics by its path, such as
+// helix/users or oauth2/token.
+func twitchEndpoint(r *http.Request) string {
+	host := r.URL.Hostname()
+	if host != "api.twitch.tv" && host != "id.twitch.tv" {
+		return "other"
+	}
+	return strings.Trim(r.URL.Path, "/")
+}
+
+// instrumentedTransport records the latency and errors of requests to Twitch.
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t instrumentedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	endpoint := twitchEndpoint(r)
+	start := time.Now()
+	res, err := t.next.RoundTrip(r)
+	twitchRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if err != nil || res.StatusCode >= http.StatusBadRequest {
+		twitchRequestErrors.WithLabelValues(endpoint).Inc()
+	}
+	return res, err
+}
+
+// countModeration records a moderation action that Twitch accepted.
+func countModeration(action string, status int) {
+	if status < http.StatusBadRequest {
+		moderationActions.WithLabelValues(action).Inc()
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	channel := testBroadcaster.Login
+
+	handled := testutil.ToFloat64(messagesHandled.WithLabelValues(channel))
+	timeouts := testutil.ToFloat64(moderationActions.WithLabelValues("timeout"))
+	failures := testutil.ToFloat64(templateErrors.WithLabelValues("execute"))
+	builtins := testutil.ToFloat64(commandsMatched.WithLabelValues(channel, "builtin"))
+
+	s.processMessage(chatMessage(testViewer, `+test {{timeout 60 "spam"}}`, map[string]string{"mod": "1"}))
+	s.processMessage(chatMessage(testViewer, `+test {{index .Arg 5}}`, map[string]string{"mod": "1"}))
+
+	if n := testutil.ToFloat64(messagesHandled.WithLabelValues(channel)) - handled; n != 2 {
+		t.Errorf("expected 2 messages handled, got %v", n)
+	}
+	if n := testutil.ToFloat64(moderationActions.WithLabelValues("timeout")) - timeouts; n != 1 {
+		t.Errorf("expected 1 timeout, got %v", n)
+	}
+	if n := testutil.ToFloat64(templateErrors.WithLabelValues("execute")) - failures; n != 1 {
+		t.Errorf("expected 1 template error, got %v", n)
+	}
+	if n := testutil.ToFloat64(commandsMatched.WithLabelValues(channel, "builtin")) - builtins; n != 2 {
+		t.Errorf("expected 2 builtin commands, got %v", n)
+	}
+
+	s.sendResponse(chatMessage(testViewer, "", nil), "one\ntwo")
+	if n := testutil.ToFloat64(outboundQueue); n != 0 {
+		t.Errorf("expected no messages waiting once sent, got %v", n)
+	}
+}
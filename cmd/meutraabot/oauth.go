@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Authorize the bot account by visiting a URL that redirects back to the
+	// OAuth handler
+	AuthFlowCode = "code"
+	// Authorize the bot account by entering a code on another device, for
+	// when the OAuth handler can not be reached
+	AuthFlowDevice = "device"
+)
+
+const (
+	twitchDeviceURL = "https://id.twitch.tv/oauth2/device"
+	twitchTokenURL  = "https://id.twitch.tv/oauth2/token"
+
+	// How long an authorization URL can be used for
+	codeAuthorizationTTL = time.Hour
+)
+
+// How often a device authorization is polled for its token, until Twitch asks
+// for it to be polled less often.
+var devicePollInterval = 5 * time.Second
+
+// The scopes the bot account is authorized with.
+var botScopes = []string{
+	"chat:edit",
+	"chat:read",
+	"channel:moderate",
+	"moderation:read",
+	"moderator:read:chatters",
+	"moderator:manage:chat_messages",
+	"moderator:manage:banned_users",
+	"moderator:manage:announcements",
+}
+
+// errNotAuthorized is returned while the bot account is waiting to be
+// authorized.
+var errNotAuthorized = errors.New("the bot account is not authorized")
+
+// RequestAuthorization makes sure an authorization of the bot account is
+// pending, logs how to complete it and returns errNotAuthorized. Pending
+// authorizations are kept in the database, so they survive a restart.
+func (s *Server) RequestAuthorization(ctx context.Context) error {
+	now := time.Now().UTC()
+	if err := s.q.DeleteExpiredAuthorizations(ctx, now); err != nil {
+		return errors.Wrap(err, "unable to delete expired authorizations")
+	}
+
+	auth, err := s.q.GetPendingAuthorization(ctx, db.GetPendingAuthorizationParams{
+		Flow:      s.config.Twitch.AuthFlow,
+		ExpiresAt: now,
+	})
+	if err == sql.ErrNoRows {
+		auth, err = s.createAuthorization(ctx)
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to start authorization")
+	}
+
+	switch auth.Flow {
+	case AuthFlowDevice:
+		authLog.Warn("authorize the bot account by entering the code", "code", auth.UserCode, "url", auth.VerificationUri)
+		s.pollDeviceAuthorization(auth)
+	default:
+		authLog.Warn("authorize the bot account", "url", s.twitch.GetAuthorizationURL(&helix.AuthorizationURLParams{
+			ResponseType: "code",
+			State:        auth.State,
+			Scopes:       botScopes,
+		}))
+	}
+	return errNotAuthorized
+}
+
+func (s *Server) createAuthorization(ctx context.Context) (db.Authorization, error) {
+	// The state is a random string to prevent CSRF attacks
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return db.Authorization{}, err
+	}
+
+	now := time.Now().UTC()
+	auth := db.Authorization{
+		State:     hex.EncodeToString(b),
+		Flow:      s.config.Twitch.AuthFlow,
+		CreatedAt: now,
+		ExpiresAt: now.Add(codeAuthorizationTTL),
+	}
+
+	if auth.Flow == AuthFlowDevice {
+		var res struct {
+			DeviceCode      string `json:"device_code"`
+			ExpiresIn       int    `json:"expires_in"`
+			UserCode        string `json:"user_code"`
+			VerificationURI string `json:"verification_uri"`
+			Message         string `json:"message"`
+		}
+		status, err := s.postTwitchForm(ctx, twitchDeviceURL, url.Values{
+			"client_id": {s.config.Twitch.ClientID},
+			"scopes":    {strings.Join(botScopes, " ")},
+		}, &res)
+		if err != nil {
+			return auth, errors.Wrap(err, "unable to request device code")
+		}
+		if status != http.StatusOK {
+			return auth, errors.New("unable to request device code: " + res.Message)
+		}
+
+		auth.DeviceCode = res.DeviceCode
+		auth.UserCode = res.UserCode
+		auth.VerificationUri = res.VerificationURI
+		auth.ExpiresAt = now.Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+
+	return auth, s.q.CreateAuthorization(ctx, db.CreateAuthorizationParams(auth))
+}
+
+// pollDeviceAuthorization polls for the token of a device authorization in
+// the background until it is granted, denied or expires.
+func (s *Server) pollDeviceAuthorization(auth db.Authorization) {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	if s.polling {
+		return
+	}
+	s.polling = true
+
+	go func() {
+		// Deferred calls run last first, so polling has stopped by the time
+		// a new authorization is started
+		restart := false
+		defer func() {
+			if restart {
+				s.checkToken()
+			}
+		}()
+		defer func() {
+			s.authMu.Lock()
+			s.polling = false
+			s.authMu.Unlock()
+		}()
+
+		ctx := context.Background()
+		interval := devicePollInterval
+		for time.Now().Before(auth.ExpiresAt) {
+			time.Sleep(interval)
+
+			var res struct {
+				AccessToken  string `json:"access_token"`
+				RefreshToken string `json:"refresh_token"`
+				Message      string `json:"message"`
+			}
+			status, err := s.postTwitchForm(ctx, twitchTokenURL, url.Values{
+				"client_id":     {s.config.Twitch.ClientID},
+				"client_secret": {s.config.Twitch.ClientSecret},
+				"scopes":        {strings.Join(botScopes, " ")},
+				"device_code":   {auth.DeviceCode},
+				"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+			}, &res)
+			switch {
+			case err != nil || status >= http.StatusInternalServerError:
+				authLog.Error("unable to poll device authorization", "status", status, "err", err)
+				continue
+			case res.Message == "authorization_pending":
+				continue
+			case res.Message == "slow_down":
+				interval += 5 * time.Second
+				continue
+			case status != http.StatusOK:
+				authLog.Warn("device authorization failed", "reason", res.Message)
+				if err := s.q.DeleteAuthorization(ctx, auth.State); err != nil {
+					authLog.Error("unable to delete authorization", "err", err)
+				}
+				// Start a new authorization
+				restart = true
+				return
+			}
+
+			if err := s.completeAuthorization(ctx, auth.State, res.AccessToken, res.RefreshToken); err != nil {
+				authLog.Error("unable to complete authorization", "err", err)
+			}
+			return
+		}
+	}()
+}
+
+// completeAuthorization stores the tokens of a granted authorization and
+// has the token supervisor check them.
+func (s *Server) completeAuthorization(ctx context.Context, state, accessToken, refreshToken string) error {
+	if err := s.storeUserTokens(ctx, accessToken, refreshToken); err != nil {
+		return err
+	}
+	if err := s.q.DeleteAuthorization(ctx, state); err != nil {
+		return errors.Wrap(err, "unable to delete authorization")
+	}
+
+	authLog.Info("the bot account has been authorized")
+	s.rotateToken(accessToken)
+	s.checkToken()
+	return nil
+}
+
+func (s *Server) postTwitchForm(ctx context.Context, endpoint string, form url.Values, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	return res.StatusCode, json.NewDecoder(res.Body).Decode(v)
+}
+
+var oauthPage = template.Must(template.New("oauth").Parse(`<html>
+	<head>
+		<title>{{.Title}}</title>
+	</head>
+	<body style="text-align:center; font-family:sans-serif;">
+		<h1>{{.Title}}</h1>
+		<p>{{.Message}}</p>
+	</body>
+</html>`))
+
+func writeOAuthPage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := oauthPage.Execute(w, struct{ Title, Message string }{title, message}); err != nil {
+		apiLog.Error("unable to write oauth page", "err", err)
+	}
+}
+
+// oauthCallback handles the redirect from Twitch after the bot account has
+// been authorized with the code flow.
+func (s *Server) oauthCallback() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		code, state := query.Get("code"), query.Get("state")
+
+		if reason := query.Get("error"); reason != "" {
+			if description := query.Get("error_description"); description != "" {
+				reason = description
+			}
+			writeOAuthPage(w, http.StatusBadRequest, "Authorization failed", reason)
+			return
+		}
+
+		if code == "" && state == "" {
+			// Display the "access_token" from the url fragment of an implicit grant
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html>
+				<head>
+						<script>
+								var url = window.location.href;
+								var access_token = url.split("#")[1].split("&")[0].split("=")[1];
+								document.write("<h1 style='text-align:center; font-size: 96px;'>" + access_token + "</h1>");
+						</script>
+				</head>
+		</html>`))
+			return
+		}
+
+		auth, err := s.q.GetAuthorization(r.Context(), state)
+		if err == sql.ErrNoRows || (err == nil && (auth.Flow != AuthFlowCode || time.Now().After(auth.ExpiresAt))) {
+			writeOAuthPage(w, http.StatusBadRequest, "Authorization failed",
+				"This authorization link has expired or was already used. Check the bot's log for a new one.")
+			return
+		} else if err != nil {
+			writeOAuthPage(w, http.StatusInternalServerError, "Authorization failed", err.Error())
+			return
+		}
+
+		res, err := s.twitch.RequestUserAccessToken(code)
+		if err != nil {
+			writeOAuthPage(w, http.StatusBadGateway, "Authorization failed", err.Error())
+			return
+		}
+		if res.StatusCode != http.StatusOK {
+			writeOAuthPage(w, http.StatusBadRequest, "Authorization failed", res.ErrorMessage)
+			return
+		}
+
+		if err := s.completeAuthorization(r.Context(), state, res.Data.AccessToken, res.Data.RefreshToken); err != nil {
+			writeOAuthPage(w, http.StatusInternalServerError, "Authorization failed", err.Error())
+			return
+		}
+
+		writeOAuthPage(w, http.StatusOK, "Authorized", "The bot account has been authorized, you can close this page.")
+	})
+}
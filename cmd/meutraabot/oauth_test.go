@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+)
+
+// roundTripFunc answers the requests of an http.Client without a network.
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestDeviceAuthorizationDenied(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	interval := devicePollInterval
+	devicePollInterval = time.Millisecond
+	t.Cleanup(func() { devicePollInterval = interval })
+
+	s.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       io.NopCloser(strings.NewReader(`{"message": "authorization_denied"}`)),
+		}, nil
+	})}
+	s.tokenCheck = make(chan struct{}, 1)
+
+	s.pollDeviceAuthorization(db.Authorization{State: "state", DeviceCode: "code", ExpiresAt: time.Now().Add(time.Minute)})
+	select {
+	case <-s.tokenCheck:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a new authorization to be started")
+	}
+
+	s.authMu.Lock()
+	polling := s.polling
+	s.authMu.Unlock()
+	if polling {
+		t.Error("expected polling to have stopped before a new authorization is started")
+	}
+}
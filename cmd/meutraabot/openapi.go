@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-chi/chi/v5"
+	"github.com/meutraa/meutraabot/pkg/api"
+)
+
+// The largest request body read to be validated. Handlers may accept less.
+const maxRequestBody = 1 << 20
+
+// loadSpec parses and checks the OpenAPI specification once.
+var loadSpec = sync.OnceValues(func() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(api.Spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return doc, nil
+})
+
+// specRoute finds the operation of the specification that routes would serve
+// a request with, and the parameters of its path.
+func specRoute(doc *openapi3.T, routes chi.Routes, r *http.Request) (*routers.Route, map[string]string, bool) {
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	rctx := chi.NewRouteContext()
+	if !routes.Match(rctx, r.Method, path) {
+		return nil, nil, false
+	}
+	return routeOperation(doc, r.Method, rctx)
+}
+
+// routeOperation finds the operation of the specification for the route a
+// request was matched with. The paths of the specification are written as the
+// patterns of the routes, so that a request is matched once, by chi.
+func routeOperation(doc *openapi3.T, method string, rctx *chi.Context) (*routers.Route, map[string]string, bool) {
+	pattern := rctx.RoutePattern()
+	if pattern != "/" {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	item := doc.Paths.Value(pattern)
+	if item == nil {
+		return nil, nil, false
+	}
+	operation := item.GetOperation(method)
+	if operation == nil {
+		return nil, nil, false
+	}
+
+	params := map[string]string{}
+	for i, key := range rctx.URLParams.Keys {
+		params[key] = rctx.URLParams.Values[i]
+	}
+	return &routers.Route{
+		Spec:      doc,
+		Path:      pattern,
+		PathItem:  item,
+		Method:    method,
+		Operation: operation,
+	}, params, true
+}
+
+// validateRequest rejects requests that do not match the specification of
+// the route they were matched with. It is used after the roles of the route
+// are checked, so that only users allowed to make a request learn what it
+// must look like. Only the parameters and bodies are validated, as users are
+// authenticated by the routes themselves.
+func validateRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, err := loadSpec()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		route, params, ok := routeOperation(doc, r.Method, chi.RouteContext(r.Context()))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		checked := r.Clone(r.Context())
+		checked.Body = io.NopCloser(bytes.NewReader(body))
+		if err := openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    checked,
+			PathParams: params,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+				SkipSettingDefaults: true,
+			},
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getSpec serves the specification in YAML, as it is written.
+func getSpec() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(api.Spec)
+	})
+}
+
+// getSpecJSON serves the specification in JSON, for tools that only read
+// JSON.
+func getSpecJSON() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, err := loadSpec()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res, err := json.Marshal(doc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPI(t *testing.T) {
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+	if err := s.q.CreateChannel(context.Background(), testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := loadSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every route is documented
+	if err := chi.Walk(s.apiRouter(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		if item := doc.Paths.Value(route); item == nil || item.GetOperation(method) == nil {
+			t.Errorf("%v %v is not in the specification", method, route)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := request(t, s, http.MethodGet, "/openapi.json", "", "")
+	spec := map[string]any{}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil || spec["openapi"] != "3.0.3" {
+		t.Errorf("expected the specification, got %v", err)
+	}
+
+	for _, invalid := range []string{
+		`{"autoreply_frequency": 10}`,
+		`{"autoreply_enabled": "yes"}`,
+		`{"unknown": true}`,
+		`{"openai_token": "sk-short"}`,
+	} {
+		if w := request(t, s, http.MethodPatch, "/channels/2", "streamer-token", invalid); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %v", invalid, w.Code)
+		}
+	}
+	if w := request(t, s, http.MethodGet, "/channels/2/bans?limit=1000", "streamer-token", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a limit out of range, got %v", w.Code)
+	}
+
+	// Requests are only validated for users allowed to make them
+	if w := request(t, s, http.MethodPatch, "/channels/2", "viewer-token", `{"unknown": true}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer, got %v", w.Code)
+	}
+
+	// Bodies must be sent as JSON
+	r := httptest.NewRequest(http.MethodPatch, "/channels/2", strings.NewReader(`{"listed": true}`))
+	r.Header.Set("Authorization", "streamer-token")
+	r.Header.Set("Content-Type", "text/plain")
+	w = httptest.NewRecorder()
+	s.apiRouter().ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a body that is not JSON, got %v", w.Code)
+	}
+}
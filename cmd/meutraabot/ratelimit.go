@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// rateLimiter counts the requests of each client in fixed windows.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, counts: map[string]int{}}
+}
+
+// allow counts a request from a client, returning false and the time until
+// the next window once the client is over the limit.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.start) >= l.window {
+		l.start = now
+		l.counts = map[string]int{}
+	}
+	l.counts[client]++
+	if l.counts[client] > l.limit {
+		return false, l.start.Add(l.window).Sub(now)
+	}
+	return true, 0
+}
+
+// limitAnonymous rate limits requests without a token by address, responding
+// with 429 to those over the limit.
+func limitAnonymous(limiter *rateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := requestUser(r); ok || limiter.limit == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// realIP may replace the address with one without a port
+			client, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				client = r.RemoteAddr
+			}
+
+			if ok, retry := limiter.allow(client, time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+				http.Error(w, "Too many requests, sign in or try again later", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// realIP replaces the address of a request with the one of the client given
+// in the X-Real-IP or X-Forwarded-For headers, like middleware.RealIP, but
+// only for requests from a trusted proxy. Anyone else could set the headers
+// to any address, and never be rate limited.
+func realIP(trusted func(netip.Addr) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		forwarded := middleware.RealIP(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if peer, err := netip.ParseAddrPort(r.RemoteAddr); err == nil && trusted(peer.Addr().Unmap()) {
+				forwarded.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	irc "github.com/gempir/go-twitch-irc/v3"
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+)
+
+// The accounts used by replays. The owner is the only user with owner
+// permissions.
+var (
+	replayBot   = helix.User{ID: "1", Login: "meutraabot", DisplayName: "meutraabot"}
+	replayOwner = helix.User{ID: "2", Login: "owner", DisplayName: "owner"}
+)
+
+// TranscriptEntry is a line of a replay transcript, a chat message or a user
+// joining a channel.
+type TranscriptEntry struct {
+	Channel   string `json:"channel"`
+	ChannelID string `json:"channel_id"`
+	User      string `json:"user"`
+	UserID    string `json:"user_id"`
+	// Tags of the message, such as mod, subscriber and reply-parent-msg-id
+	Tags    map[string]string `json:"tags"`
+	Message string            `json:"message"`
+	ID      string            `json:"id"`
+	// Replays the user joining the channel instead of a message
+	Join bool `json:"join"`
+	// Marks the user as a known bot account
+	Bot bool `json:"bot"`
+}
+
+// ReadTranscript reads a transcript of JSON entries, one per line. Blank
+// lines and lines starting with # are skipped.
+func ReadTranscript(r io.Reader) ([]TranscriptEntry, error) {
+	entries := []TranscriptEntry{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry := TranscriptEntry{}
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, errors.Wrap(err, "line "+strconv.Itoa(n))
+		}
+		if entry.Channel == "" || entry.User == "" {
+			return nil, fmt.Errorf("line %v: channel and user are required", n)
+		}
+		if entry.ID == "" {
+			entry.ID = "msg-" + strconv.Itoa(n)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// offlineTransport fails every request, so that replays never reach the network.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return nil, errors.New("network access is disabled in replays")
+}
+
+// NewReplayServer returns a server using the given database with fake Twitch
+// and chat backends that knows every user and channel in the transcript.
+func NewReplayServer(q db.Querier, entries []TranscriptEntry) (*Server, *FakeTwitch, *FakeChat, error) {
+	key, err := generateSecretKey()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	config := defaultConfig()
+	config.Twitch.OwnerID = replayOwner.ID
+	config.Secrets.Key = key
+
+	secrets, err := NewSecretStore(q, config.Secrets)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	twitch := NewFakeTwitch(replayBot, replayOwner)
+	bots := []Bot{}
+	// Users without an id are numbered in order of appearance
+	ids := map[string]string{replayBot.Login: replayBot.ID, replayOwner.Login: replayOwner.ID}
+	addUser := func(login, id string) string {
+		if known, ok := ids[login]; ok {
+			return known
+		}
+		if id == "" {
+			id = strconv.Itoa(1000 + len(ids))
+		}
+		ids[login] = id
+		twitch.Users = append(twitch.Users, helix.User{ID: id, Login: login, DisplayName: login})
+		return id
+	}
+	for i := range entries {
+		entries[i].ChannelID = addUser(entries[i].Channel, entries[i].ChannelID)
+		entries[i].UserID = addUser(entries[i].User, entries[i].UserID)
+		if entries[i].Bot {
+			bots = append(bots, Bot{Username: entries[i].User, UserID: entries[i].UserID})
+		}
+	}
+
+	chat := &FakeChat{}
+	s := &Server{
+		q:             q,
+		config:        config,
+		secrets:       secrets,
+		twitch:        twitch,
+		irc:           chat,
+		client:        &http.Client{Transport: offlineTransport{}},
+		twitchForUser: twitch.ForUser,
+		knownBots:     func() ([]Bot, error) { return lo.UniqBy(bots, func(b Bot) string { return b.UserID }), nil },
+		selfLogin:     replayBot.Login,
+		selfID:        replayBot.ID,
+		history:       map[string][]*irc.PrivateMessage{},
+		conversations: map[string][]*irc.PrivateMessage{},
+		noDelay:       true,
+	}
+	return s, twitch, chat, nil
+}
+
+// Replay feeds each entry of a transcript through the bot, returning a
+// record of the messages and the bot's responses and moderation actions.
+func (s *Server) Replay(entries []TranscriptEntry, twitch *FakeTwitch, chat *FakeChat) string {
+	out := strings.Builder{}
+	for _, entry := range entries {
+		if entry.Join {
+			fmt.Fprintf(&out, "> #%v %v joined\n", entry.Channel, entry.User)
+		} else {
+			fmt.Fprintf(&out, "> #%v %v: %v\n", entry.Channel, entry.User, entry.Message)
+		}
+		s.feed(entry)
+		writeEffects(&out, twitch, chat)
+	}
+	return out.String()
+}
+
+// feed handles an entry as if it was received from chat, waiting for any
+// response to be sent.
+func (s *Server) feed(entry TranscriptEntry) {
+	if entry.Join {
+		s.checkUser(entry.Channel, entry.User)
+		return
+	}
+
+	e := &irc.PrivateMessage{
+		User: irc.User{
+			ID:          entry.UserID,
+			Name:        entry.User,
+			DisplayName: entry.User,
+		},
+		Tags:    entry.Tags,
+		Message: entry.Message,
+		Channel: entry.Channel,
+		RoomID:  entry.ChannelID,
+		ID:      entry.ID,
+	}
+	if e.Tags == nil {
+		e.Tags = map[string]string{}
+	}
+	if parent := e.Tags["reply-parent-msg-id"]; parent != "" {
+		e.Reply = &irc.Reply{
+			ParentMsgID:     parent,
+			ParentUserID:    e.Tags["reply-parent-user-id"],
+			ParentUserLogin: e.Tags["reply-parent-user-login"],
+			ParentMsgBody:   e.Tags["reply-parent-msg-body"],
+		}
+	}
+	s.sendResponse(e, s.processMessage(e))
+}
+
+// writeEffects writes and forgets the messages sent and moderation actions
+// made since it was last called.
+func writeEffects(w io.Writer, twitch *FakeTwitch, chat *FakeChat) {
+	for _, m := range chat.TakeMessages() {
+		fmt.Fprintf(w, "< %v\n", m)
+	}
+	for _, a := range twitch.TakeActions() {
+		fmt.Fprintf(w, "! %v\n", a)
+	}
+}
+
+// copyCommands copies the global commands and the commands and settings of
+// each channel in the transcript from one database to another.
+func copyCommands(ctx context.Context, from, to db.Querier, entries []TranscriptEntry) error {
+	channelIDs := lo.Uniq(lo.Map(entries, func(e TranscriptEntry, _ int) string { return e.ChannelID }))
+	for _, id := range append([]string{"0"}, channelIDs...) {
+		commands, err := from.GetCommandsByID(ctx, id)
+		if err != nil {
+			return errors.Wrap(err, "unable to get commands for "+id)
+		}
+		for _, c := range commands {
+			if err := to.SaveCommand(ctx, db.SaveCommandParams{
+				ChannelID:   id,
+				Name:        c.Name,
+				Template:    c.Template,
+				Description: c.Description,
+				UpdatedBy:   c.UpdatedBy,
+				UpdatedAt:   c.UpdatedAt,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if id == "0" {
+			continue
+		}
+		channel, err := from.GetChannel(ctx, id)
+		if err != nil {
+			continue
+		}
+		if err := to.UpdateChannel(ctx, db.UpdateChannelParams{
+			ChannelID:            id,
+			AutoreplyEnabled:     channel.AutoreplyEnabled,
+			AutoreplyFrequency:   channel.AutoreplyFrequency,
+			ReplySafety:          channel.ReplySafety,
+			ChatlogEnabled:       channel.ChatlogEnabled,
+			ChatlogRetentionDays: channel.ChatlogRetentionDays,
+			CommandsPublic:       channel.CommandsPublic,
+			Listed:               channel.Listed,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runReplay replays a transcript against a temporary database, comparing the
+// result with a golden file when one is given.
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	golden := fs.String("golden", "", "file the replay must match")
+	update := fs.Bool("update", false, "write the replay to the golden file instead of comparing")
+	fromDB := fs.Bool("from-db", false, "copy the commands of the transcript's channels from the configured database")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: replay [-golden FILE [-update]] [-from-db] TRANSCRIPT")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || (*update && *golden == "") {
+		fs.Usage()
+		return errors.New("invalid arguments")
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	entries, err := ReadTranscript(file)
+	file.Close()
+	if err != nil {
+		return errors.Wrap(err, "unable to read transcript")
+	}
+
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "meutraabot-replay")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	conn, err := db.Open(ctx, db.SQLite, "file:"+filepath.Join(dir, "db.sql")+"?mode=rwc")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := db.Migrate(ctx, db.SQLite, conn); err != nil {
+		return err
+	}
+	q, err := db.PrepareQuerier(ctx, db.SQLite, conn)
+	if err != nil {
+		return err
+	}
+
+	s, twitch, chat, err := NewReplayServer(q, entries)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := q.CreateChannel(ctx, e.ChannelID); err != nil {
+			return err
+		}
+	}
+
+	if *fromDB {
+		config, err := LoadConfig()
+		if err != nil {
+			return err
+		}
+		if err := config.ValidateDatabase(); err != nil {
+			return err
+		}
+		from := Server{config: config}
+		if err := from.OpenDatabase(); err != nil {
+			return err
+		}
+		defer from.Close()
+		fromQ, err := db.PrepareQuerier(ctx, config.Database.Engine, from.conn)
+		if err != nil {
+			return err
+		}
+		if err := copyCommands(ctx, fromQ, q, entries); err != nil {
+			return errors.Wrap(err, "unable to copy commands")
+		}
+	}
+
+	// Replays are repeatable, so random() gives the same numbers every time
+	rand.Seed(1)
+
+	result := s.Replay(entries, twitch, chat)
+
+	switch {
+	case *update:
+		return os.WriteFile(*golden, []byte(result), 0644)
+	case *golden != "":
+		expected, err := os.ReadFile(*golden)
+		if err != nil {
+			return err
+		}
+		if diff := diffLines(string(expected), result); diff != "" {
+			return errors.New("replay does not match " + *golden + ":\n" + diff)
+		}
+		fmt.Println("replay matches", *golden)
+	default:
+		fmt.Print(result)
+	}
+	return nil
+}
+
+// diffLines describes the lines that differ between two texts, or returns an
+// empty string if they are the same.
+func diffLines(expected, actual string) string {
+	e := strings.Split(expected, "\n")
+	a := strings.Split(actual, "\n")
+	diff := strings.Builder{}
+	for i := 0; i < len(e) || i < len(a); i++ {
+		var el, al string
+		if i < len(e) {
+			el = e[i]
+		}
+		if i < len(a) {
+			al = a[i]
+		}
+		if el != al {
+			fmt.Fprintf(&diff, "line %v:\n  - %v\n  + %v\n", i+1, el, al)
+		}
+	}
+	return diff.String()
+}
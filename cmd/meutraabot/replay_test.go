@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	transcripts, err := filepath.Glob("testdata/replay/*.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range transcripts {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			entries, err := ReadTranscript(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			base, _, _ := newTestServer(t)
+			s, twitch, chat, err := NewReplayServer(base.q, entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if err := s.q.CreateChannel(context.Background(), e.ChannelID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rand.Seed(1)
+			result := s.Replay(entries, twitch, chat)
+
+			expected, err := os.ReadFile(strings.TrimSuffix(path, ".jsonl") + ".golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := diffLines(string(expected), result); diff != "" {
+				t.Errorf("replay does not match:\n%v", diff)
+			}
+		})
+	}
+}
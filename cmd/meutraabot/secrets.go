@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/pkg/errors"
+)
+
+// Names of the secrets kept in the secret store.
+const (
+	secretUserAccessToken = "bot/user_access_token"
+	secretRefreshToken    = "bot/refresh_token"
+)
+
+func channelTokenSecret(channelID string) string {
+	return "channel/" + channelID + "/openai_token"
+}
+
+type secretKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// SecretStore keeps secrets in the database encrypted with AES-GCM. Each
+// value records the id of the key that encrypted it, so values encrypted with
+// a previous key can still be read until they are rotated.
+type SecretStore struct {
+	q       db.Querier
+	current *secretKey
+	keys    map[string]*secretKey
+}
+
+// parseSecretKey decodes a base64 encoded 32 byte key.
+func parseSecretKey(encoded string) (*secretKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "secret key is not valid base64")
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("secret key must be 32 bytes, not %v", len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(raw)
+	return &secretKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// generateToken returns a new random hex encoded 32 byte token.
+func generateToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// generateSecretKey returns a new random base64 encoded key.
+func generateSecretKey() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func NewSecretStore(q db.Querier, config SecretsConfig) (*SecretStore, error) {
+	current, err := parseSecretKey(config.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "secrets.key")
+	}
+
+	s := &SecretStore{
+		q:       q,
+		current: current,
+		keys:    map[string]*secretKey{current.id: current},
+	}
+	for _, encoded := range config.PreviousKeys {
+		key, err := parseSecretKey(encoded)
+		if err != nil {
+			return nil, errors.Wrap(err, "secrets.previous_keys")
+		}
+		s.keys[key.id] = key
+	}
+	return s, nil
+}
+
+// Get decrypts a secret, returning sql.ErrNoRows if it does not exist.
+func (s *SecretStore) Get(ctx context.Context, name string) (string, error) {
+	secret, err := s.q.GetSecret(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return s.decrypt(secret)
+}
+
+func (s *SecretStore) Set(ctx context.Context, name, value string) error {
+	nonce := make([]byte, s.current.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	// The name is authenticated so a value can not be moved to another secret
+	sealed := s.current.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return s.q.SetSecret(ctx, db.SetSecretParams{
+		Name:  name,
+		KeyID: s.current.id,
+		Value: sealed,
+	})
+}
+
+func (s *SecretStore) Delete(ctx context.Context, name string) error {
+	return s.q.DeleteSecret(ctx, name)
+}
+
+// Exists reports whether a secret has been set.
+func (s *SecretStore) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := s.q.GetSecret(ctx, name)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *SecretStore) decrypt(secret db.Secret) (string, error) {
+	key, ok := s.keys[secret.KeyID]
+	if !ok {
+		return "", fmt.Errorf("secret %v was encrypted with unknown key %v", secret.Name, secret.KeyID)
+	}
+
+	size := key.aead.NonceSize()
+	if len(secret.Value) < size {
+		return "", fmt.Errorf("secret %v is too short", secret.Name)
+	}
+
+	plain, err := key.aead.Open(nil, secret.Value[:size], secret.Value[size:], []byte(secret.Name))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to decrypt secret "+secret.Name)
+	}
+	return string(plain), nil
+}
+
+// Rotate re-encrypts every secret that was encrypted with a previous key,
+// returning how many were rotated.
+func (s *SecretStore) Rotate(ctx context.Context) (int, error) {
+	secrets, err := s.q.GetSecrets(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, secret := range secrets {
+		if secret.KeyID == s.current.id {
+			continue
+		}
+
+		value, err := s.decrypt(secret)
+		if err != nil {
+			return rotated, err
+		}
+		if err := s.Set(ctx, secret.Name, value); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+// PrepareSecrets opens the secret store, rotates any secrets still encrypted
+// with a previous key and moves secrets kept outside the store into it.
+func (s *Server) PrepareSecrets() error {
+	secrets, err := NewSecretStore(s.q, s.config.Secrets)
+	if err != nil {
+		return err
+	}
+	s.secrets = secrets
+
+	ctx := context.Background()
+	rotated, err := s.secrets.Rotate(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to rotate secrets")
+	}
+	if rotated > 0 {
+		dbLog.Info("re-encrypted secrets with the current key", "count", rotated)
+	}
+
+	return s.importSecrets(ctx)
+}
+
+// importSecrets moves the bot's tokens from the files they used to be written
+// to, and the channel OpenAI tokens from the channels table, into the store.
+func (s *Server) importSecrets(ctx context.Context) error {
+	for name, file := range map[string]string{
+		secretUserAccessToken: s.config.Path("user_access_token"),
+		secretRefreshToken:    s.config.Path("refresh_token"),
+	} {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return errors.Wrap(err, "unable to read "+file)
+		}
+
+		exists, err := s.secrets.Exists(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if err := s.secrets.Set(ctx, name, string(data)); err != nil {
+				return errors.Wrap(err, "unable to store "+name)
+			}
+		}
+
+		if err := os.Remove(file); err != nil {
+			return errors.Wrap(err, "unable to remove "+file)
+		}
+		dbLog.Info("moved token file into the secret store", "file", file)
+	}
+
+	channels, err := s.q.GetChannelTokens(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrap(err, "unable to get channel tokens")
+	}
+	for _, channel := range channels {
+		if err := s.secrets.Set(ctx, channelTokenSecret(channel.ChannelID), channel.OpenaiToken.String); err != nil {
+			return errors.Wrap(err, "unable to store openai token")
+		}
+		if err := s.q.UpdateChannelToken(ctx, db.UpdateChannelTokenParams{
+			ChannelID: channel.ChannelID,
+		}); err != nil {
+			return errors.Wrap(err, "unable to clear openai token")
+		}
+		dbLog.Info("moved openai token into the secret store", "channel_id", channel.ChannelID)
+	}
+	return nil
+}
@@ -3,20 +3,12 @@ package main
 import (
 	"context"
 	"database/sql"
-	"database/sql/driver"
 	"fmt"
-	"io/ioutil"
-	l "log"
-	"math/rand"
 	"net/http"
-	"os"
-	"regexp"
+	"sync"
 	"time"
 
 	"github.com/samber/lo"
-	sqlite3 "modernc.org/sqlite"
-
-	_ "embed"
 
 	irc "github.com/gempir/go-twitch-irc/v3"
 	"github.com/meutraa/meutraabot/pkg/db"
@@ -25,64 +17,136 @@ import (
 )
 
 type Server struct {
-	irc           *irc.Client
-	conn          *sql.DB
-	twitch        *helix.Client
-	q             *db.Queries
-	client        *http.Client
-	env           *Environment
-	oauth         chan string
-	selfLogin     string
-	selfID        string
-	history       map[string][]*irc.PrivateMessage
+	// Replaced when the bot is reauthorized, so read with chat()
+	ircMu  sync.RWMutex
+	irc    Chat
+	conn   *sql.DB
+	twitch TwitchAPI
+	// Creates a Twitch API client using a user's access token
+	twitchForUser func(accessToken string) (TwitchAPI, error)
+	// Returns the known bot accounts
+	knownBots    func() ([]Bot, error)
+	q            db.Querier
+	client       *http.Client
+	config       *Config
+	secrets      *SecretStore
+	authMu       sync.Mutex
+	polling      bool
+	tokenMu      sync.Mutex
+	token        TokenStatus
+	tokenChanged chan struct{}
+	tokenCheck   chan struct{}
+	selfLogin    string
+	selfID       string
+	// Guards history and conversations, as responses are sent from other
+	// goroutines than the one chat is read from
+	historyMu sync.Mutex
+	history   map[string][]*irc.PrivateMessage
+	// Skip the typing delay of delay:: responses, for replays
+	noDelay       bool
 	conversations map[string][]*irc.PrivateMessage
-}
-
-type Environment struct {
-	twitchOwnerID      string
-	twitchClientSecret string
-	twitchRedirectURL  string
-	twitchClientID     string
+	// The users of API tokens
+	tokenUsers tokenUserCache
+	// Whether channels log chat
+	chatlogs chatlogCache
+	// The state of the chat connection, for health checks
+	chatMu      sync.Mutex
+	connected   bool
+	joined      map[string]bool
+	lastMessage time.Time
+	// The subscribers to each channel's events
+	events eventHub
 }
 
 func (s *Server) Close() {
-	if nil != s.irc {
-		s.irc.Disconnect()
+	if chat := s.chat(); nil != chat {
+		chat.Disconnect()
 	}
 	if nil != s.conn {
 		s.conn.Close()
 	}
 }
 
-// //go:embed schema.sql
-var ddl string
+// chat returns the connection to chat, or nil before the bot is authorized.
+func (s *Server) chat() Chat {
+	s.ircMu.RLock()
+	defer s.ircMu.RUnlock()
+	return s.irc
+}
 
-func (s *Server) PrepareDatabase() error {
-	regex := func(ctx *sqlite3.FunctionContext, args []driver.Value) (driver.Value, error) {
-		return regexp.MatchString(args[0].(string), args[1].(string))
+// channelHistory returns the messages said in a channel, and whether the
+// history of the channel was seeded yet.
+func (s *Server) channelHistory(channel string) ([]*irc.PrivateMessage, bool) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	history, ok := s.history[channel]
+	return history, ok
+}
+
+// seedHistory sets the history of a channel after a restart, unless messages
+// were recorded in it since.
+func (s *Server) seedHistory(channel string, history []*irc.PrivateMessage) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	if _, ok := s.history[channel]; !ok {
+		s.history[channel] = history
 	}
+}
 
-	sqlite3.RegisterScalarFunction("regexp", 2, regex)
+// addHistory records a message said in a channel.
+func (s *Server) addHistory(e *irc.PrivateMessage) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	s.history[e.Channel] = append(s.history[e.Channel], e)
+}
+
+// conversation returns the messages of the threads the bot replied in, in a
+// channel.
+func (s *Server) conversation(channel string) ([]*irc.PrivateMessage, bool) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	messages, ok := s.conversations[channel]
+	return messages, ok
+}
+
+// addConversation records a message of a thread the bot replies in.
+func (s *Server) addConversation(e *irc.PrivateMessage) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	s.conversations[e.Channel] = append(s.conversations[e.Channel], e)
+}
+
+// OpenDatabase connects to the database without preparing any queries, so
+// that it can be used before the schema is migrated.
+func (s *Server) OpenDatabase() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*30))
+	defer cancel()
 
-	conn, err := sql.Open("sqlite", "file:db.sql?mode=rwc")
+	conn, err := db.Open(ctx, s.config.Database.Engine, s.config.Database.URL)
 	if nil != err {
 		return errors.Wrap(err, "unable to establish connection to database")
 	}
 	s.conn = conn
+	return nil
+}
 
-	if err := s.conn.Ping(); err != nil {
-		return errors.Wrap(err, "unable to ping database")
+func (s *Server) PrepareDatabase() error {
+	if err := s.OpenDatabase(); nil != err {
+		return err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*30))
 	defer cancel()
 
-	// create tables
-	if _, err := conn.ExecContext(ctx, ddl); err != nil {
-		return errors.Wrap(err, "unable to create tables")
+	applied, err := db.Migrate(ctx, s.config.Database.Engine, s.conn)
+	if err != nil {
+		return errors.Wrap(err, "unable to migrate database")
+	}
+	for _, m := range applied {
+		dbLog.Info("applied migration", "migration", m.Name)
 	}
 
-	queries, err := db.Prepare(ctx, conn)
+	queries, err := db.PrepareQuerier(ctx, s.config.Database.Engine, s.conn)
 	if nil != err {
 		return errors.Wrap(err, "unable to prepare queries")
 	}
@@ -90,147 +154,137 @@ func (s *Server) PrepareDatabase() error {
 	return nil
 }
 
-func (s *Server) RefreshUserAccessToken() error {
-	dat, err := ioutil.ReadFile("refresh_token")
-	if nil != err {
-		return errors.Wrap(err, "unable to read refresh_token")
+// errRefreshRejected is returned when Twitch no longer accepts the refresh
+// token, and the bot account must be authorized again.
+var errRefreshRejected = errors.New("refresh token was rejected")
+
+// RefreshUserAccessToken exchanges the refresh token for a new user access
+// token, returning how long the new token is valid for.
+func (s *Server) RefreshUserAccessToken(ctx context.Context) (time.Duration, error) {
+	refreshToken, err := s.secrets.Get(ctx, secretRefreshToken)
+	if err == sql.ErrNoRows {
+		return 0, errRefreshRejected
+	} else if nil != err {
+		return 0, errors.Wrap(err, "unable to read refresh token")
 	}
 
-	res, err := s.twitch.RefreshUserAccessToken(string(dat))
+	res, err := s.twitch.RefreshUserAccessToken(refreshToken)
 	if err != nil {
-		return errors.Wrap(err, "unable to refresh user access token")
+		return 0, errors.Wrap(err, "unable to refresh user access token")
 	}
-
-	l.Println("aquired a refreshed user access token that expires in", res.Data.ExpiresIn, "seconds")
-
-	err = os.WriteFile("user_access_token", []byte(res.Data.AccessToken), 0640)
-	if err != nil {
-		return errors.Wrap(err, "unable to write user access token to file")
+	switch {
+	case res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusUnauthorized:
+		return 0, errRefreshRejected
+	case res.StatusCode != http.StatusOK:
+		return 0, errors.New("unable to refresh user access token: " + res.ErrorMessage)
 	}
 
-	err = os.WriteFile("refresh_token", []byte(res.Data.RefreshToken), 0640)
-	if err != nil {
-		return errors.Wrap(err, "unable to write refresh token to file")
-	}
-
-	s.twitch.SetUserAccessToken(res.Data.AccessToken)
-
-	return nil
-}
-
-func (s *Server) GetNewUserAccessToken() error {
-	// Create a random string to prevent CSRF attacks
-	var letters = []rune("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
-	b := make([]rune, 32)
-	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
-	}
-	state := string(b)
-
-	url := s.twitch.GetAuthorizationURL(&helix.AuthorizationURLParams{
-		ResponseType: "code",
-		State:        state,
-		Scopes: []string{
-			"chat:edit",
-			"chat:read",
-			"channel:moderate",
-			"moderation:read",
-			"moderator:read:chatters",
-			"moderator:manage:chat_messages",
-			"moderator:manage:banned_users",
-			"moderator:manage:announcements",
-		},
-	})
-
-	fmt.Println("Open", url, "and grant user access token")
-	// block waiting for token
-	code := <-s.oauth
-	stateRes := <-s.oauth
+	authLog.Info("acquired a refreshed user access token", "expires_in", time.Duration(res.Data.ExpiresIn)*time.Second)
 
-	if state != stateRes {
-		return errors.New("state does not match oauth response")
+	if err := s.storeUserTokens(ctx, res.Data.AccessToken, res.Data.RefreshToken); err != nil {
+		return 0, err
 	}
 
-	res, err := s.twitch.RequestUserAccessToken(code)
-	if err != nil {
-		return errors.Wrap(err, "unable to request user access token")
-	}
+	s.rotateToken(res.Data.AccessToken)
+	s.tokenMu.Lock()
+	s.token.RefreshedAt = time.Now()
+	s.tokenMu.Unlock()
 
-	l.Println("aquired a user access token that expires in", res.Data.ExpiresIn, "seconds")
+	return time.Duration(res.Data.ExpiresIn) * time.Second, nil
+}
 
-	err = os.WriteFile("user_access_token", []byte(res.Data.AccessToken), 0640)
-	if err != nil {
-		return errors.Wrap(err, "unable to write user access token to file")
+func (s *Server) storeUserTokens(ctx context.Context, accessToken, refreshToken string) error {
+	if err := s.secrets.Set(ctx, secretUserAccessToken, accessToken); err != nil {
+		return errors.Wrap(err, "unable to store user access token")
 	}
 
-	err = os.WriteFile("refresh_token", []byte(res.Data.RefreshToken), 0640)
-	if err != nil {
-		return errors.Wrap(err, "unable to write refresh token to file")
+	if err := s.secrets.Set(ctx, secretRefreshToken, refreshToken); err != nil {
+		return errors.Wrap(err, "unable to store refresh token")
 	}
-
-	s.twitch.SetUserAccessToken(res.Data.AccessToken)
-
 	return nil
 }
 
 func (s *Server) PrepareTwitchClient() error {
-	l.Println("preparing twitch client")
-	client, err := helix.NewClient(&helix.Options{
-		ClientID:     s.env.twitchClientID,
-		ClientSecret: s.env.twitchClientSecret,
-		RedirectURI:  s.env.twitchRedirectURL,
-	})
+	twitchLog.Debug("preparing twitch client")
+	s.client = &http.Client{Transport: instrumentedTransport{next: http.DefaultTransport}}
+	client, err := newHelixTwitch(s.config.Twitch, s.client)
 	if err != nil {
-		return errors.Wrap(err, "unable to create twitch api client")
+		return err
 	}
-	s.client = &http.Client{}
 	s.twitch = client
+	s.twitchForUser = func(accessToken string) (TwitchAPI, error) {
+		client, err := newHelixTwitch(TwitchConfig{
+			ClientID:     s.config.Twitch.ClientID,
+			ClientSecret: s.config.Twitch.ClientSecret,
+		}, s.client)
+		if err != nil {
+			return nil, err
+		}
+		client.SetUserAccessToken(accessToken)
+		return client, nil
+	}
+	s.knownBots = getBotList
 
 	return nil
 }
 
-func (s *Server) EnsureValidUserToken() error {
+// EnsureValidUserToken loads, validates and refreshes the bot's user access
+// token, returning how long it is valid for, or errNotAuthorized when the bot
+// account must be authorized.
+func (s *Server) EnsureValidUserToken() (time.Duration, error) {
+	ctx := context.Background()
+
 	// Check to see if we have a token loaded already.
 	if s.twitch.GetUserAccessToken() == "" {
-		// Read the user access token from a file, if it exists
-		dat, err := ioutil.ReadFile("user_access_token")
-		if nil != err {
-			l.Println("unable to read existing user access token, requesting new one")
-			return s.GetNewUserAccessToken()
+		// Read the user access token from the secret store, if it exists
+		token, err := s.secrets.Get(ctx, secretUserAccessToken)
+		if err == sql.ErrNoRows {
+			authLog.Info("no user access token saved, requesting authorization")
+			return 0, s.RequestAuthorization(ctx)
+		} else if nil != err {
+			return 0, errors.Wrap(err, "unable to read user access token")
 		}
-		s.twitch.SetUserAccessToken(string(dat))
+		s.rotateToken(token)
 	}
 
 	// Now that we have a token loaded, test it
-	l.Println("testing validility of token")
+	authLog.Debug("validating user access token")
 	isValid, res, err := s.twitch.ValidateToken(s.twitch.GetUserAccessToken())
 	if err != nil {
-		// This is probably fatal
-		return errors.Wrap(err, "unable to validate user access token")
-	} else if !isValid {
-		l.Println("saved token is not valid, requesting new one")
-		return s.GetNewUserAccessToken()
-	} else if res.Data.ExpiresIn < 3600 {
-		return s.RefreshUserAccessToken()
+		return 0, errors.Wrap(err, "unable to validate user access token")
 	}
-	return nil
+
+	expiresIn := time.Duration(res.Data.ExpiresIn) * time.Second
+	if isValid && expiresIn >= tokenRefreshMargin {
+		return expiresIn, nil
+	}
+
+	if !isValid {
+		authLog.Info("saved token is not valid, refreshing it")
+	}
+	expiresIn, err = s.RefreshUserAccessToken(ctx)
+	if err == errRefreshRejected {
+		authLog.Warn("refresh token was rejected, requesting authorization")
+		return 0, s.RequestAuthorization(ctx)
+	}
+	return expiresIn, err
 }
 
 // Channels should always be login usernames, not ids
 func (s *Server) JoinChannels(channelnames []string, channelIDs []string) {
 	if len(channelnames) != len(channelIDs) {
-		l.Println("JoinChannels requires len(usernames) == len(userIDs)")
+		ircLog.Error("JoinChannels requires len(usernames) == len(userIDs)")
 		return
 	}
 
-	s.irc.Join(channelnames...)
+	s.chat().Join(channelnames...)
 
-	bots, err := getBotList()
+	bots, err := s.knownBots()
 	if nil != err {
-		l.Println("unable to get known bot list", err)
+		ircLog.Error("unable to get known bot list", "err", err)
 		return
 	}
-	l.Printf("found db of %v bots", len(bots))
+	ircLog.Info("loaded known bot list", "count", len(bots))
 
 	// Vet all users
 	go func() {
@@ -240,25 +294,25 @@ func (s *Server) JoinChannels(channelnames []string, channelIDs []string) {
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 			defer cancel()
 
-			log(channel, "", "getting user list to check for bots", nil)
+			ircLog.Debug("getting user list to check for bots", "channel", channel)
 			usernames, err := s.Chatters(ctx, channelIDs[i])
 			if nil != err {
-				log(channel, "", "unable to get user list", err)
+				ircLog.Error("unable to get user list", "channel", channel, "err", err)
 				continue
 			}
 
-			log(channel, "", "found chatters: "+fmt.Sprintf("%v", len(usernames)), nil)
+			ircLog.Debug("found chatters", "channel", channel, "count", len(usernames))
 
 			users, err := Users(s.twitch, []string{}, usernames)
 			if nil != err {
-				log(channel, "", "unable to get users for channel", err)
+				ircLog.Error("unable to get users for channel", "channel", channel, "err", err)
 				continue
 			}
 
 			// get the already approved bots
 			approvals, err := s.q.GetApprovals(ctx, channelIDs[i])
 			if nil != err && err != sql.ErrNoRows {
-				l.Println("unable to get approvals", err)
+				ircLog.Error("unable to get approvals", "channel", channel, "err", err)
 				continue
 			}
 
@@ -274,45 +328,50 @@ func (s *Server) JoinChannels(channelnames []string, channelIDs []string) {
 				return !isApproved && isBot
 			})
 
-			log(channel, "", "found unapproved users: "+fmt.Sprintf("%v", len(bots)), nil)
+			ircLog.Info("found unapproved bots", "channel", channel, "count", len(bots))
 
 			for _, bot := range bots {
-				s.funcBan(ctx, Data{
+				s.banBot(ctx, Data{
 					Channel:   channel,
 					ChannelID: channelIDs[i],
 					User:      bot.Login,
 					UserID:    bot.ID,
-				}, 0, fmt.Sprintf("unapproved bot"))
+				}, "unapproved bot")
 			}
 		}
 	}()
 }
 
 func (s *Server) PrepareIRC() error {
+	// The token is read while the client is replaced, so that a rotated token
+	// is not lost between them
+	s.ircMu.Lock()
+	client := irc.NewClient(s.selfLogin, "oauth:"+s.twitch.GetUserAccessToken())
+	client.Capabilities = append(client.Capabilities, irc.MembershipCapability)
 	if s.irc != nil {
 		s.irc.Disconnect()
 	}
-	s.irc = irc.NewClient(s.selfLogin, "oauth:"+s.twitch.GetUserAccessToken())
-	s.irc.Capabilities = append(s.irc.Capabilities, irc.MembershipCapability)
+	s.irc = client
+	s.ircMu.Unlock()
 
-	fmt.Println("created irc client")
-	s.irc.OnGlobalUserStateMessage(func(m irc.GlobalUserStateMessage) {
+	ircLog.Debug("created irc client")
+	client.OnGlobalUserStateMessage(func(m irc.GlobalUserStateMessage) {
 		// Get own user id
-		s.irc.Join(s.selfLogin)
+		client.Join(s.selfLogin)
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
 		defer cancel()
 
 		// Get the list of channels we should join
 		channels, err := s.q.GetChannels(ctx)
 		if nil != err && err != sql.ErrNoRows {
-			l.Println("unable to get channels", err)
+			dbLog.Error("unable to get channels", "err", err)
 			return
 		}
 		// convert these ids to logins
 
 		users, err := Users(s.twitch, channels, []string{})
 		if nil != err {
-			l.Println("unable to get users", err)
+			twitchLog.Error("unable to get users for channels", "err", err)
 			return
 		}
 
@@ -322,22 +381,34 @@ func (s *Server) PrepareIRC() error {
 		s.JoinChannels(usernames, userIds)
 	})
 
-	s.irc.OnUserJoinMessage(func(m irc.UserJoinMessage) {
-		log(m.Channel, m.User, "joined", nil)
-		go s.checkUser(nil, m.Channel, m.User)
+	client.OnConnect(func() {
+		s.setConnected(true)
 	})
 
-	s.irc.OnPrivateMessage(s.handleMessage)
+	client.OnSelfJoinMessage(func(m irc.UserJoinMessage) {
+		s.setJoined(m.Channel, true)
+	})
+
+	client.OnSelfPartMessage(func(m irc.UserPartMessage) {
+		s.setJoined(m.Channel, false)
+	})
 
-	fmt.Println("connecting to irc")
-	return s.irc.Connect()
+	client.OnUserJoinMessage(func(m irc.UserJoinMessage) {
+		ircLog.Debug("joined", "channel", m.Channel, "user", m.User)
+		go s.checkUser(m.Channel, m.User)
+	})
+
+	client.OnPrivateMessage(s.handleMessage)
+
+	ircLog.Info("connecting to irc")
+	defer s.setConnected(false)
+	return client.Connect()
 }
 
-func (s *Server) checkUser(bots []Bot, channel, username string) {
-	var err error
-	bots, err = getBotList()
+func (s *Server) checkUser(channel, username string) {
+	bots, err := s.knownBots()
 	if nil != err {
-		log(channel, username, "unable to download known bot list", err)
+		ircLog.Error("unable to download known bot list", "channel", channel, "user", username, "err", err)
 		return
 	}
 
@@ -347,13 +418,13 @@ func (s *Server) checkUser(bots []Bot, channel, username string) {
 	// Get channel and user id
 	cUser, err := User(s.twitch, "", channel)
 	if nil != err {
-		log(channel, username, "unable to get channel", err)
+		ircLog.Error("unable to get channel", "channel", channel, "user", username, "err", err)
 		return
 	}
 
 	qUser, err := User(s.twitch, "", username)
 	if nil != err {
-		log(channel, username, "unable to get user", err)
+		ircLog.Error("unable to get user", "channel", channel, "user", username, "err", err)
 		return
 	}
 
@@ -365,7 +436,7 @@ func (s *Server) checkUser(bots []Bot, channel, username string) {
 		UserID:    userID,
 	})
 	if nil != err && err != sql.ErrNoRows {
-		log(channel, username, "unable to check approval status", err)
+		ircLog.Error("unable to check approval status", "channel", channel, "user", username, "err", err)
 		return
 	}
 	if approved > 0 {
@@ -374,41 +445,62 @@ func (s *Server) checkUser(bots []Bot, channel, username string) {
 
 	for _, bot := range bots {
 		if bot.UserID == userID {
-			s.funcBan(ctx, Data{
+			s.banBot(ctx, Data{
 				Channel:   channel,
 				ChannelID: channelID,
 				User:      bot.Username,
 				UserID:    bot.UserID,
-			}, 0, fmt.Sprintf("bot in %v channels", bot.ChannelCount))
+			}, fmt.Sprintf("bot in %v channels", bot.ChannelCount))
 			return
 		}
 	}
 
 	// This user is not a bot
-	log(channel, username, "not a bot", nil)
+	ircLog.Debug("not a bot", "channel", channel, "user", username)
 	if err := s.q.Approve(ctx, db.ApproveParams{
 		ChannelID: channelID,
 		UserID:    userID,
 		Manual:    false,
 	}); nil != err {
-		log(channel, username, "unable to approve", err)
+		ircLog.Error("unable to approve", "channel", channel, "user", username, "err", err)
 		return
 	}
 }
 
-func (s *Server) ReadEnvironmentVariables() error {
-	// Read our username from the environment, end if failure
-	s.env = &Environment{}
-	s.env.twitchClientID = os.Getenv("TWITCH_CLIENT_ID")
-	s.env.twitchClientSecret = os.Getenv("TWITCH_CLIENT_SECRET")
-	s.env.twitchOwnerID = os.Getenv("TWITCH_OWNER_ID")
-	s.env.twitchRedirectURL = os.Getenv("TWITCH_REDIRECT_URL")
+// banBot bans a known bot that is not approved in a channel, notifying the
+// channel's webhooks.
+func (s *Server) banBot(ctx context.Context, d Data, reason string) {
+	if s.ban(ctx, d, 0, reason) {
+		s.notify(d.ChannelID, WebhookBotBanned, BotBannedEvent{
+			UserID: d.UserID,
+			User:   d.User,
+			Reason: reason,
+		})
+	}
+}
+
+// approveUser manually approves a user in a channel so that they are never
+// banned as a bot, and unbans them.
+func (s *Server) approveUser(ctx context.Context, d Data) error {
+	if err := s.q.Approve(ctx, db.ApproveParams{
+		ChannelID: d.ChannelID,
+		UserID:    d.UserID,
+		Manual:    true,
+	}); nil != err {
+		return errors.Wrap(err, "unable to approve")
+	}
+	s.funcUnban(ctx, d)
+	return nil
+}
 
-	if s.env.twitchOwnerID == "" ||
-		s.env.twitchClientSecret == "" ||
-		s.env.twitchRedirectURL == "" ||
-		s.env.twitchClientID == "" {
-		return errors.New("missing environment variable")
+// unapproveUser removes the approval of a user in a channel, and bans them.
+func (s *Server) unapproveUser(ctx context.Context, d Data) error {
+	if err := s.q.Unapprove(ctx, db.UnapproveParams{
+		ChannelID: d.ChannelID,
+		UserID:    d.UserID,
+	}); nil != err {
+		return errors.Wrap(err, "unable to unapprove")
 	}
+	s.funcBan(ctx, d, 0, "bot unapproved")
 	return nil
 }
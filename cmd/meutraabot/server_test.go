@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+)
+
+func TestCheckUser(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	s.knownBots = func() ([]Bot, error) {
+		return []Bot{{Username: testSpamBot.Login, UserID: testSpamBot.ID, ChannelCount: 5000}}, nil
+	}
+
+	s.checkUser(testBroadcaster.Login, testSpamBot.Login)
+	actions := twitch.TakeActions()
+	if len(actions) != 1 || actions[0] != (ModerationAction{
+		Action:    "ban",
+		ChannelID: testBroadcaster.ID,
+		UserID:    testSpamBot.ID,
+		Reason:    "bot in 5000 channels",
+	}) {
+		t.Errorf("expected spambot to be banned, got %v", actions)
+	}
+
+	// Users that are not known bots are approved
+	s.checkUser(testBroadcaster.Login, testViewer.Login)
+	if actions := twitch.TakeActions(); len(actions) != 0 {
+		t.Errorf("expected no moderation, got %v", actions)
+	}
+	approved, err := s.q.IsApproved(ctx, db.IsApprovedParams{ChannelID: testBroadcaster.ID, UserID: testViewer.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved != 1 {
+		t.Error("expected viewer to be approved")
+	}
+
+	// Approved bots are left alone
+	if err := s.q.Approve(ctx, db.ApproveParams{ChannelID: testBroadcaster.ID, UserID: testSpamBot.ID, Manual: true}); err != nil {
+		t.Fatal(err)
+	}
+	s.checkUser(testBroadcaster.Login, testSpamBot.Login)
+	if actions := twitch.TakeActions(); len(actions) != 0 {
+		t.Errorf("expected no moderation, got %v", actions)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// Twitch requires tokens to be validated at least hourly
+	tokenCheckInterval = time.Hour
+	// Refresh the token when it expires within this
+	tokenRefreshMargin = time.Hour
+	// How often authorization is requested again while it is pending, so an
+	// expired URL or device code is replaced
+	authorizationCheckInterval = 10 * time.Minute
+)
+
+// TokenStatus describes the bot's user access token.
+type TokenStatus struct {
+	Valid       bool      `json:"valid"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CheckedAt   time.Time `json:"checked_at"`
+	NextCheckAt time.Time `json:"next_check_at"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	// Consecutive failed checks
+	Failures int    `json:"failures"`
+	Error    string `json:"error,omitempty"`
+}
+
+// TokenStatus returns the status of the bot's user access token.
+func (s *Server) TokenStatus() TokenStatus {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+	return s.token
+}
+
+// updateTokenStatus changes the token status and wakes everything waiting in
+// WaitForAuthorization.
+func (s *Server) updateTokenStatus(update func(t *TokenStatus)) {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+	update(&s.token)
+	close(s.tokenChanged)
+	s.tokenChanged = make(chan struct{})
+}
+
+// checkToken asks the token supervisor to check the token now.
+func (s *Server) checkToken() {
+	select {
+	case s.tokenCheck <- struct{}{}:
+	default:
+	}
+}
+
+// rotateToken gives a new user access token to everything that uses it. The
+// IRC client uses it the next time it logs in, including when it reconnects
+// on its own. The IRC client is not replaced while the token is given to it.
+func (s *Server) rotateToken(token string) {
+	s.ircMu.Lock()
+	defer s.ircMu.Unlock()
+	s.twitch.SetUserAccessToken(token)
+	if s.irc != nil {
+		s.irc.SetIRCToken("oauth:" + token)
+	}
+}
+
+// WaitForAuthorization blocks until the token supervisor has found a valid
+// user access token.
+func (s *Server) WaitForAuthorization() {
+	for {
+		s.tokenMu.Lock()
+		valid, changed := s.token.Valid, s.tokenChanged
+		s.tokenMu.Unlock()
+		if valid {
+			return
+		}
+		<-changed
+	}
+}
+
+// superviseToken validates the user access token, refreshing it before it
+// expires and requesting authorization when it can not be refreshed. Failed
+// checks are retried with exponential backoff.
+func (s *Server) superviseToken() {
+	for {
+		expiresIn, err := s.EnsureValidUserToken()
+		now := time.Now()
+
+		wait := tokenCheckInterval
+		failures := 0
+		switch {
+		case err == nil:
+			// Check again in time to refresh the token before it expires
+			if refresh := expiresIn - tokenRefreshMargin; refresh < wait {
+				wait = refresh
+			}
+			if wait < time.Minute {
+				wait = time.Minute
+			}
+		case err == errNotAuthorized:
+			authLog.Warn("chat is disabled until the bot account is authorized")
+			wait = authorizationCheckInterval
+		default:
+			failures = s.TokenStatus().Failures + 1
+			wait = backoff(failures, 5*time.Second, 10*time.Minute)
+			authLog.Error("unable to ensure a valid user access token", "retry_in", wait, "err", err)
+		}
+
+		s.updateTokenStatus(func(t *TokenStatus) {
+			t.CheckedAt = now
+			t.NextCheckAt = now.Add(wait)
+			t.Failures = failures
+			t.Error = ""
+			switch {
+			case err == nil:
+				t.Valid = true
+				t.ExpiresAt = now.Add(expiresIn)
+			case err == errNotAuthorized:
+				t.Valid = false
+				t.ExpiresAt = time.Time{}
+				t.Error = err.Error()
+			default:
+				// The token may still work if it could not be checked
+				t.Valid = t.Valid && now.Before(t.ExpiresAt)
+				t.Error = err.Error()
+			}
+		})
+
+		select {
+		case <-time.After(wait):
+		case <-s.tokenCheck:
+		}
+	}
+}
+
+// backoff returns the delay before retrying after consecutive failures,
+// doubling from min up to max, with jitter.
+func backoff(failures int, min, max time.Duration) time.Duration {
+	d := max
+	if failures < 32 {
+		if shifted := min << (failures - 1); shifted > 0 && shifted < max {
+			d = shifted
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
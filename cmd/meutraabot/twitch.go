@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	irc "github.com/gempir/go-twitch-irc/v3"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/pkg/errors"
+)
+
+// TwitchAPI is the part of the Twitch API the bot uses.
+type TwitchAPI interface {
+	GetUsers(params *helix.UsersParams) (*helix.UsersResponse, error)
+	GetUsersFollows(params *helix.UsersFollowsParams) (*helix.UsersFollowsResponse, error)
+	GetChannelChatChatters(params *helix.GetChatChattersParams) (*helix.GetChatChattersResponse, error)
+	GetStreams(params *helix.StreamsParams) (*helix.StreamsResponse, error)
+	BanUser(params *helix.BanUserParams) (*helix.BanUserResponse, error)
+	UnbanUser(params *helix.UnbanUserParams) (*helix.UnbanUserResponse, error)
+	// DeleteChatMessage deletes a message, or every message in the channel
+	// when messageID is empty, returning the status code of the response.
+	DeleteChatMessage(broadcasterID, moderatorID, messageID string) (int, error)
+
+	GetAuthorizationURL(params *helix.AuthorizationURLParams) string
+	RequestUserAccessToken(code string) (*helix.UserAccessTokenResponse, error)
+	RefreshUserAccessToken(refreshToken string) (*helix.RefreshTokenResponse, error)
+	ValidateToken(accessToken string) (bool, *helix.ValidateTokenResponse, error)
+	GetUserAccessToken() string
+	SetUserAccessToken(accessToken string)
+}
+
+// Chat is the part of the chat connection the bot uses to talk in channels.
+type Chat interface {
+	Say(channel, text string)
+	Reply(channel, parentMsgID, text string)
+	Join(channels ...string)
+	Depart(channel string)
+	SetIRCToken(ircToken string)
+	Disconnect() error
+}
+
+var (
+	_ TwitchAPI = (*helixTwitch)(nil)
+	_ Chat      = (*irc.Client)(nil)
+)
+
+// helixTwitch implements TwitchAPI with the helix client, adding the
+// endpoints it is missing.
+type helixTwitch struct {
+	*helix.Client
+	clientID string
+	client   *http.Client
+}
+
+func newHelixTwitch(config TwitchConfig, client *http.Client) (*helixTwitch, error) {
+	c, err := helix.NewClient(&helix.Options{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RedirectURI:  config.RedirectURL,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create twitch api client")
+	}
+	return &helixTwitch{Client: c, clientID: config.ClientID, client: client}, nil
+}
+
+// https://dev.twitch.tv/docs/api/reference#delete-chat-messages
+func (h *helixTwitch) DeleteChatMessage(broadcasterID, moderatorID, messageID string) (int, error) {
+	req, err := http.NewRequest(http.MethodDelete, "https://api.twitch.tv/helix/moderation/chat", nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to create request")
+	}
+
+	req.Header.Add("Authorization", "Bearer "+h.GetUserAccessToken())
+	req.Header.Add("Client-Id", h.clientID)
+
+	q := req.URL.Query()
+	q.Add("broadcaster_id", broadcasterID)
+	q.Add("moderator_id", moderatorID)
+	if "" != messageID {
+		q.Add("message_id", messageID)
+	}
+	req.URL.RawQuery = q.Encode()
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to do request")
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	twitchLog.Debug("deleted chat message", "channel_id", broadcasterID, "message_id", messageID, "status", resp.StatusCode, "body", string(body), "err", err)
+	return resp.StatusCode, nil
+}
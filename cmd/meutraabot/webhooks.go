@@ -0,0 +1,578 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/meutraa/meutraabot/pkg/db"
+	"github.com/samber/lo"
+)
+
+// The events that webhooks may subscribe to.
+const (
+	WebhookBotBanned      = "bot_banned"
+	WebhookCommandChanged = "command_changed"
+	WebhookChannelJoined  = "channel_joined"
+	WebhookChannelLeft    = "channel_left"
+)
+
+var webhookEvents = []string{
+	WebhookBotBanned,
+	WebhookCommandChanged,
+	WebhookChannelJoined,
+	WebhookChannelLeft,
+}
+
+// BotBannedEvent is a known bot that was banned for not being approved.
+type BotBannedEvent struct {
+	UserID string `json:"user_id"`
+	User   string `json:"user"`
+	Reason string `json:"reason"`
+}
+
+// CommandChangedEvent is a command that was set or deleted. The template and
+// description are empty for deleted commands.
+type CommandChangedEvent struct {
+	Name        string `json:"name"`
+	Action      string `json:"action"`
+	Template    string `json:"template,omitempty"`
+	Description string `json:"description,omitempty"`
+	UpdatedBy   string `json:"updated_by"`
+}
+
+// ChannelEvent is a channel the bot joined or left.
+type ChannelEvent struct {
+	Login string `json:"login"`
+}
+
+// How many times a delivery is attempted before it is given up on.
+const webhookAttempts = 5
+
+// The delay before the second attempt of a delivery, doubling for each
+// attempt after it.
+var webhookBackoff = 5 * time.Second
+
+// How long a webhook has to respond to each attempt.
+const webhookTimeout = 10 * time.Second
+
+// How long attempts to deliver to webhooks are kept in the delivery log.
+const webhookDeliveryRetention = 7 * 24 * time.Hour
+
+// Whether webhooks may be delivered to addresses that are not public, which
+// would let users of the API reach services that only the bot can.
+var webhookPrivateAddresses = false
+
+// webhookClient delivers webhooks, connecting only to public addresses
+// whatever the URL of a webhook resolves to, including after redirects.
+var webhookClient = newWebhookClient()
+
+func newWebhookClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be connected to instead of the webhook
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: webhookTimeout, Control: dialPublic}).DialContext
+	return &http.Client{Transport: transport, Timeout: webhookTimeout}
+}
+
+// dialPublic refuses to connect to an address that webhooks may not be
+// delivered to, once the host has been resolved.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if addr, err := netip.ParseAddr(host); err != nil || !publicAddress(addr) {
+		return fmt.Errorf("webhooks may not be delivered to %v", host)
+	}
+	return nil
+}
+
+// The addresses webhooks may not be delivered to, which are not public or
+// commonly reach services in the network the bot runs in.
+var webhookDeniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, including cloud metadata
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+	netip.MustParsePrefix("::/128"),          // unspecified
+	netip.MustParsePrefix("::1/128"),         // loopback
+	netip.MustParsePrefix("::ffff:0:0/96"),   // IPv4-mapped
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local NAT64
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001::/23"),       // protocol assignments, including Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link-local
+	netip.MustParsePrefix("fec0::/10"),       // site-local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// publicAddress reports whether webhooks may be delivered to an address, which
+// must not be in any of webhookDeniedPrefixes.
+func publicAddress(addr netip.Addr) bool {
+	if webhookPrivateAddresses {
+		return true
+	}
+	// IPv4 addresses are checked as such however they are written
+	addr = addr.Unmap().WithZone("")
+	if !addr.IsValid() {
+		return false
+	}
+	for _, prefix := range webhookDeniedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// The header holding the hex encoded HMAC-SHA256 of the body, keyed by the
+// webhook's secret.
+const webhookSignatureHeader = "X-Meutraabot-Signature"
+
+func webhookSecret(id int64) string {
+	return "webhook/" + strconv.FormatInt(id, 10) + "/secret"
+}
+
+// signWebhook returns the signature of a webhook payload.
+func signWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscriber is a webhook subscribed to an event, with the secret its
+// deliveries are signed with.
+type subscriber struct {
+	hook   db.Webhook
+	secret string
+}
+
+// subscribers returns the webhooks of a channel that subscribe to an event.
+func (s *Server) subscribers(ctx context.Context, channelID, event string) []subscriber {
+	hooks, err := s.q.GetWebhooks(ctx, channelID)
+	if err != nil && err != sql.ErrNoRows {
+		dbLog.Error("unable to get webhooks", "channel_id", channelID, "err", err)
+		return nil
+	}
+
+	subscribers := []subscriber{}
+	for _, hook := range hooks {
+		if !lo.Contains(strings.Split(hook.Events, ","), event) {
+			continue
+		}
+		secret, err := s.secrets.Get(ctx, webhookSecret(hook.ID))
+		if err != nil {
+			apiLog.Error("unable to get webhook secret", "webhook_id", hook.ID, "err", err)
+			continue
+		}
+		subscribers = append(subscribers, subscriber{hook: hook, secret: secret})
+	}
+	return subscribers
+}
+
+// eventPayload returns the body of a delivery of an event.
+func eventPayload(channelID, event string, data any) ([]byte, error) {
+	return json.Marshal(Event{
+		Type:      event,
+		ChannelID: channelID,
+		Time:      time.Now().UTC(),
+		Data:      data,
+	})
+}
+
+// notify delivers an event to the channel's webhooks that subscribe to it,
+// in the background.
+func (s *Server) notify(channelID, event string, data any) {
+	payload, err := eventPayload(channelID, event, data)
+	if err != nil {
+		apiLog.Error("unable to marshal webhook event", "event", event, "err", err)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+
+		for _, sub := range s.subscribers(ctx, channelID, event) {
+			go s.deliver(sub, event, payload)
+		}
+	}()
+}
+
+// notifyLeft delivers the channel_left event to the webhooks of a channel that
+// was unregistered, and then deletes them so that they are not delivered to
+// if it registers again. The subscribers are found before they are deleted,
+// and their deliveries made in the background.
+func (s *Server) notifyLeft(ctx context.Context, channelID string, data ChannelEvent) error {
+	subscribers := s.subscribers(ctx, channelID, WebhookChannelLeft)
+	if err := deleteWebhooks(ctx, s.q, channelID); err != nil {
+		return err
+	}
+
+	payload, err := eventPayload(channelID, WebhookChannelLeft, data)
+	if err != nil {
+		apiLog.Error("unable to marshal webhook event", "event", WebhookChannelLeft, "err", err)
+		return nil
+	}
+	for _, sub := range subscribers {
+		go s.deliver(sub, WebhookChannelLeft, payload)
+	}
+	return nil
+}
+
+// deleteWebhooks deletes the webhooks of a channel with their secrets and
+// delivery logs.
+func deleteWebhooks(ctx context.Context, q db.Querier, channelID string) error {
+	hooks, err := q.GetWebhooks(ctx, channelID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := q.DeleteWebhook(ctx, db.DeleteWebhookParams{ChannelID: channelID, ID: hook.ID}); err != nil {
+			return err
+		}
+		if err := q.DeleteWebhookDeliveries(ctx, hook.ID); err != nil {
+			return err
+		}
+		if err := q.DeleteSecret(ctx, webhookSecret(hook.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver posts a payload to a webhook, retrying with exponential backoff
+// until it responds with a 2xx status, and recording every attempt.
+func (s *Server) deliver(sub subscriber, event string, payload []byte) {
+	hook := sub.hook
+	log := apiLog.With("webhook_id", hook.ID, "channel_id", hook.ChannelID, "event", event)
+	signature := signWebhook(sub.secret, payload)
+
+	delay := webhookBackoff
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		status, err := postWebhook(webhookClient, hook.Url, event, signature, payload)
+		errText := ""
+		if err != nil {
+			errText = err.Error()
+		} else if status < 200 || status > 299 {
+			errText = http.StatusText(status)
+		}
+
+		if err := s.q.RecordWebhookDelivery(context.Background(), db.RecordWebhookDeliveryParams{
+			WebhookID:  hook.ID,
+			Event:      event,
+			Payload:    string(payload),
+			Attempt:    int64(attempt),
+			StatusCode: int64(status),
+			Error:      errText,
+			CreatedAt:  time.Now().UTC(),
+		}); err != nil {
+			log.Error("unable to record webhook delivery", "err", err)
+		}
+
+		if errText == "" {
+			log.Debug("delivered webhook", "attempt", attempt)
+			return
+		}
+		log.Warn("unable to deliver webhook", "attempt", attempt, "status", status, "err", errText)
+	}
+}
+
+// postWebhook sends one attempt of a delivery, returning the response status.
+func postWebhook(client *http.Client, target, event, signature string, payload []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "meutraabot")
+	req.Header.Set("X-Meutraabot-Event", event)
+	req.Header.Set(webhookSignatureHeader, signature)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+	return res.StatusCode, nil
+}
+
+// pruneWebhookDeliveries deletes attempts to deliver to webhooks that are
+// older than the retention period.
+func (s *Server) pruneWebhookDeliveries() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := s.q.DeleteWebhookDeliveriesBefore(ctx, time.Now().UTC().Add(-webhookDeliveryRetention)); err != nil {
+		dbLog.Error("unable to prune webhook deliveries", "err", err)
+	}
+}
+
+// Webhook is a webhook subscription as returned by the API. The secret is
+// only returned when the webhook is created.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+func newWebhook(hook db.Webhook) Webhook {
+	return Webhook{
+		ID:        hook.ID,
+		URL:       hook.Url,
+		Events:    strings.Split(hook.Events, ","),
+		CreatedBy: hook.CreatedBy,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+type WebhookBody struct {
+	URL string `json:"url"`
+	// Defaults to every event
+	Events []string `json:"events"`
+}
+
+// validateWebhook checks that a webhook posts to an http or https URL of a
+// public host and subscribes only to known events. Hosts are resolved again
+// when webhooks are delivered, so names are checked then.
+func validateWebhook(body WebhookBody) error {
+	u, err := url.Parse(body.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if addr, err := netip.ParseAddr(host); (err == nil && !publicAddress(addr)) ||
+		(!webhookPrivateAddresses && (host == "localhost" || strings.HasSuffix(host, ".localhost"))) {
+		return fmt.Errorf("url must be of a public host")
+	}
+	if len(body.URL) > 2000 {
+		return fmt.Errorf("url must be at most 2000 bytes")
+	}
+	for _, event := range body.Events {
+		if !lo.Contains(webhookEvents, event) {
+			return fmt.Errorf("unknown event %q, must be one of %v", event, strings.Join(webhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// channelWebhook returns the {webhook} of the route, responding with 404 if the
+// channel has no such webhook.
+func (s *Server) channelWebhook(w http.ResponseWriter, r *http.Request) (db.Webhook, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "webhook"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return db.Webhook{}, false
+	}
+
+	hook, err := s.q.GetWebhook(r.Context(), db.GetWebhookParams{ChannelID: requestChannel(r), ID: id})
+	if err == sql.ErrNoRows {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return db.Webhook{}, false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return db.Webhook{}, false
+	}
+	return hook, true
+}
+
+func (s *Server) listWebhooks() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hooks, err := s.q.GetWebhooks(r.Context(), requestChannel(r))
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res, err := json.Marshal(lo.Map(hooks, func(hook db.Webhook, _ int) Webhook { return newWebhook(hook) }))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
+
+// createWebhook subscribes a URL to the channel's events, responding with the
+// webhook and the secret its deliveries are signed with.
+func (s *Server) createWebhook() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := WebhookBody{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(body.Events) == 0 {
+			body.Events = webhookEvents
+		}
+		if err := validateWebhook(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		secret, err := generateToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		user, _ := requestUser(r)
+		hook, err := s.q.CreateWebhook(r.Context(), db.CreateWebhookParams{
+			ChannelID: requestChannel(r),
+			Url:       body.URL,
+			Events:    strings.Join(lo.Uniq(body.Events), ","),
+			CreatedBy: user.ID,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := s.secrets.Set(r.Context(), webhookSecret(hook.ID), secret); err != nil {
+			s.q.DeleteWebhook(r.Context(), db.DeleteWebhookParams{ChannelID: hook.ChannelID, ID: hook.ID})
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, hook.ChannelID, AuditWebhookCreated, nil, newWebhook(hook))
+
+		created := newWebhook(hook)
+		created.Secret = secret
+		res, err := json.Marshal(created)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	})
+}
+
+func (s *Server) deleteWebhook() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hook, ok := s.channelWebhook(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.q.DeleteWebhook(r.Context(), db.DeleteWebhookParams{ChannelID: hook.ChannelID, ID: hook.ID}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.q.DeleteWebhookDeliveries(r.Context(), hook.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.secrets.Delete(r.Context(), webhookSecret(hook.ID)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.auditRequest(r, hook.ChannelID, AuditWebhookDeleted, newWebhook(hook), nil)
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// WebhookDelivery is an attempt to deliver an event to a webhook as returned
+// by the API.
+type WebhookDelivery struct {
+	ID      int64           `json:"id"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+	Attempt int64           `json:"attempt"`
+	// 0 when there was no response
+	StatusCode int64     `json:"status_code"`
+	Error      string    `json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newWebhookDelivery(d db.WebhookDelivery) WebhookDelivery {
+	return WebhookDelivery{
+		ID:         d.ID,
+		Event:      d.Event,
+		Payload:    json.RawMessage(d.Payload),
+		Attempt:    d.Attempt,
+		StatusCode: d.StatusCode,
+		Error:      d.Error,
+		CreatedAt:  d.CreatedAt,
+	}
+}
+
+// listWebhookDeliveries lists the most recent attempts to deliver to a
+// webhook, newest first.
+func (s *Server) listWebhookDeliveries() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hook, ok := s.channelWebhook(w, r)
+		if !ok {
+			return
+		}
+
+		limit := int64(50)
+		if str := r.URL.Query().Get("limit"); str != "" {
+			var err error
+			limit, err = strconv.ParseInt(str, 10, 64)
+			if err != nil || limit < 1 || limit > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+		}
+
+		deliveries, err := s.q.GetWebhookDeliveries(r.Context(), db.GetWebhookDeliveriesParams{WebhookID: hook.ID, Limit: limit})
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res, err := json.Marshal(lo.Map(deliveries, func(d db.WebhookDelivery, _ int) WebhookDelivery { return newWebhookDelivery(d) }))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	})
+}
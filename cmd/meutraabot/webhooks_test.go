@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+)
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	twitch.Tokens["viewer-token"] = testViewer.ID
+
+	for _, invalid := range []string{
+		`{"url": "ftp://example.com"}`,
+		`{"url": "http://127.0.0.1:8080/hook"}`,
+		`{"url": "http://[::1]/hook"}`,
+		`{"url": "http://169.254.169.254/latest/meta-data"}`,
+		`{"url": "http://10.0.0.1/hook"}`,
+		`{"url": "http://0.0.0.0/hook"}`,
+		`{"url": "http://localhost/hook"}`,
+		`{"url": "http://100.64.0.1/hook"}`,
+		`{"url": "http://[64:ff9b::a9fe:a9fe]/hook"}`,
+	} {
+		if w := request(t, s, http.MethodPost, "/channels/2/webhooks", "streamer-token", invalid); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %v", invalid, w.Code)
+		}
+	}
+	if _, err := webhookClient.Get("http://127.0.0.1:1/"); err == nil || !strings.Contains(err.Error(), "may not be delivered") {
+		t.Errorf("expected a private address to be refused when dialed, got %v", err)
+	}
+
+	// The receiver listens on a loopback address
+	backoff := webhookBackoff
+	webhookBackoff = time.Millisecond
+	webhookPrivateAddresses = true
+	t.Cleanup(func() {
+		webhookBackoff = backoff
+		webhookPrivateAddresses = false
+	})
+
+	// The receiver fails the first delivery, so that it is retried
+	type received struct {
+		event, signature string
+		body             []byte
+	}
+	deliveries := make(chan received, 10)
+	attempts := atomic.Int32{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- received{r.Header.Get("X-Meutraabot-Event"), r.Header.Get(webhookSignatureHeader), body}
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(receiver.Close)
+
+	body := `{"url": "` + receiver.URL + `", "events": ["command_changed"]}`
+	if w := request(t, s, http.MethodPost, "/channels/2/webhooks", "viewer-token", body); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a viewer, got %v", w.Code)
+	}
+	if w := request(t, s, http.MethodPost, "/channels/2/webhooks", "streamer-token", `{"url": "`+receiver.URL+`", "events": ["everything"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown event, got %v", w.Code)
+	}
+
+	w := request(t, s, http.MethodPost, "/channels/2/webhooks", "streamer-token", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %v: %v", w.Code, w.Body)
+	}
+	hook := Webhook{}
+	if err := json.Unmarshal(w.Body.Bytes(), &hook); err != nil {
+		t.Fatal(err)
+	}
+	if hook.Secret == "" || len(hook.Events) != 1 {
+		t.Fatalf("expected a secret and one event, got %+v", hook)
+	}
+
+	// Only subscribed events are delivered
+	s.notify(testBroadcaster.ID, WebhookChannelLeft, ChannelEvent{Login: testBroadcaster.Login})
+	s.handleCommand(ctx, chatMessage(testViewer, "+set ^!hi hello", map[string]string{"mod": "1"}))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case d := <-deliveries:
+			if d.event != WebhookCommandChanged {
+				t.Errorf("expected a %v event, got %v", WebhookCommandChanged, d.event)
+			}
+			if d.signature != signWebhook(hook.Secret, d.body) {
+				t.Errorf("expected the delivery to be signed, got %q", d.signature)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the webhook to be delivered twice")
+		}
+	}
+
+	// The delivery log is written after each response
+	path := "/channels/2/webhooks/" + strconv.FormatInt(hook.ID, 10) + "/deliveries"
+	logged := []WebhookDelivery{}
+	for deadline := time.Now().Add(5 * time.Second); len(logged) < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		w := request(t, s, http.MethodGet, path, "streamer-token", "")
+		if err := json.Unmarshal(w.Body.Bytes(), &logged); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(logged) != 2 || logged[0].Attempt != 2 || logged[0].StatusCode != 200 || logged[1].StatusCode != 500 {
+		t.Errorf("expected a failed and a successful attempt, got %+v", logged)
+	}
+
+	if w := request(t, s, http.MethodDelete, "/channels/2/webhooks/"+strconv.FormatInt(hook.ID, 10), "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	if w := request(t, s, http.MethodGet, path, "streamer-token", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected the webhook to be deleted, got %v", w.Code)
+	}
+	if exists, err := s.secrets.Exists(ctx, webhookSecret(hook.ID)); err != nil || exists {
+		t.Errorf("expected the secret to be deleted, %v", err)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	for address, public := range map[string]bool{
+		"1.1.1.1":            true,
+		"8.8.8.8":            true,
+		"2606:4700::1111":    true,
+		"0.0.0.0":            false,
+		"0.1.2.3":            false,
+		"10.1.2.3":           false,
+		"100.64.0.1":         false,
+		"100.127.255.254":    false,
+		"100.128.0.1":        true,
+		"127.0.0.1":          false,
+		"169.254.169.254":    false,
+		"172.16.0.1":         false,
+		"172.32.0.1":         true,
+		"192.168.1.1":        false,
+		"198.18.0.1":         false,
+		"198.19.255.254":     false,
+		"198.20.0.1":         true,
+		"224.0.0.1":          false,
+		"255.255.255.255":    false,
+		"::":                 false,
+		"::1":                false,
+		"::ffff:127.0.0.1":   false,
+		"::ffff:8.8.8.8":     true,
+		"64:ff9b::a9fe:a9fe": false,
+		"64:ff9b:1::1":       false,
+		"2001:db8::1":        false,
+		"2001::1":            false,
+		"2002:a00:1::1":      false,
+		"fc00::1":            false,
+		"fd12:3456::1":       false,
+		"fe80::1%eth0":       false,
+		"ff02::1":            false,
+	} {
+		if got := publicAddress(netip.MustParseAddr(address)); got != public {
+			t.Errorf("expected %v to be public: %v, got %v", address, public, got)
+		}
+	}
+}
+
+func TestWebhooksUnregistered(t *testing.T) {
+	ctx := context.Background()
+	s, twitch, _ := newTestServer(t)
+	twitch.Tokens["streamer-token"] = testBroadcaster.ID
+	webhookPrivateAddresses = true
+	t.Cleanup(func() { webhookPrivateAddresses = false })
+
+	events := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events <- r.Header.Get("X-Meutraabot-Event")
+	}))
+	t.Cleanup(receiver.Close)
+
+	if err := s.q.CreateChannel(ctx, testBroadcaster.ID); err != nil {
+		t.Fatal(err)
+	}
+	w := request(t, s, http.MethodPost, "/channels/2/webhooks", "streamer-token", `{"url": "`+receiver.URL+`", "events": ["channel_joined", "channel_left"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %v: %v", w.Code, w.Body)
+	}
+	hook := Webhook{}
+	if err := json.Unmarshal(w.Body.Bytes(), &hook); err != nil {
+		t.Fatal(err)
+	}
+
+	// The webhook still hears that the channel was left
+	if w := request(t, s, http.MethodDelete, "/channels/2", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	select {
+	case event := <-events:
+		if event != WebhookChannelLeft {
+			t.Errorf("expected a %v event, got %v", WebhookChannelLeft, event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the webhook to be delivered to")
+	}
+	if hooks, err := s.q.GetWebhooks(ctx, testBroadcaster.ID); err != nil || len(hooks) != 0 {
+		t.Errorf("expected the webhooks to be deleted, got %v, %v", hooks, err)
+	}
+	if exists, err := s.secrets.Exists(ctx, webhookSecret(hook.ID)); err != nil || exists {
+		t.Errorf("expected the secret to be deleted, %v", err)
+	}
+
+	// Nor is it delivered to once the channel registers again
+	if w := request(t, s, http.MethodPut, "/channels/2", "streamer-token", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", w.Code, w.Body)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("expected no more deliveries, got %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPruneWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestServer(t)
+
+	for _, age := range []time.Duration{0, webhookDeliveryRetention + time.Hour} {
+		if err := s.q.RecordWebhookDelivery(ctx, db.RecordWebhookDeliveryParams{
+			WebhookID: 1,
+			Event:     WebhookChannelJoined,
+			Payload:   "{}",
+			Attempt:   1,
+			CreatedAt: time.Now().UTC().Add(-age),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.pruneWebhookDeliveries()
+
+	deliveries, err := s.q.GetWebhookDeliveries(ctx, db.GetWebhookDeliveriesParams{WebhookID: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || time.Since(deliveries[0].CreatedAt) > time.Hour {
+		t.Errorf("expected only the recent delivery to be kept, got %+v", deliveries)
+	}
+}
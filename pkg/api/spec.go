@@ -0,0 +1,12 @@
+// Package api holds the OpenAPI specification of the bot's HTTP API, and a
+// client generated from it.
+package api
+
+import _ "embed"
+
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen@v2.3.0 -config codegen.yaml openapi.yaml
+
+// Spec is the OpenAPI specification in YAML.
+//
+//go:embed openapi.yaml
+var Spec []byte
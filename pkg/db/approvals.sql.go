@@ -13,7 +13,8 @@ const approve = `-- name: Approve :exec
 INSERT INTO
   approvals (channel_id, user_id, manual)
 VALUES
-  (?, ?, ?) ON CONFLICT DO NOTHING
+  (?, ?, ?) ON CONFLICT (channel_id, user_id) DO UPDATE
+  SET manual = approvals.manual OR excluded.manual
 `
 
 type ApproveParams struct {
@@ -61,6 +62,102 @@ func (q *Queries) GetApprovals(ctx context.Context, channelID string) ([]Approva
 	return items, nil
 }
 
+const getApprovalsByType = `-- name: GetApprovalsByType :many
+SELECT
+  channel_id, user_id, manual
+FROM
+  approvals
+WHERE
+  channel_id = ?
+  AND manual = ?
+  AND user_id > ?
+ORDER BY user_id ASC
+LIMIT ?
+`
+
+type GetApprovalsByTypeParams struct {
+	ChannelID string
+	Manual    bool
+	UserID    string
+	Limit     int64
+}
+
+func (q *Queries) GetApprovalsByType(ctx context.Context, arg GetApprovalsByTypeParams) ([]Approval, error) {
+	rows, err := q.query(ctx, q.getApprovalsByTypeStmt, getApprovalsByType,
+		arg.ChannelID,
+		arg.Manual,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Approval
+	for rows.Next() {
+		var i Approval
+		if err := rows.Scan(&i.ChannelID, &i.UserID, &i.Manual); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getApprovalsByTypeBefore = `-- name: GetApprovalsByTypeBefore :many
+SELECT
+  channel_id, user_id, manual
+FROM
+  approvals
+WHERE
+  channel_id = ?
+  AND manual = ?
+  AND user_id < ?
+ORDER BY user_id DESC
+LIMIT ?
+`
+
+type GetApprovalsByTypeBeforeParams struct {
+	ChannelID string
+	Manual    bool
+	UserID    string
+	Limit     int64
+}
+
+func (q *Queries) GetApprovalsByTypeBefore(ctx context.Context, arg GetApprovalsByTypeBeforeParams) ([]Approval, error) {
+	rows, err := q.query(ctx, q.getApprovalsByTypeBeforeStmt, getApprovalsByTypeBefore,
+		arg.ChannelID,
+		arg.Manual,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Approval
+	for rows.Next() {
+		var i Approval
+		if err := rows.Scan(&i.ChannelID, &i.UserID, &i.Manual); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const isApproved = `-- name: IsApproved :one
 SELECT
   COUNT(*)
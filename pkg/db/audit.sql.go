@@ -0,0 +1,87 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: audit.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const getAuditLog = `-- name: GetAuditLog :many
+SELECT id, channel_id, actor_id, actor_login, "action", "before", "after", created_at
+  FROM audit_log
+  WHERE channel_id = ?
+  AND id < ?
+  ORDER BY id DESC
+  LIMIT ?
+`
+
+type GetAuditLogParams struct {
+	ChannelID string
+	ID        int64
+	Limit     int64
+}
+
+func (q *Queries) GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error) {
+	rows, err := q.query(ctx, q.getAuditLogStmt, getAuditLog, arg.ChannelID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.ActorID,
+			&i.ActorLogin,
+			&i.Action,
+			&i.Before,
+			&i.After,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordAudit = `-- name: RecordAudit :exec
+INSERT INTO audit_log (channel_id, actor_id, actor_login, action, before, after, created_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type RecordAuditParams struct {
+	ChannelID  string
+	ActorID    string
+	ActorLogin string
+	Action     string
+	Before     sql.NullString
+	After      sql.NullString
+	CreatedAt  time.Time
+}
+
+func (q *Queries) RecordAudit(ctx context.Context, arg RecordAuditParams) error {
+	_, err := q.exec(ctx, q.recordAuditStmt, recordAudit,
+		arg.ChannelID,
+		arg.ActorID,
+		arg.ActorLogin,
+		arg.Action,
+		arg.Before,
+		arg.After,
+		arg.CreatedAt,
+	)
+	return err
+}
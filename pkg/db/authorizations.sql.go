@@ -0,0 +1,103 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: authorizations.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createAuthorization = `-- name: CreateAuthorization :exec
+INSERT INTO authorizations (state, flow, device_code, user_code, verification_uri, created_at, expires_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateAuthorizationParams struct {
+	State           string
+	Flow            string
+	DeviceCode      string
+	UserCode        string
+	VerificationUri string
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
+
+func (q *Queries) CreateAuthorization(ctx context.Context, arg CreateAuthorizationParams) error {
+	_, err := q.exec(ctx, q.createAuthorizationStmt, createAuthorization,
+		arg.State,
+		arg.Flow,
+		arg.DeviceCode,
+		arg.UserCode,
+		arg.VerificationUri,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const deleteAuthorization = `-- name: DeleteAuthorization :exec
+DELETE FROM authorizations WHERE state = ?
+`
+
+func (q *Queries) DeleteAuthorization(ctx context.Context, state string) error {
+	_, err := q.exec(ctx, q.deleteAuthorizationStmt, deleteAuthorization, state)
+	return err
+}
+
+const deleteExpiredAuthorizations = `-- name: DeleteExpiredAuthorizations :exec
+DELETE FROM authorizations WHERE expires_at <= ?
+`
+
+func (q *Queries) DeleteExpiredAuthorizations(ctx context.Context, expiresAt time.Time) error {
+	_, err := q.exec(ctx, q.deleteExpiredAuthorizationsStmt, deleteExpiredAuthorizations, expiresAt)
+	return err
+}
+
+const getAuthorization = `-- name: GetAuthorization :one
+SELECT state, flow, device_code, user_code, verification_uri, created_at, expires_at FROM authorizations WHERE state = ?
+`
+
+func (q *Queries) GetAuthorization(ctx context.Context, state string) (Authorization, error) {
+	row := q.queryRow(ctx, q.getAuthorizationStmt, getAuthorization, state)
+	var i Authorization
+	err := row.Scan(
+		&i.State,
+		&i.Flow,
+		&i.DeviceCode,
+		&i.UserCode,
+		&i.VerificationUri,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getPendingAuthorization = `-- name: GetPendingAuthorization :one
+SELECT state, flow, device_code, user_code, verification_uri, created_at, expires_at FROM authorizations
+  WHERE flow = ? AND expires_at > ?
+  ORDER BY created_at DESC
+  LIMIT 1
+`
+
+type GetPendingAuthorizationParams struct {
+	Flow      string
+	ExpiresAt time.Time
+}
+
+func (q *Queries) GetPendingAuthorization(ctx context.Context, arg GetPendingAuthorizationParams) (Authorization, error) {
+	row := q.queryRow(ctx, q.getPendingAuthorizationStmt, getPendingAuthorization, arg.Flow, arg.ExpiresAt)
+	var i Authorization
+	err := row.Scan(
+		&i.State,
+		&i.Flow,
+		&i.DeviceCode,
+		&i.UserCode,
+		&i.VerificationUri,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
@@ -32,7 +32,7 @@ func (q *Queries) DeleteChannel(ctx context.Context, channelID string) error {
 }
 
 const getChannel = `-- name: GetChannel :one
-SELECT channel_id, autoreply_enabled, autoreply_frequency, reply_safety, openai_token FROM channels WHERE channel_id = ?
+SELECT channel_id, autoreply_enabled, autoreply_frequency, reply_safety, openai_token, chatlog_enabled, chatlog_retention_days, commands_public, listed FROM channels WHERE channel_id = ?
 `
 
 func (q *Queries) GetChannel(ctx context.Context, channelID string) (Channel, error) {
@@ -44,10 +44,48 @@ func (q *Queries) GetChannel(ctx context.Context, channelID string) (Channel, er
 		&i.AutoreplyFrequency,
 		&i.ReplySafety,
 		&i.OpenaiToken,
+		&i.ChatlogEnabled,
+		&i.ChatlogRetentionDays,
+		&i.CommandsPublic,
+		&i.Listed,
 	)
 	return i, err
 }
 
+const getChannelTokens = `-- name: GetChannelTokens :many
+SELECT channel_id, openai_token
+  FROM channels
+  WHERE openai_token IS NOT NULL
+`
+
+type GetChannelTokensRow struct {
+	ChannelID   string
+	OpenaiToken sql.NullString
+}
+
+func (q *Queries) GetChannelTokens(ctx context.Context) ([]GetChannelTokensRow, error) {
+	rows, err := q.query(ctx, q.getChannelTokensStmt, getChannelTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChannelTokensRow
+	for rows.Next() {
+		var i GetChannelTokensRow
+		if err := rows.Scan(&i.ChannelID, &i.OpenaiToken); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChannels = `-- name: GetChannels :many
 SELECT channel_id FROM channels
 `
@@ -75,19 +113,54 @@ func (q *Queries) GetChannels(ctx context.Context) ([]string, error) {
 	return items, nil
 }
 
+const getListedChannels = `-- name: GetListedChannels :many
+SELECT channel_id FROM channels WHERE listed = true
+`
+
+func (q *Queries) GetListedChannels(ctx context.Context) ([]string, error) {
+	rows, err := q.query(ctx, q.getListedChannelsStmt, getListedChannels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var channel_id string
+		if err := rows.Scan(&channel_id); err != nil {
+			return nil, err
+		}
+		items = append(items, channel_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateChannel = `-- name: UpdateChannel :exec
 UPDATE channels
  SET autoreply_enabled = ?,
   autoreply_frequency = ?,
-  reply_safety = ?
+  reply_safety = ?,
+  chatlog_enabled = ?,
+  chatlog_retention_days = ?,
+  commands_public = ?,
+  listed = ?
  WHERE channel_id = ?
 `
 
 type UpdateChannelParams struct {
-	AutoreplyEnabled   bool
-	AutoreplyFrequency float64
-	ReplySafety        int64
-	ChannelID          string
+	AutoreplyEnabled     bool
+	AutoreplyFrequency   float64
+	ReplySafety          int64
+	ChatlogEnabled       bool
+	ChatlogRetentionDays int64
+	CommandsPublic       bool
+	Listed               bool
+	ChannelID            string
 }
 
 func (q *Queries) UpdateChannel(ctx context.Context, arg UpdateChannelParams) error {
@@ -95,6 +168,10 @@ func (q *Queries) UpdateChannel(ctx context.Context, arg UpdateChannelParams) er
 		arg.AutoreplyEnabled,
 		arg.AutoreplyFrequency,
 		arg.ReplySafety,
+		arg.ChatlogEnabled,
+		arg.ChatlogRetentionDays,
+		arg.CommandsPublic,
+		arg.Listed,
 		arg.ChannelID,
 	)
 	return err
@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const deleteCommand = `-- name: DeleteCommand :exec
@@ -44,6 +45,32 @@ func (q *Queries) GetCommand(ctx context.Context, arg GetCommandParams) (string,
 	return template, err
 }
 
+const getCommandDetails = `-- name: GetCommandDetails :one
+SELECT channel_id, name, template, description, updated_by, updated_at
+  FROM commands
+  WHERE channel_id = ?
+  AND name = ?
+`
+
+type GetCommandDetailsParams struct {
+	ChannelID string
+	Name      string
+}
+
+func (q *Queries) GetCommandDetails(ctx context.Context, arg GetCommandDetailsParams) (Command, error) {
+	row := q.queryRow(ctx, q.getCommandDetailsStmt, getCommandDetails, arg.ChannelID, arg.Name)
+	var i Command
+	err := row.Scan(
+		&i.ChannelID,
+		&i.Name,
+		&i.Template,
+		&i.Description,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getCommands = `-- name: GetCommands :many
 SELECT name
   FROM commands
@@ -75,15 +102,18 @@ func (q *Queries) GetCommands(ctx context.Context, channelID string) ([]string,
 }
 
 const getCommandsByID = `-- name: GetCommandsByID :many
-SELECT name, template
+SELECT name, template, description, updated_by, updated_at
   FROM commands
   WHERE channel_id = ?
   ORDER BY name ASC
 `
 
 type GetCommandsByIDRow struct {
-	Name     string
-	Template string
+	Name        string
+	Template    string
+	Description string
+	UpdatedBy   string
+	UpdatedAt   sql.NullTime
 }
 
 func (q *Queries) GetCommandsByID(ctx context.Context, channelID string) ([]GetCommandsByIDRow, error) {
@@ -95,7 +125,13 @@ func (q *Queries) GetCommandsByID(ctx context.Context, channelID string) ([]GetC
 	var items []GetCommandsByIDRow
 	for rows.Next() {
 		var i GetCommandsByIDRow
-		if err := rows.Scan(&i.Name, &i.Template); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.Template,
+			&i.Description,
+			&i.UpdatedBy,
+			&i.UpdatedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -123,14 +159,19 @@ SELECT
   AND regexp(name, ?)
 `
 
+type GetMatchingCommandsParams struct {
+	ChannelID string
+	Message   string
+}
+
 type GetMatchingCommandsRow struct {
 	Template  string
 	Name      string
 	ChannelID string
 }
 
-func (q *Queries) GetMatchingCommands(ctx context.Context, regexp ...interface{}) ([]GetMatchingCommandsRow, error) {
-	rows, err := q.query(ctx, q.getMatchingCommandsStmt, getMatchingCommands, regexp...)
+func (q *Queries) GetMatchingCommands(ctx context.Context, arg GetMatchingCommandsParams) ([]GetMatchingCommandsRow, error) {
+	rows, err := q.query(ctx, q.getMatchingCommandsStmt, getMatchingCommands, arg.ChannelID, arg.Message)
 	if err != nil {
 		return nil, err
 	}
@@ -152,20 +193,61 @@ func (q *Queries) GetMatchingCommands(ctx context.Context, regexp ...interface{}
 	return items, nil
 }
 
+const saveCommand = `-- name: SaveCommand :exec
+INSERT INTO commands (channel_id, name, template, description, updated_by, updated_at)
+  VALUES (?, ?, ?, ?, ?, ?)
+  ON CONFLICT(channel_id, name) DO UPDATE
+  SET template = excluded.template,
+    description = excluded.description,
+    updated_by = excluded.updated_by,
+    updated_at = excluded.updated_at
+`
+
+type SaveCommandParams struct {
+	ChannelID   string
+	Name        string
+	Template    string
+	Description string
+	UpdatedBy   string
+	UpdatedAt   sql.NullTime
+}
+
+func (q *Queries) SaveCommand(ctx context.Context, arg SaveCommandParams) error {
+	_, err := q.exec(ctx, q.saveCommandStmt, saveCommand,
+		arg.ChannelID,
+		arg.Name,
+		arg.Template,
+		arg.Description,
+		arg.UpdatedBy,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
 const setCommand = `-- name: SetCommand :exec
-INSERT INTO commands (channel_id, name, template)
-  VALUES (?, ?, ?)
+INSERT INTO commands (channel_id, name, template, updated_by, updated_at)
+  VALUES (?, ?, ?, ?, ?)
   ON CONFLICT(channel_id, name) DO UPDATE
-  SET template = ?
+  SET template = excluded.template,
+    updated_by = excluded.updated_by,
+    updated_at = excluded.updated_at
 `
 
 type SetCommandParams struct {
 	ChannelID string
 	Name      string
 	Template  string
+	UpdatedBy string
+	UpdatedAt sql.NullTime
 }
 
 func (q *Queries) SetCommand(ctx context.Context, arg SetCommandParams) error {
-	_, err := q.exec(ctx, q.setCommandStmt, setCommand, arg.ChannelID, arg.Name, arg.Template, arg.Template)
+	_, err := q.exec(ctx, q.setCommandStmt, setCommand,
+		arg.ChannelID,
+		arg.Name,
+		arg.Template,
+		arg.UpdatedBy,
+		arg.UpdatedAt,
+	)
 	return err
 }
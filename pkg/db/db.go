@@ -30,45 +30,180 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.approveStmt, err = db.PrepareContext(ctx, approve); err != nil {
 		return nil, fmt.Errorf("error preparing query Approve: %w", err)
 	}
+	if q.createAuthorizationStmt, err = db.PrepareContext(ctx, createAuthorization); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAuthorization: %w", err)
+	}
 	if q.createChannelStmt, err = db.PrepareContext(ctx, createChannel); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateChannel: %w", err)
 	}
+	if q.createIncomingWebhookStmt, err = db.PrepareContext(ctx, createIncomingWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateIncomingWebhook: %w", err)
+	}
+	if q.createWebhookStmt, err = db.PrepareContext(ctx, createWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhook: %w", err)
+	}
+	if q.deleteAuthorizationStmt, err = db.PrepareContext(ctx, deleteAuthorization); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAuthorization: %w", err)
+	}
 	if q.deleteChannelStmt, err = db.PrepareContext(ctx, deleteChannel); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteChannel: %w", err)
 	}
 	if q.deleteCommandStmt, err = db.PrepareContext(ctx, deleteCommand); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteCommand: %w", err)
 	}
+	if q.deleteDashboardGrantStmt, err = db.PrepareContext(ctx, deleteDashboardGrant); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteDashboardGrant: %w", err)
+	}
+	if q.deleteExpiredAuthorizationsStmt, err = db.PrepareContext(ctx, deleteExpiredAuthorizations); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredAuthorizations: %w", err)
+	}
+	if q.deleteIncomingWebhookStmt, err = db.PrepareContext(ctx, deleteIncomingWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteIncomingWebhook: %w", err)
+	}
+	if q.deleteMessagesBeforeStmt, err = db.PrepareContext(ctx, deleteMessagesBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMessagesBefore: %w", err)
+	}
+	if q.deleteNumberStmt, err = db.PrepareContext(ctx, deleteNumber); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteNumber: %w", err)
+	}
+	if q.deleteSecretStmt, err = db.PrepareContext(ctx, deleteSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSecret: %w", err)
+	}
+	if q.deleteWebhookStmt, err = db.PrepareContext(ctx, deleteWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhook: %w", err)
+	}
+	if q.deleteWebhookDeliveriesStmt, err = db.PrepareContext(ctx, deleteWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhookDeliveries: %w", err)
+	}
+	if q.deleteWebhookDeliveriesBeforeStmt, err = db.PrepareContext(ctx, deleteWebhookDeliveriesBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhookDeliveriesBefore: %w", err)
+	}
 	if q.getApprovalsStmt, err = db.PrepareContext(ctx, getApprovals); err != nil {
 		return nil, fmt.Errorf("error preparing query GetApprovals: %w", err)
 	}
+	if q.getApprovalsByTypeStmt, err = db.PrepareContext(ctx, getApprovalsByType); err != nil {
+		return nil, fmt.Errorf("error preparing query GetApprovalsByType: %w", err)
+	}
+	if q.getApprovalsByTypeBeforeStmt, err = db.PrepareContext(ctx, getApprovalsByTypeBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query GetApprovalsByTypeBefore: %w", err)
+	}
+	if q.getAuditLogStmt, err = db.PrepareContext(ctx, getAuditLog); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAuditLog: %w", err)
+	}
+	if q.getAuthorizationStmt, err = db.PrepareContext(ctx, getAuthorization); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAuthorization: %w", err)
+	}
+	if q.getBansStmt, err = db.PrepareContext(ctx, getBans); err != nil {
+		return nil, fmt.Errorf("error preparing query GetBans: %w", err)
+	}
 	if q.getChannelStmt, err = db.PrepareContext(ctx, getChannel); err != nil {
 		return nil, fmt.Errorf("error preparing query GetChannel: %w", err)
 	}
+	if q.getChannelTokensStmt, err = db.PrepareContext(ctx, getChannelTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChannelTokens: %w", err)
+	}
 	if q.getChannelsStmt, err = db.PrepareContext(ctx, getChannels); err != nil {
 		return nil, fmt.Errorf("error preparing query GetChannels: %w", err)
 	}
 	if q.getCommandStmt, err = db.PrepareContext(ctx, getCommand); err != nil {
 		return nil, fmt.Errorf("error preparing query GetCommand: %w", err)
 	}
+	if q.getCommandDetailsStmt, err = db.PrepareContext(ctx, getCommandDetails); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCommandDetails: %w", err)
+	}
 	if q.getCommandsStmt, err = db.PrepareContext(ctx, getCommands); err != nil {
 		return nil, fmt.Errorf("error preparing query GetCommands: %w", err)
 	}
 	if q.getCommandsByIDStmt, err = db.PrepareContext(ctx, getCommandsByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetCommandsByID: %w", err)
 	}
+	if q.getDashboardGrantStmt, err = db.PrepareContext(ctx, getDashboardGrant); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDashboardGrant: %w", err)
+	}
+	if q.getDashboardGrantsStmt, err = db.PrepareContext(ctx, getDashboardGrants); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDashboardGrants: %w", err)
+	}
+	if q.getDashboardGrantsForUserStmt, err = db.PrepareContext(ctx, getDashboardGrantsForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDashboardGrantsForUser: %w", err)
+	}
+	if q.getIncomingWebhookStmt, err = db.PrepareContext(ctx, getIncomingWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIncomingWebhook: %w", err)
+	}
+	if q.getIncomingWebhookByTokenStmt, err = db.PrepareContext(ctx, getIncomingWebhookByToken); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIncomingWebhookByToken: %w", err)
+	}
+	if q.getIncomingWebhooksStmt, err = db.PrepareContext(ctx, getIncomingWebhooks); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIncomingWebhooks: %w", err)
+	}
+	if q.getListedChannelsStmt, err = db.PrepareContext(ctx, getListedChannels); err != nil {
+		return nil, fmt.Errorf("error preparing query GetListedChannels: %w", err)
+	}
 	if q.getMatchingCommandsStmt, err = db.PrepareContext(ctx, getMatchingCommands); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMatchingCommands: %w", err)
 	}
 	if q.getNumberStmt, err = db.PrepareContext(ctx, getNumber); err != nil {
 		return nil, fmt.Errorf("error preparing query GetNumber: %w", err)
 	}
+	if q.getNumbersStmt, err = db.PrepareContext(ctx, getNumbers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNumbers: %w", err)
+	}
+	if q.getPendingAuthorizationStmt, err = db.PrepareContext(ctx, getPendingAuthorization); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPendingAuthorization: %w", err)
+	}
+	if q.getRecentMessagesStmt, err = db.PrepareContext(ctx, getRecentMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRecentMessages: %w", err)
+	}
+	if q.getSecretStmt, err = db.PrepareContext(ctx, getSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSecret: %w", err)
+	}
+	if q.getSecretsStmt, err = db.PrepareContext(ctx, getSecrets); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSecrets: %w", err)
+	}
+	if q.getWebhookStmt, err = db.PrepareContext(ctx, getWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhook: %w", err)
+	}
+	if q.getWebhookDeliveriesStmt, err = db.PrepareContext(ctx, getWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhookDeliveries: %w", err)
+	}
+	if q.getWebhooksStmt, err = db.PrepareContext(ctx, getWebhooks); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhooks: %w", err)
+	}
+	if q.insertMessageStmt, err = db.PrepareContext(ctx, insertMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertMessage: %w", err)
+	}
 	if q.isApprovedStmt, err = db.PrepareContext(ctx, isApproved); err != nil {
 		return nil, fmt.Errorf("error preparing query IsApproved: %w", err)
 	}
+	if q.liftBansStmt, err = db.PrepareContext(ctx, liftBans); err != nil {
+		return nil, fmt.Errorf("error preparing query LiftBans: %w", err)
+	}
+	if q.recordAuditStmt, err = db.PrepareContext(ctx, recordAudit); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordAudit: %w", err)
+	}
+	if q.recordBanStmt, err = db.PrepareContext(ctx, recordBan); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordBan: %w", err)
+	}
+	if q.recordWebhookDeliveryStmt, err = db.PrepareContext(ctx, recordWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordWebhookDelivery: %w", err)
+	}
+	if q.saveCommandStmt, err = db.PrepareContext(ctx, saveCommand); err != nil {
+		return nil, fmt.Errorf("error preparing query SaveCommand: %w", err)
+	}
+	if q.searchUserMessagesStmt, err = db.PrepareContext(ctx, searchUserMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchUserMessages: %w", err)
+	}
 	if q.setCommandStmt, err = db.PrepareContext(ctx, setCommand); err != nil {
 		return nil, fmt.Errorf("error preparing query SetCommand: %w", err)
 	}
+	if q.setDashboardGrantStmt, err = db.PrepareContext(ctx, setDashboardGrant); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDashboardGrant: %w", err)
+	}
+	if q.setNumberStmt, err = db.PrepareContext(ctx, setNumber); err != nil {
+		return nil, fmt.Errorf("error preparing query SetNumber: %w", err)
+	}
+	if q.setSecretStmt, err = db.PrepareContext(ctx, setSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSecret: %w", err)
+	}
 	if q.unapproveStmt, err = db.PrepareContext(ctx, unapprove); err != nil {
 		return nil, fmt.Errorf("error preparing query Unapprove: %w", err)
 	}
@@ -93,11 +228,31 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing approveStmt: %w", cerr)
 		}
 	}
+	if q.createAuthorizationStmt != nil {
+		if cerr := q.createAuthorizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAuthorizationStmt: %w", cerr)
+		}
+	}
 	if q.createChannelStmt != nil {
 		if cerr := q.createChannelStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createChannelStmt: %w", cerr)
 		}
 	}
+	if q.createIncomingWebhookStmt != nil {
+		if cerr := q.createIncomingWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createIncomingWebhookStmt: %w", cerr)
+		}
+	}
+	if q.createWebhookStmt != nil {
+		if cerr := q.createWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookStmt: %w", cerr)
+		}
+	}
+	if q.deleteAuthorizationStmt != nil {
+		if cerr := q.deleteAuthorizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAuthorizationStmt: %w", cerr)
+		}
+	}
 	if q.deleteChannelStmt != nil {
 		if cerr := q.deleteChannelStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteChannelStmt: %w", cerr)
@@ -108,16 +263,91 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteCommandStmt: %w", cerr)
 		}
 	}
+	if q.deleteDashboardGrantStmt != nil {
+		if cerr := q.deleteDashboardGrantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteDashboardGrantStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredAuthorizationsStmt != nil {
+		if cerr := q.deleteExpiredAuthorizationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredAuthorizationsStmt: %w", cerr)
+		}
+	}
+	if q.deleteIncomingWebhookStmt != nil {
+		if cerr := q.deleteIncomingWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteIncomingWebhookStmt: %w", cerr)
+		}
+	}
+	if q.deleteMessagesBeforeStmt != nil {
+		if cerr := q.deleteMessagesBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMessagesBeforeStmt: %w", cerr)
+		}
+	}
+	if q.deleteNumberStmt != nil {
+		if cerr := q.deleteNumberStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteNumberStmt: %w", cerr)
+		}
+	}
+	if q.deleteSecretStmt != nil {
+		if cerr := q.deleteSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSecretStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookStmt != nil {
+		if cerr := q.deleteWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookDeliveriesStmt != nil {
+		if cerr := q.deleteWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookDeliveriesBeforeStmt != nil {
+		if cerr := q.deleteWebhookDeliveriesBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookDeliveriesBeforeStmt: %w", cerr)
+		}
+	}
 	if q.getApprovalsStmt != nil {
 		if cerr := q.getApprovalsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getApprovalsStmt: %w", cerr)
 		}
 	}
+	if q.getApprovalsByTypeStmt != nil {
+		if cerr := q.getApprovalsByTypeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getApprovalsByTypeStmt: %w", cerr)
+		}
+	}
+	if q.getApprovalsByTypeBeforeStmt != nil {
+		if cerr := q.getApprovalsByTypeBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getApprovalsByTypeBeforeStmt: %w", cerr)
+		}
+	}
+	if q.getAuditLogStmt != nil {
+		if cerr := q.getAuditLogStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAuditLogStmt: %w", cerr)
+		}
+	}
+	if q.getAuthorizationStmt != nil {
+		if cerr := q.getAuthorizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAuthorizationStmt: %w", cerr)
+		}
+	}
+	if q.getBansStmt != nil {
+		if cerr := q.getBansStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getBansStmt: %w", cerr)
+		}
+	}
 	if q.getChannelStmt != nil {
 		if cerr := q.getChannelStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getChannelStmt: %w", cerr)
 		}
 	}
+	if q.getChannelTokensStmt != nil {
+		if cerr := q.getChannelTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChannelTokensStmt: %w", cerr)
+		}
+	}
 	if q.getChannelsStmt != nil {
 		if cerr := q.getChannelsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getChannelsStmt: %w", cerr)
@@ -128,6 +358,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getCommandStmt: %w", cerr)
 		}
 	}
+	if q.getCommandDetailsStmt != nil {
+		if cerr := q.getCommandDetailsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCommandDetailsStmt: %w", cerr)
+		}
+	}
 	if q.getCommandsStmt != nil {
 		if cerr := q.getCommandsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getCommandsStmt: %w", cerr)
@@ -138,6 +373,41 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getCommandsByIDStmt: %w", cerr)
 		}
 	}
+	if q.getDashboardGrantStmt != nil {
+		if cerr := q.getDashboardGrantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDashboardGrantStmt: %w", cerr)
+		}
+	}
+	if q.getDashboardGrantsStmt != nil {
+		if cerr := q.getDashboardGrantsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDashboardGrantsStmt: %w", cerr)
+		}
+	}
+	if q.getDashboardGrantsForUserStmt != nil {
+		if cerr := q.getDashboardGrantsForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDashboardGrantsForUserStmt: %w", cerr)
+		}
+	}
+	if q.getIncomingWebhookStmt != nil {
+		if cerr := q.getIncomingWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIncomingWebhookStmt: %w", cerr)
+		}
+	}
+	if q.getIncomingWebhookByTokenStmt != nil {
+		if cerr := q.getIncomingWebhookByTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIncomingWebhookByTokenStmt: %w", cerr)
+		}
+	}
+	if q.getIncomingWebhooksStmt != nil {
+		if cerr := q.getIncomingWebhooksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIncomingWebhooksStmt: %w", cerr)
+		}
+	}
+	if q.getListedChannelsStmt != nil {
+		if cerr := q.getListedChannelsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getListedChannelsStmt: %w", cerr)
+		}
+	}
 	if q.getMatchingCommandsStmt != nil {
 		if cerr := q.getMatchingCommandsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getMatchingCommandsStmt: %w", cerr)
@@ -148,16 +418,106 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getNumberStmt: %w", cerr)
 		}
 	}
+	if q.getNumbersStmt != nil {
+		if cerr := q.getNumbersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNumbersStmt: %w", cerr)
+		}
+	}
+	if q.getPendingAuthorizationStmt != nil {
+		if cerr := q.getPendingAuthorizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPendingAuthorizationStmt: %w", cerr)
+		}
+	}
+	if q.getRecentMessagesStmt != nil {
+		if cerr := q.getRecentMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRecentMessagesStmt: %w", cerr)
+		}
+	}
+	if q.getSecretStmt != nil {
+		if cerr := q.getSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSecretStmt: %w", cerr)
+		}
+	}
+	if q.getSecretsStmt != nil {
+		if cerr := q.getSecretsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSecretsStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookStmt != nil {
+		if cerr := q.getWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookDeliveriesStmt != nil {
+		if cerr := q.getWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.getWebhooksStmt != nil {
+		if cerr := q.getWebhooksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhooksStmt: %w", cerr)
+		}
+	}
+	if q.insertMessageStmt != nil {
+		if cerr := q.insertMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertMessageStmt: %w", cerr)
+		}
+	}
 	if q.isApprovedStmt != nil {
 		if cerr := q.isApprovedStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing isApprovedStmt: %w", cerr)
 		}
 	}
+	if q.liftBansStmt != nil {
+		if cerr := q.liftBansStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing liftBansStmt: %w", cerr)
+		}
+	}
+	if q.recordAuditStmt != nil {
+		if cerr := q.recordAuditStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordAuditStmt: %w", cerr)
+		}
+	}
+	if q.recordBanStmt != nil {
+		if cerr := q.recordBanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordBanStmt: %w", cerr)
+		}
+	}
+	if q.recordWebhookDeliveryStmt != nil {
+		if cerr := q.recordWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordWebhookDeliveryStmt: %w", cerr)
+		}
+	}
+	if q.saveCommandStmt != nil {
+		if cerr := q.saveCommandStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing saveCommandStmt: %w", cerr)
+		}
+	}
+	if q.searchUserMessagesStmt != nil {
+		if cerr := q.searchUserMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchUserMessagesStmt: %w", cerr)
+		}
+	}
 	if q.setCommandStmt != nil {
 		if cerr := q.setCommandStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing setCommandStmt: %w", cerr)
 		}
 	}
+	if q.setDashboardGrantStmt != nil {
+		if cerr := q.setDashboardGrantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDashboardGrantStmt: %w", cerr)
+		}
+	}
+	if q.setNumberStmt != nil {
+		if cerr := q.setNumberStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setNumberStmt: %w", cerr)
+		}
+	}
+	if q.setSecretStmt != nil {
+		if cerr := q.setSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSecretStmt: %w", cerr)
+		}
+	}
 	if q.unapproveStmt != nil {
 		if cerr := q.unapproveStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing unapproveStmt: %w", cerr)
@@ -210,49 +570,139 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                      DBTX
-	tx                      *sql.Tx
-	addToNumberStmt         *sql.Stmt
-	approveStmt             *sql.Stmt
-	createChannelStmt       *sql.Stmt
-	deleteChannelStmt       *sql.Stmt
-	deleteCommandStmt       *sql.Stmt
-	getApprovalsStmt        *sql.Stmt
-	getChannelStmt          *sql.Stmt
-	getChannelsStmt         *sql.Stmt
-	getCommandStmt          *sql.Stmt
-	getCommandsStmt         *sql.Stmt
-	getCommandsByIDStmt     *sql.Stmt
-	getMatchingCommandsStmt *sql.Stmt
-	getNumberStmt           *sql.Stmt
-	isApprovedStmt          *sql.Stmt
-	setCommandStmt          *sql.Stmt
-	unapproveStmt           *sql.Stmt
-	updateChannelStmt       *sql.Stmt
-	updateChannelTokenStmt  *sql.Stmt
+	db                                DBTX
+	tx                                *sql.Tx
+	addToNumberStmt                   *sql.Stmt
+	approveStmt                       *sql.Stmt
+	createAuthorizationStmt           *sql.Stmt
+	createChannelStmt                 *sql.Stmt
+	createIncomingWebhookStmt         *sql.Stmt
+	createWebhookStmt                 *sql.Stmt
+	deleteAuthorizationStmt           *sql.Stmt
+	deleteChannelStmt                 *sql.Stmt
+	deleteCommandStmt                 *sql.Stmt
+	deleteDashboardGrantStmt          *sql.Stmt
+	deleteExpiredAuthorizationsStmt   *sql.Stmt
+	deleteIncomingWebhookStmt         *sql.Stmt
+	deleteMessagesBeforeStmt          *sql.Stmt
+	deleteNumberStmt                  *sql.Stmt
+	deleteSecretStmt                  *sql.Stmt
+	deleteWebhookStmt                 *sql.Stmt
+	deleteWebhookDeliveriesStmt       *sql.Stmt
+	deleteWebhookDeliveriesBeforeStmt *sql.Stmt
+	getApprovalsStmt                  *sql.Stmt
+	getApprovalsByTypeStmt            *sql.Stmt
+	getApprovalsByTypeBeforeStmt      *sql.Stmt
+	getAuditLogStmt                   *sql.Stmt
+	getAuthorizationStmt              *sql.Stmt
+	getBansStmt                       *sql.Stmt
+	getChannelStmt                    *sql.Stmt
+	getChannelTokensStmt              *sql.Stmt
+	getChannelsStmt                   *sql.Stmt
+	getCommandStmt                    *sql.Stmt
+	getCommandDetailsStmt             *sql.Stmt
+	getCommandsStmt                   *sql.Stmt
+	getCommandsByIDStmt               *sql.Stmt
+	getDashboardGrantStmt             *sql.Stmt
+	getDashboardGrantsStmt            *sql.Stmt
+	getDashboardGrantsForUserStmt     *sql.Stmt
+	getIncomingWebhookStmt            *sql.Stmt
+	getIncomingWebhookByTokenStmt     *sql.Stmt
+	getIncomingWebhooksStmt           *sql.Stmt
+	getListedChannelsStmt             *sql.Stmt
+	getMatchingCommandsStmt           *sql.Stmt
+	getNumberStmt                     *sql.Stmt
+	getNumbersStmt                    *sql.Stmt
+	getPendingAuthorizationStmt       *sql.Stmt
+	getRecentMessagesStmt             *sql.Stmt
+	getSecretStmt                     *sql.Stmt
+	getSecretsStmt                    *sql.Stmt
+	getWebhookStmt                    *sql.Stmt
+	getWebhookDeliveriesStmt          *sql.Stmt
+	getWebhooksStmt                   *sql.Stmt
+	insertMessageStmt                 *sql.Stmt
+	isApprovedStmt                    *sql.Stmt
+	liftBansStmt                      *sql.Stmt
+	recordAuditStmt                   *sql.Stmt
+	recordBanStmt                     *sql.Stmt
+	recordWebhookDeliveryStmt         *sql.Stmt
+	saveCommandStmt                   *sql.Stmt
+	searchUserMessagesStmt            *sql.Stmt
+	setCommandStmt                    *sql.Stmt
+	setDashboardGrantStmt             *sql.Stmt
+	setNumberStmt                     *sql.Stmt
+	setSecretStmt                     *sql.Stmt
+	unapproveStmt                     *sql.Stmt
+	updateChannelStmt                 *sql.Stmt
+	updateChannelTokenStmt            *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                      tx,
-		tx:                      tx,
-		addToNumberStmt:         q.addToNumberStmt,
-		approveStmt:             q.approveStmt,
-		createChannelStmt:       q.createChannelStmt,
-		deleteChannelStmt:       q.deleteChannelStmt,
-		deleteCommandStmt:       q.deleteCommandStmt,
-		getApprovalsStmt:        q.getApprovalsStmt,
-		getChannelStmt:          q.getChannelStmt,
-		getChannelsStmt:         q.getChannelsStmt,
-		getCommandStmt:          q.getCommandStmt,
-		getCommandsStmt:         q.getCommandsStmt,
-		getCommandsByIDStmt:     q.getCommandsByIDStmt,
-		getMatchingCommandsStmt: q.getMatchingCommandsStmt,
-		getNumberStmt:           q.getNumberStmt,
-		isApprovedStmt:          q.isApprovedStmt,
-		setCommandStmt:          q.setCommandStmt,
-		unapproveStmt:           q.unapproveStmt,
-		updateChannelStmt:       q.updateChannelStmt,
-		updateChannelTokenStmt:  q.updateChannelTokenStmt,
+		db:                                tx,
+		tx:                                tx,
+		addToNumberStmt:                   q.addToNumberStmt,
+		approveStmt:                       q.approveStmt,
+		createAuthorizationStmt:           q.createAuthorizationStmt,
+		createChannelStmt:                 q.createChannelStmt,
+		createIncomingWebhookStmt:         q.createIncomingWebhookStmt,
+		createWebhookStmt:                 q.createWebhookStmt,
+		deleteAuthorizationStmt:           q.deleteAuthorizationStmt,
+		deleteChannelStmt:                 q.deleteChannelStmt,
+		deleteCommandStmt:                 q.deleteCommandStmt,
+		deleteDashboardGrantStmt:          q.deleteDashboardGrantStmt,
+		deleteExpiredAuthorizationsStmt:   q.deleteExpiredAuthorizationsStmt,
+		deleteIncomingWebhookStmt:         q.deleteIncomingWebhookStmt,
+		deleteMessagesBeforeStmt:          q.deleteMessagesBeforeStmt,
+		deleteNumberStmt:                  q.deleteNumberStmt,
+		deleteSecretStmt:                  q.deleteSecretStmt,
+		deleteWebhookStmt:                 q.deleteWebhookStmt,
+		deleteWebhookDeliveriesStmt:       q.deleteWebhookDeliveriesStmt,
+		deleteWebhookDeliveriesBeforeStmt: q.deleteWebhookDeliveriesBeforeStmt,
+		getApprovalsStmt:                  q.getApprovalsStmt,
+		getApprovalsByTypeStmt:            q.getApprovalsByTypeStmt,
+		getApprovalsByTypeBeforeStmt:      q.getApprovalsByTypeBeforeStmt,
+		getAuditLogStmt:                   q.getAuditLogStmt,
+		getAuthorizationStmt:              q.getAuthorizationStmt,
+		getBansStmt:                       q.getBansStmt,
+		getChannelStmt:                    q.getChannelStmt,
+		getChannelTokensStmt:              q.getChannelTokensStmt,
+		getChannelsStmt:                   q.getChannelsStmt,
+		getCommandStmt:                    q.getCommandStmt,
+		getCommandDetailsStmt:             q.getCommandDetailsStmt,
+		getCommandsStmt:                   q.getCommandsStmt,
+		getCommandsByIDStmt:               q.getCommandsByIDStmt,
+		getDashboardGrantStmt:             q.getDashboardGrantStmt,
+		getDashboardGrantsStmt:            q.getDashboardGrantsStmt,
+		getDashboardGrantsForUserStmt:     q.getDashboardGrantsForUserStmt,
+		getIncomingWebhookStmt:            q.getIncomingWebhookStmt,
+		getIncomingWebhookByTokenStmt:     q.getIncomingWebhookByTokenStmt,
+		getIncomingWebhooksStmt:           q.getIncomingWebhooksStmt,
+		getListedChannelsStmt:             q.getListedChannelsStmt,
+		getMatchingCommandsStmt:           q.getMatchingCommandsStmt,
+		getNumberStmt:                     q.getNumberStmt,
+		getNumbersStmt:                    q.getNumbersStmt,
+		getPendingAuthorizationStmt:       q.getPendingAuthorizationStmt,
+		getRecentMessagesStmt:             q.getRecentMessagesStmt,
+		getSecretStmt:                     q.getSecretStmt,
+		getSecretsStmt:                    q.getSecretsStmt,
+		getWebhookStmt:                    q.getWebhookStmt,
+		getWebhookDeliveriesStmt:          q.getWebhookDeliveriesStmt,
+		getWebhooksStmt:                   q.getWebhooksStmt,
+		insertMessageStmt:                 q.insertMessageStmt,
+		isApprovedStmt:                    q.isApprovedStmt,
+		liftBansStmt:                      q.liftBansStmt,
+		recordAuditStmt:                   q.recordAuditStmt,
+		recordBanStmt:                     q.recordBanStmt,
+		recordWebhookDeliveryStmt:         q.recordWebhookDeliveryStmt,
+		saveCommandStmt:                   q.saveCommandStmt,
+		searchUserMessagesStmt:            q.searchUserMessagesStmt,
+		setCommandStmt:                    q.setCommandStmt,
+		setDashboardGrantStmt:             q.setDashboardGrantStmt,
+		setNumberStmt:                     q.setNumberStmt,
+		setSecretStmt:                     q.setSecretStmt,
+		unapproveStmt:                     q.unapproveStmt,
+		updateChannelStmt:                 q.updateChannelStmt,
+		updateChannelTokenStmt:            q.updateChannelTokenStmt,
 	}
 }
@@ -0,0 +1,386 @@
+package db_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db"
+)
+
+// These tests run against sqlite by default. Set TEST_DATABASE_ENGINE=postgres
+// and TEST_DATABASE_URL to run them against a postgres database instead; each
+// test creates a schema of its own in that database and drops it afterwards.
+func openTestDatabase(t *testing.T) (*sql.DB, db.Querier) {
+	t.Helper()
+
+	ctx := context.Background()
+	engine := testEngine()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if engine == db.SQLite && dsn == "" {
+		dsn = "file:" + filepath.Join(t.TempDir(), "test.sql") + "?mode=rwc"
+	}
+	if engine == db.Postgres {
+		dsn = testSchema(t, dsn)
+	}
+
+	conn, err := db.Open(ctx, engine, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if _, err := db.Migrate(ctx, engine, conn); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := db.PrepareQuerier(ctx, engine, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, q
+}
+
+// testSchema creates a schema for a test in the postgres database, dropping it
+// once the test is done, and returns the dsn with the schema as its search
+// path, so that every connection uses it.
+func testSchema(t *testing.T, dsn string) string {
+	t.Helper()
+
+	ctx := context.Background()
+	admin, err := db.Open(ctx, db.Postgres, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(b)
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Error(err)
+		}
+		admin.Close()
+	})
+
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
+
+func testEngine() db.Engine {
+	if engine := os.Getenv("TEST_DATABASE_ENGINE"); engine != "" {
+		return db.Engine(engine)
+	}
+	return db.SQLite
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	conn, _ := openTestDatabase(t)
+
+	applied, err := db.Migrate(ctx, testEngine(), conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected no pending migrations, applied %v", len(applied))
+	}
+
+	statuses, err := db.Status(ctx, testEngine(), conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("migration %v was not applied", status.Name)
+		}
+	}
+}
+
+func TestChannels(t *testing.T) {
+	ctx := context.Background()
+	_, q := openTestDatabase(t)
+
+	if err := q.CreateChannel(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	// Creating a channel twice is not an error
+	if err := q.CreateChannel(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	channel, err := q.GetChannel(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channel.AutoreplyEnabled || channel.AutoreplyFrequency != 2 || channel.ReplySafety != 2 ||
+		channel.OpenaiToken.Valid || channel.ChatlogEnabled || channel.ChatlogRetentionDays != 7 {
+		t.Errorf("unexpected channel defaults %+v", channel)
+	}
+
+	if err := q.UpdateChannel(ctx, db.UpdateChannelParams{
+		ChannelID:            "1",
+		AutoreplyEnabled:     true,
+		AutoreplyFrequency:   3.5,
+		ReplySafety:          1,
+		ChatlogEnabled:       true,
+		ChatlogRetentionDays: 30,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.UpdateChannelToken(ctx, db.UpdateChannelTokenParams{
+		ChannelID:   "1",
+		OpenaiToken: sql.NullString{String: "token", Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	channel, err = q.GetChannel(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := db.Channel{
+		ChannelID:            "1",
+		AutoreplyEnabled:     true,
+		AutoreplyFrequency:   3.5,
+		ReplySafety:          1,
+		OpenaiToken:          sql.NullString{String: "token", Valid: true},
+		ChatlogEnabled:       true,
+		ChatlogRetentionDays: 30,
+	}
+	if channel != expected {
+		t.Errorf("expected %+v, got %+v", expected, channel)
+	}
+
+	channels, err := q.GetChannels(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || channels[0] != "1" {
+		t.Errorf("unexpected channels %v", channels)
+	}
+
+	if err := q.DeleteChannel(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.GetChannel(ctx, "1"); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+	_, q := openTestDatabase(t)
+
+	for _, c := range []db.SetCommandParams{
+		{ChannelID: "1", Name: "^!hi", Template: "old"},
+		{ChannelID: "1", Name: "^!hi", Template: "hello"},
+		{ChannelID: "1", Name: "^!bye$", Template: "bye"},
+		{ChannelID: "0", Name: "^!(hi|hey)", Template: "global"},
+		{ChannelID: "2", Name: "^!hi", Template: "other channel"},
+	} {
+		if err := q.SetCommand(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	template, err := q.GetCommand(ctx, db.GetCommandParams{ChannelID: "1", Name: "^!hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template != "hello" {
+		t.Errorf("expected the command to be replaced, got %v", template)
+	}
+
+	names, err := q.GetCommands(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "^!bye$" || names[1] != "^!hi" {
+		t.Errorf("unexpected commands %v", names)
+	}
+
+	commands, err := q.GetCommandsByID(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 2 || commands[1].Template != "hello" {
+		t.Errorf("unexpected commands %+v", commands)
+	}
+
+	matches, err := q.GetMatchingCommands(ctx, db.GetMatchingCommandsParams{
+		ChannelID: "1",
+		Message:   "!hi there",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected a local and global match, got %+v", matches)
+	}
+	for _, m := range matches {
+		if (m.ChannelID == "1" && m.Template != "hello") || (m.ChannelID == "0" && m.Template != "global") {
+			t.Errorf("unexpected match %+v", m)
+		}
+	}
+
+	matches, err = q.GetMatchingCommands(ctx, db.GetMatchingCommandsParams{
+		ChannelID: "1",
+		Message:   "!bye now",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no matches, got %+v", matches)
+	}
+
+	if err := q.DeleteCommand(ctx, db.DeleteCommandParams{ChannelID: "1", Name: "^!hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.GetCommand(ctx, db.GetCommandParams{ChannelID: "1", Name: "^!hi"}); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestApprovals(t *testing.T) {
+	ctx := context.Background()
+	_, q := openTestDatabase(t)
+
+	for _, a := range []db.ApproveParams{
+		{ChannelID: "1", UserID: "10", Manual: true},
+		{ChannelID: "1", UserID: "11", Manual: false},
+		// Approving twice keeps the original approval
+		{ChannelID: "1", UserID: "10", Manual: false},
+	} {
+		if err := q.Approve(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	approvals, err := q.GetApprovals(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approvals) != 1 || approvals[0] != (db.Approval{ChannelID: "1", UserID: "10", Manual: true}) {
+		t.Errorf("expected only the manual approval, got %+v", approvals)
+	}
+
+	count, err := q.IsApproved(ctx, db.IsApprovedParams{ChannelID: "1", UserID: "11"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected user to be approved, got %v", count)
+	}
+
+	if err := q.Unapprove(ctx, db.UnapproveParams{ChannelID: "1", UserID: "11"}); err != nil {
+		t.Fatal(err)
+	}
+	count, err = q.IsApproved(ctx, db.IsApprovedParams{ChannelID: "1", UserID: "11"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected user to be unapproved, got %v", count)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	ctx := context.Background()
+	_, q := openTestDatabase(t)
+
+	for _, value := range []int64{5, -2} {
+		if err := q.AddToNumber(ctx, db.AddToNumberParams{ChannelID: "1", Name: "deaths", Value: value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	number, err := q.GetNumber(ctx, db.GetNumberParams{ChannelID: "1", Name: "deaths"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if number.Value != 3 {
+		t.Errorf("expected 3, got %v", number.Value)
+	}
+
+	if _, err := q.GetNumber(ctx, db.GetNumberParams{ChannelID: "2", Name: "deaths"}); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	ctx := context.Background()
+	_, q := openTestDatabase(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, text := range []string{"first message", "second message", "third"} {
+		if err := q.InsertMessage(ctx, db.InsertMessageParams{
+			MessageID:       "id",
+			ChannelID:       "1",
+			UserID:          "10",
+			UserLogin:       "viewer",
+			UserDisplayName: "Viewer",
+			Message:         text,
+			Tags:            "{}",
+			CreatedAt:       now.Add(time.Duration(i-2) * 24 * time.Hour),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recent, err := q.GetRecentMessages(ctx, db.GetRecentMessagesParams{ChannelID: "1", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].Message != "third" || recent[1].Message != "second message" {
+		t.Errorf("unexpected recent messages %+v", recent)
+	}
+	if !recent[0].CreatedAt.Equal(now) {
+		t.Errorf("expected created at %v, got %v", now, recent[0].CreatedAt)
+	}
+
+	found, err := q.SearchUserMessages(ctx, db.SearchUserMessagesParams{
+		ChannelID: "1",
+		UserLogin: "viewer",
+		Message:   "message",
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].Message != "second message" {
+		t.Errorf("unexpected search results %+v", found)
+	}
+
+	if err := q.DeleteMessagesBefore(ctx, db.DeleteMessagesBeforeParams{
+		ChannelID: "1",
+		CreatedAt: now.Add(-time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	recent, err = q.GetRecentMessages(ctx, db.GetRecentMessagesParams{ChannelID: "1", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 {
+		t.Errorf("expected old messages to be deleted, got %+v", recent)
+	}
+}
@@ -0,0 +1,152 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: grants.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteDashboardGrant = `-- name: DeleteDashboardGrant :exec
+DELETE FROM dashboard_grants
+  WHERE channel_id = ?
+  AND user_id = ?
+`
+
+type DeleteDashboardGrantParams struct {
+	ChannelID string
+	UserID    string
+}
+
+func (q *Queries) DeleteDashboardGrant(ctx context.Context, arg DeleteDashboardGrantParams) error {
+	_, err := q.exec(ctx, q.deleteDashboardGrantStmt, deleteDashboardGrant, arg.ChannelID, arg.UserID)
+	return err
+}
+
+const getDashboardGrant = `-- name: GetDashboardGrant :one
+SELECT channel_id, user_id, role, granted_by, created_at
+  FROM dashboard_grants
+  WHERE channel_id = ?
+  AND user_id = ?
+`
+
+type GetDashboardGrantParams struct {
+	ChannelID string
+	UserID    string
+}
+
+func (q *Queries) GetDashboardGrant(ctx context.Context, arg GetDashboardGrantParams) (DashboardGrant, error) {
+	row := q.queryRow(ctx, q.getDashboardGrantStmt, getDashboardGrant, arg.ChannelID, arg.UserID)
+	var i DashboardGrant
+	err := row.Scan(
+		&i.ChannelID,
+		&i.UserID,
+		&i.Role,
+		&i.GrantedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDashboardGrants = `-- name: GetDashboardGrants :many
+SELECT channel_id, user_id, role, granted_by, created_at
+  FROM dashboard_grants
+  WHERE channel_id = ?
+  ORDER BY user_id ASC
+`
+
+func (q *Queries) GetDashboardGrants(ctx context.Context, channelID string) ([]DashboardGrant, error) {
+	rows, err := q.query(ctx, q.getDashboardGrantsStmt, getDashboardGrants, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DashboardGrant
+	for rows.Next() {
+		var i DashboardGrant
+		if err := rows.Scan(
+			&i.ChannelID,
+			&i.UserID,
+			&i.Role,
+			&i.GrantedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDashboardGrantsForUser = `-- name: GetDashboardGrantsForUser :many
+SELECT channel_id, user_id, role, granted_by, created_at
+  FROM dashboard_grants
+  WHERE user_id = ?
+  ORDER BY channel_id ASC
+`
+
+func (q *Queries) GetDashboardGrantsForUser(ctx context.Context, userID string) ([]DashboardGrant, error) {
+	rows, err := q.query(ctx, q.getDashboardGrantsForUserStmt, getDashboardGrantsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DashboardGrant
+	for rows.Next() {
+		var i DashboardGrant
+		if err := rows.Scan(
+			&i.ChannelID,
+			&i.UserID,
+			&i.Role,
+			&i.GrantedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setDashboardGrant = `-- name: SetDashboardGrant :exec
+INSERT INTO dashboard_grants (channel_id, user_id, role, granted_by, created_at)
+  VALUES (?, ?, ?, ?, ?)
+  ON CONFLICT(channel_id, user_id) DO UPDATE
+  SET role = excluded.role,
+    granted_by = excluded.granted_by,
+    created_at = excluded.created_at
+`
+
+type SetDashboardGrantParams struct {
+	ChannelID string
+	UserID    string
+	Role      string
+	GrantedBy string
+	CreatedAt time.Time
+}
+
+func (q *Queries) SetDashboardGrant(ctx context.Context, arg SetDashboardGrantParams) error {
+	_, err := q.exec(ctx, q.setDashboardGrantStmt, setDashboardGrant,
+		arg.ChannelID,
+		arg.UserID,
+		arg.Role,
+		arg.GrantedBy,
+		arg.CreatedAt,
+	)
+	return err
+}
@@ -0,0 +1,144 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: incoming_webhooks.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createIncomingWebhook = `-- name: CreateIncomingWebhook :one
+INSERT INTO incoming_webhooks (channel_id, command, token_hash, created_by, created_at)
+  VALUES (?, ?, ?, ?, ?)
+  RETURNING id, channel_id, command, token_hash, created_by, created_at
+`
+
+type CreateIncomingWebhookParams struct {
+	ChannelID string
+	Command   string
+	TokenHash string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateIncomingWebhook(ctx context.Context, arg CreateIncomingWebhookParams) (IncomingWebhook, error) {
+	row := q.queryRow(ctx, q.createIncomingWebhookStmt, createIncomingWebhook,
+		arg.ChannelID,
+		arg.Command,
+		arg.TokenHash,
+		arg.CreatedBy,
+		arg.CreatedAt,
+	)
+	var i IncomingWebhook
+	err := row.Scan(
+		&i.ID,
+		&i.ChannelID,
+		&i.Command,
+		&i.TokenHash,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteIncomingWebhook = `-- name: DeleteIncomingWebhook :exec
+DELETE FROM incoming_webhooks
+  WHERE channel_id = ?
+  AND id = ?
+`
+
+type DeleteIncomingWebhookParams struct {
+	ChannelID string
+	ID        int64
+}
+
+func (q *Queries) DeleteIncomingWebhook(ctx context.Context, arg DeleteIncomingWebhookParams) error {
+	_, err := q.exec(ctx, q.deleteIncomingWebhookStmt, deleteIncomingWebhook, arg.ChannelID, arg.ID)
+	return err
+}
+
+const getIncomingWebhook = `-- name: GetIncomingWebhook :one
+SELECT id, channel_id, command, token_hash, created_by, created_at
+  FROM incoming_webhooks
+  WHERE channel_id = ?
+  AND id = ?
+`
+
+type GetIncomingWebhookParams struct {
+	ChannelID string
+	ID        int64
+}
+
+func (q *Queries) GetIncomingWebhook(ctx context.Context, arg GetIncomingWebhookParams) (IncomingWebhook, error) {
+	row := q.queryRow(ctx, q.getIncomingWebhookStmt, getIncomingWebhook, arg.ChannelID, arg.ID)
+	var i IncomingWebhook
+	err := row.Scan(
+		&i.ID,
+		&i.ChannelID,
+		&i.Command,
+		&i.TokenHash,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getIncomingWebhookByToken = `-- name: GetIncomingWebhookByToken :one
+SELECT id, channel_id, command, token_hash, created_by, created_at
+  FROM incoming_webhooks
+  WHERE token_hash = ?
+`
+
+func (q *Queries) GetIncomingWebhookByToken(ctx context.Context, tokenHash string) (IncomingWebhook, error) {
+	row := q.queryRow(ctx, q.getIncomingWebhookByTokenStmt, getIncomingWebhookByToken, tokenHash)
+	var i IncomingWebhook
+	err := row.Scan(
+		&i.ID,
+		&i.ChannelID,
+		&i.Command,
+		&i.TokenHash,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getIncomingWebhooks = `-- name: GetIncomingWebhooks :many
+SELECT id, channel_id, command, token_hash, created_by, created_at
+  FROM incoming_webhooks
+  WHERE channel_id = ?
+  ORDER BY id
+`
+
+func (q *Queries) GetIncomingWebhooks(ctx context.Context, channelID string) ([]IncomingWebhook, error) {
+	rows, err := q.query(ctx, q.getIncomingWebhooksStmt, getIncomingWebhooks, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IncomingWebhook
+	for rows.Next() {
+		var i IncomingWebhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.Command,
+			&i.TokenHash,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
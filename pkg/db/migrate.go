@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version int NOT NULL PRIMARY KEY,
+  name text NOT NULL,
+  applied_at timestamp NOT NULL
+)`
+
+// Migration is a numbered schema change, read from
+// migrations/ENGINE/NNNN_name.sql.
+type Migration struct {
+	Version int64
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus describes whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrations returns the embedded migrations for an engine ordered by version.
+func Migrations(engine Engine) ([]Migration, error) {
+	names, err := fs.Glob(migrationFiles, path.Join("migrations", string(engine), "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("migration %v is not named NNNN_name.sql", name)
+		}
+
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %v has an invalid version: %w", name, err)
+		}
+
+		data, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    base,
+			SQL:     string(data),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %v", migrations[i].Version)
+		}
+	}
+
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations for database engine %q", engine)
+	}
+
+	return migrations, nil
+}
+
+// Status reports every known migration and whether it has been applied.
+func Status(ctx context.Context, engine Engine, conn *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations(engine)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		statuses = append(statuses, MigrationStatus{
+			Migration: m,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Migrate applies every pending migration in order, each in its own
+// transaction, and returns the migrations that were applied.
+func Migrate(ctx context.Context, engine Engine, conn *sql.DB) ([]Migration, error) {
+	statuses, err := Status(ctx, engine, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []Migration{}
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+
+		if err := apply(ctx, engine, conn, status.Migration); err != nil {
+			return applied, fmt.Errorf("error applying migration %v: %w", status.Name, err)
+		}
+		applied = append(applied, status.Migration)
+	}
+	return applied, nil
+}
+
+func apply(ctx context.Context, engine Engine, conn *sql.DB, m Migration) error {
+	insert := "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"
+	if engine == Postgres {
+		insert = "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)"
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, insert, m.Version, m.Name, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS channels (
+  channel_id text NOT NULL PRIMARY KEY,
+  autoreply_enabled boolean NOT NULL DEFAULT false,
+  autoreply_frequency double precision NOT NULL DEFAULT 2,
+  reply_safety bigint NOT NULL DEFAULT 2,
+  openai_token text
+);
+
+CREATE TABLE IF NOT EXISTS approvals (
+  channel_id text NOT NULL,
+  user_id text NOT NULL,
+  manual boolean NOT NULL,
+  UNIQUE (channel_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS commands (
+  channel_id text NOT NULL,
+  name text NOT NULL,
+  template text NOT NULL,
+  UNIQUE (channel_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS numbers (
+  channel_id text NOT NULL,
+  name text NOT NULL,
+  value bigint NOT NULL DEFAULT 0,
+  UNIQUE (channel_id, name)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE secrets (
+  name text NOT NULL PRIMARY KEY,
+  key_id text NOT NULL,
+  value bytea NOT NULL
+);
@@ -0,0 +1,9 @@
+CREATE TABLE authorizations (
+  state text NOT NULL PRIMARY KEY,
+  flow text NOT NULL,
+  device_code text NOT NULL DEFAULT '',
+  user_code text NOT NULL DEFAULT '',
+  verification_uri text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL,
+  expires_at timestamptz NOT NULL
+);
@@ -0,0 +1,5 @@
+ALTER TABLE commands ADD COLUMN description text NOT NULL DEFAULT '';
+
+ALTER TABLE commands ADD COLUMN updated_by text NOT NULL DEFAULT '';
+
+ALTER TABLE commands ADD COLUMN updated_at timestamptz;
@@ -0,0 +1,10 @@
+CREATE TABLE dashboard_grants (
+  channel_id text NOT NULL,
+  user_id text NOT NULL,
+  role text NOT NULL,
+  granted_by text NOT NULL,
+  created_at timestamptz NOT NULL,
+  PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE INDEX dashboard_grants_user ON dashboard_grants (user_id);
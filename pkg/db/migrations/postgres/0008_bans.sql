@@ -0,0 +1,12 @@
+CREATE TABLE bans (
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  channel_id text NOT NULL,
+  user_id text NOT NULL,
+  user_login text NOT NULL,
+  duration bigint NOT NULL,
+  reason text NOT NULL,
+  created_at timestamptz NOT NULL,
+  lifted_at timestamptz
+);
+
+CREATE INDEX bans_channel ON bans (channel_id, created_at);
@@ -0,0 +1,23 @@
+CREATE TABLE webhooks (
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  channel_id text NOT NULL,
+  url text NOT NULL,
+  events text NOT NULL,
+  created_by text NOT NULL,
+  created_at timestamptz NOT NULL
+);
+
+CREATE INDEX webhooks_channel ON webhooks (channel_id);
+
+CREATE TABLE webhook_deliveries (
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  webhook_id bigint NOT NULL,
+  event text NOT NULL,
+  payload text NOT NULL,
+  attempt bigint NOT NULL,
+  status_code bigint NOT NULL,
+  error text NOT NULL,
+  created_at timestamptz NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
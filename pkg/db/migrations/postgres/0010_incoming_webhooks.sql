@@ -0,0 +1,10 @@
+CREATE TABLE incoming_webhooks (
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  channel_id text NOT NULL,
+  command text NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  created_by text NOT NULL,
+  created_at timestamptz NOT NULL
+);
+
+CREATE INDEX incoming_webhooks_channel ON incoming_webhooks (channel_id);
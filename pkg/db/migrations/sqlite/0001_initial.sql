@@ -1,4 +1,4 @@
-CREATE TABLE channels (
+CREATE TABLE IF NOT EXISTS channels (
   channel_id text NOT NULL PRIMARY KEY,
   autoreply_enabled boolean NOT NULL DEFAULT false,
   autoreply_frequency float NOT NULL DEFAULT 2,
@@ -6,23 +6,23 @@ CREATE TABLE channels (
   openai_token text
 );
 
-CREATE TABLE approvals (
+CREATE TABLE IF NOT EXISTS approvals (
   channel_id text NOT NULL,
   user_id text NOT NULL,
   manual boolean NOT NULL,
   UNIQUE (channel_id, user_id)
 );
 
-CREATE TABLE commands (
+CREATE TABLE IF NOT EXISTS commands (
   channel_id text NOT NULL,
   name text NOT NULL,
   template text NOT NULL,
   UNIQUE (channel_id, name)
 );
 
-CREATE TABLE numbers (
+CREATE TABLE IF NOT EXISTS numbers (
   channel_id text NOT NULL,
   name text NOT NULL,
   value int NOT NULL DEFAULT 0,
   UNIQUE (channel_id, name)
-);
\ No newline at end of file
+);
@@ -0,0 +1,17 @@
+ALTER TABLE channels ADD COLUMN chatlog_enabled boolean NOT NULL DEFAULT false;
+
+ALTER TABLE channels ADD COLUMN chatlog_retention_days int NOT NULL DEFAULT 7;
+
+CREATE TABLE messages (
+  id integer NOT NULL PRIMARY KEY,
+  message_id text NOT NULL,
+  channel_id text NOT NULL,
+  user_id text NOT NULL,
+  user_login text NOT NULL,
+  user_display_name text NOT NULL,
+  message text NOT NULL,
+  tags text NOT NULL,
+  created_at datetime NOT NULL
+);
+
+CREATE INDEX messages_channel_user ON messages (channel_id, user_login, created_at);
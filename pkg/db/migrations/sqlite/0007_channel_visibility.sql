@@ -0,0 +1,3 @@
+ALTER TABLE channels ADD COLUMN commands_public boolean NOT NULL DEFAULT true;
+
+ALTER TABLE channels ADD COLUMN listed boolean NOT NULL DEFAULT false;
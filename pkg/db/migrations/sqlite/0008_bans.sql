@@ -0,0 +1,12 @@
+CREATE TABLE bans (
+  id integer NOT NULL PRIMARY KEY,
+  channel_id text NOT NULL,
+  user_id text NOT NULL,
+  user_login text NOT NULL,
+  duration int NOT NULL,
+  reason text NOT NULL,
+  created_at datetime NOT NULL,
+  lifted_at datetime
+);
+
+CREATE INDEX bans_channel ON bans (channel_id, created_at);
@@ -0,0 +1,23 @@
+CREATE TABLE webhooks (
+  id integer NOT NULL PRIMARY KEY,
+  channel_id text NOT NULL,
+  url text NOT NULL,
+  events text NOT NULL,
+  created_by text NOT NULL,
+  created_at datetime NOT NULL
+);
+
+CREATE INDEX webhooks_channel ON webhooks (channel_id);
+
+CREATE TABLE webhook_deliveries (
+  id integer NOT NULL PRIMARY KEY,
+  webhook_id integer NOT NULL,
+  event text NOT NULL,
+  payload text NOT NULL,
+  attempt int NOT NULL,
+  status_code int NOT NULL,
+  error text NOT NULL,
+  created_at datetime NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
@@ -0,0 +1,12 @@
+CREATE TABLE audit_log (
+  id integer NOT NULL PRIMARY KEY,
+  channel_id text NOT NULL,
+  actor_id text NOT NULL,
+  actor_login text NOT NULL,
+  action text NOT NULL,
+  before text,
+  after text,
+  created_at datetime NOT NULL
+);
+
+CREATE INDEX audit_log_channel ON audit_log (channel_id, id);
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/meutraa/meutraabot/pkg/db/postgres"
+	sqlite3 "modernc.org/sqlite"
+
+	_ "github.com/lib/pq"
+)
+
+// Engine is a supported database backend.
+type Engine string
+
+const (
+	SQLite   Engine = "sqlite"
+	Postgres Engine = "postgres"
+)
+
+var registerRegexp sync.Once
+
+// Open connects to the database for the given engine and checks that it is
+// reachable. It does not migrate the schema or prepare any queries.
+func Open(ctx context.Context, engine Engine, dsn string) (*sql.DB, error) {
+	switch engine {
+	case SQLite:
+		// sqlite has no built in implementation of REGEXP
+		registerRegexp.Do(func() {
+			sqlite3.MustRegisterDeterministicScalarFunction("regexp", 2,
+				func(ctx *sqlite3.FunctionContext, args []driver.Value) (driver.Value, error) {
+					return regexp.MatchString(args[0].(string), args[1].(string))
+				},
+			)
+		})
+	case Postgres:
+	default:
+		return nil, fmt.Errorf("unsupported database engine %q", engine)
+	}
+
+	conn, err := sql.Open(string(engine), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	if engine == SQLite {
+		// sqlite allows a single writer, so concurrent writes on separate
+		// connections fail with SQLITE_BUSY instead of waiting
+		conn.SetMaxOpenConns(1)
+	}
+
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error pinging database: %w", err)
+	}
+	return conn, nil
+}
+
+// PrepareQuerier prepares every query for the given engine.
+func PrepareQuerier(ctx context.Context, engine Engine, conn *sql.DB) (Querier, error) {
+	switch engine {
+	case SQLite:
+		return Prepare(ctx, conn)
+	case Postgres:
+		q, err := postgres.Prepare(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		return &postgresQueries{q: q}, nil
+	}
+	return nil, fmt.Errorf("unsupported database engine %q", engine)
+}
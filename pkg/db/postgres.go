@@ -0,0 +1,303 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/meutraa/meutraabot/pkg/db/postgres"
+	"github.com/samber/lo"
+)
+
+// postgresQueries adapts the queries generated for postgres to Querier.
+// The postgres schema uses column types that generate identical Go structs,
+// so every conversion is a plain type conversion.
+type postgresQueries struct {
+	q *postgres.Queries
+}
+
+var _ Querier = (*postgresQueries)(nil)
+
+func convert[T, U any](items []T, f func(T) U) []U {
+	return lo.Map(items, func(item T, _ int) U { return f(item) })
+}
+
+func (p *postgresQueries) AddToNumber(ctx context.Context, arg AddToNumberParams) error {
+	return p.q.AddToNumber(ctx, postgres.AddToNumberParams(arg))
+}
+
+func (p *postgresQueries) Approve(ctx context.Context, arg ApproveParams) error {
+	return p.q.Approve(ctx, postgres.ApproveParams(arg))
+}
+
+func (p *postgresQueries) CreateChannel(ctx context.Context, channelID string) error {
+	return p.q.CreateChannel(ctx, channelID)
+}
+
+func (p *postgresQueries) CreateAuthorization(ctx context.Context, arg CreateAuthorizationParams) error {
+	return p.q.CreateAuthorization(ctx, postgres.CreateAuthorizationParams(arg))
+}
+
+func (p *postgresQueries) CreateIncomingWebhook(ctx context.Context, arg CreateIncomingWebhookParams) (IncomingWebhook, error) {
+	row, err := p.q.CreateIncomingWebhook(ctx, postgres.CreateIncomingWebhookParams(arg))
+	return IncomingWebhook(row), err
+}
+
+func (p *postgresQueries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row, err := p.q.CreateWebhook(ctx, postgres.CreateWebhookParams(arg))
+	return Webhook(row), err
+}
+
+func (p *postgresQueries) DeleteAuthorization(ctx context.Context, state string) error {
+	return p.q.DeleteAuthorization(ctx, state)
+}
+
+func (p *postgresQueries) DeleteChannel(ctx context.Context, channelID string) error {
+	return p.q.DeleteChannel(ctx, channelID)
+}
+
+func (p *postgresQueries) DeleteCommand(ctx context.Context, arg DeleteCommandParams) error {
+	return p.q.DeleteCommand(ctx, postgres.DeleteCommandParams(arg))
+}
+
+func (p *postgresQueries) DeleteDashboardGrant(ctx context.Context, arg DeleteDashboardGrantParams) error {
+	return p.q.DeleteDashboardGrant(ctx, postgres.DeleteDashboardGrantParams(arg))
+}
+
+func (p *postgresQueries) DeleteExpiredAuthorizations(ctx context.Context, expiresAt time.Time) error {
+	return p.q.DeleteExpiredAuthorizations(ctx, expiresAt)
+}
+
+func (p *postgresQueries) DeleteIncomingWebhook(ctx context.Context, arg DeleteIncomingWebhookParams) error {
+	return p.q.DeleteIncomingWebhook(ctx, postgres.DeleteIncomingWebhookParams(arg))
+}
+
+func (p *postgresQueries) DeleteMessagesBefore(ctx context.Context, arg DeleteMessagesBeforeParams) error {
+	return p.q.DeleteMessagesBefore(ctx, postgres.DeleteMessagesBeforeParams(arg))
+}
+
+func (p *postgresQueries) DeleteNumber(ctx context.Context, arg DeleteNumberParams) error {
+	return p.q.DeleteNumber(ctx, postgres.DeleteNumberParams(arg))
+}
+
+func (p *postgresQueries) DeleteSecret(ctx context.Context, name string) error {
+	return p.q.DeleteSecret(ctx, name)
+}
+
+func (p *postgresQueries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) error {
+	return p.q.DeleteWebhook(ctx, postgres.DeleteWebhookParams(arg))
+}
+
+func (p *postgresQueries) DeleteWebhookDeliveries(ctx context.Context, webhookID int64) error {
+	return p.q.DeleteWebhookDeliveries(ctx, webhookID)
+}
+
+func (p *postgresQueries) DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt time.Time) error {
+	return p.q.DeleteWebhookDeliveriesBefore(ctx, createdAt)
+}
+
+func (p *postgresQueries) GetApprovals(ctx context.Context, channelID string) ([]Approval, error) {
+	rows, err := p.q.GetApprovals(ctx, channelID)
+	return convert(rows, func(r postgres.Approval) Approval { return Approval(r) }), err
+}
+
+func (p *postgresQueries) GetApprovalsByType(ctx context.Context, arg GetApprovalsByTypeParams) ([]Approval, error) {
+	rows, err := p.q.GetApprovalsByType(ctx, postgres.GetApprovalsByTypeParams(arg))
+	return convert(rows, func(r postgres.Approval) Approval { return Approval(r) }), err
+}
+
+func (p *postgresQueries) GetApprovalsByTypeBefore(ctx context.Context, arg GetApprovalsByTypeBeforeParams) ([]Approval, error) {
+	rows, err := p.q.GetApprovalsByTypeBefore(ctx, postgres.GetApprovalsByTypeBeforeParams(arg))
+	return convert(rows, func(r postgres.Approval) Approval { return Approval(r) }), err
+}
+
+func (p *postgresQueries) GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error) {
+	rows, err := p.q.GetAuditLog(ctx, postgres.GetAuditLogParams(arg))
+	return convert(rows, func(r postgres.AuditLog) AuditLog { return AuditLog(r) }), err
+}
+
+func (p *postgresQueries) GetAuthorization(ctx context.Context, state string) (Authorization, error) {
+	row, err := p.q.GetAuthorization(ctx, state)
+	return Authorization(row), err
+}
+
+func (p *postgresQueries) GetBans(ctx context.Context, arg GetBansParams) ([]Ban, error) {
+	rows, err := p.q.GetBans(ctx, postgres.GetBansParams(arg))
+	return convert(rows, func(r postgres.Ban) Ban { return Ban(r) }), err
+}
+
+func (p *postgresQueries) GetChannel(ctx context.Context, channelID string) (Channel, error) {
+	row, err := p.q.GetChannel(ctx, channelID)
+	return Channel(row), err
+}
+
+func (p *postgresQueries) GetChannelTokens(ctx context.Context) ([]GetChannelTokensRow, error) {
+	rows, err := p.q.GetChannelTokens(ctx)
+	return convert(rows, func(r postgres.GetChannelTokensRow) GetChannelTokensRow { return GetChannelTokensRow(r) }), err
+}
+
+func (p *postgresQueries) GetChannels(ctx context.Context) ([]string, error) {
+	return p.q.GetChannels(ctx)
+}
+
+func (p *postgresQueries) GetCommand(ctx context.Context, arg GetCommandParams) (string, error) {
+	return p.q.GetCommand(ctx, postgres.GetCommandParams(arg))
+}
+
+func (p *postgresQueries) GetCommandDetails(ctx context.Context, arg GetCommandDetailsParams) (Command, error) {
+	row, err := p.q.GetCommandDetails(ctx, postgres.GetCommandDetailsParams(arg))
+	return Command(row), err
+}
+
+func (p *postgresQueries) GetCommands(ctx context.Context, channelID string) ([]string, error) {
+	return p.q.GetCommands(ctx, channelID)
+}
+
+func (p *postgresQueries) GetCommandsByID(ctx context.Context, channelID string) ([]GetCommandsByIDRow, error) {
+	rows, err := p.q.GetCommandsByID(ctx, channelID)
+	return convert(rows, func(r postgres.GetCommandsByIDRow) GetCommandsByIDRow { return GetCommandsByIDRow(r) }), err
+}
+
+func (p *postgresQueries) GetDashboardGrant(ctx context.Context, arg GetDashboardGrantParams) (DashboardGrant, error) {
+	row, err := p.q.GetDashboardGrant(ctx, postgres.GetDashboardGrantParams(arg))
+	return DashboardGrant(row), err
+}
+
+func (p *postgresQueries) GetDashboardGrants(ctx context.Context, channelID string) ([]DashboardGrant, error) {
+	rows, err := p.q.GetDashboardGrants(ctx, channelID)
+	return convert(rows, func(r postgres.DashboardGrant) DashboardGrant { return DashboardGrant(r) }), err
+}
+
+func (p *postgresQueries) GetDashboardGrantsForUser(ctx context.Context, userID string) ([]DashboardGrant, error) {
+	rows, err := p.q.GetDashboardGrantsForUser(ctx, userID)
+	return convert(rows, func(r postgres.DashboardGrant) DashboardGrant { return DashboardGrant(r) }), err
+}
+
+func (p *postgresQueries) GetIncomingWebhook(ctx context.Context, arg GetIncomingWebhookParams) (IncomingWebhook, error) {
+	row, err := p.q.GetIncomingWebhook(ctx, postgres.GetIncomingWebhookParams(arg))
+	return IncomingWebhook(row), err
+}
+
+func (p *postgresQueries) GetIncomingWebhookByToken(ctx context.Context, tokenHash string) (IncomingWebhook, error) {
+	row, err := p.q.GetIncomingWebhookByToken(ctx, tokenHash)
+	return IncomingWebhook(row), err
+}
+
+func (p *postgresQueries) GetIncomingWebhooks(ctx context.Context, channelID string) ([]IncomingWebhook, error) {
+	rows, err := p.q.GetIncomingWebhooks(ctx, channelID)
+	return convert(rows, func(r postgres.IncomingWebhook) IncomingWebhook { return IncomingWebhook(r) }), err
+}
+
+func (p *postgresQueries) GetListedChannels(ctx context.Context) ([]string, error) {
+	return p.q.GetListedChannels(ctx)
+}
+
+func (p *postgresQueries) GetMatchingCommands(ctx context.Context, arg GetMatchingCommandsParams) ([]GetMatchingCommandsRow, error) {
+	rows, err := p.q.GetMatchingCommands(ctx, postgres.GetMatchingCommandsParams(arg))
+	return convert(rows, func(r postgres.GetMatchingCommandsRow) GetMatchingCommandsRow { return GetMatchingCommandsRow(r) }), err
+}
+
+func (p *postgresQueries) GetNumber(ctx context.Context, arg GetNumberParams) (Number, error) {
+	row, err := p.q.GetNumber(ctx, postgres.GetNumberParams(arg))
+	return Number(row), err
+}
+
+func (p *postgresQueries) GetNumbers(ctx context.Context, channelID string) ([]Number, error) {
+	rows, err := p.q.GetNumbers(ctx, channelID)
+	return convert(rows, func(r postgres.Number) Number { return Number(r) }), err
+}
+
+func (p *postgresQueries) GetPendingAuthorization(ctx context.Context, arg GetPendingAuthorizationParams) (Authorization, error) {
+	row, err := p.q.GetPendingAuthorization(ctx, postgres.GetPendingAuthorizationParams(arg))
+	return Authorization(row), err
+}
+
+func (p *postgresQueries) GetRecentMessages(ctx context.Context, arg GetRecentMessagesParams) ([]Message, error) {
+	rows, err := p.q.GetRecentMessages(ctx, postgres.GetRecentMessagesParams(arg))
+	return convert(rows, func(r postgres.Message) Message { return Message(r) }), err
+}
+
+func (p *postgresQueries) GetSecret(ctx context.Context, name string) (Secret, error) {
+	row, err := p.q.GetSecret(ctx, name)
+	return Secret(row), err
+}
+
+func (p *postgresQueries) GetSecrets(ctx context.Context) ([]Secret, error) {
+	rows, err := p.q.GetSecrets(ctx)
+	return convert(rows, func(r postgres.Secret) Secret { return Secret(r) }), err
+}
+
+func (p *postgresQueries) GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error) {
+	row, err := p.q.GetWebhook(ctx, postgres.GetWebhookParams(arg))
+	return Webhook(row), err
+}
+
+func (p *postgresQueries) GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := p.q.GetWebhookDeliveries(ctx, postgres.GetWebhookDeliveriesParams(arg))
+	return convert(rows, func(r postgres.WebhookDelivery) WebhookDelivery { return WebhookDelivery(r) }), err
+}
+
+func (p *postgresQueries) GetWebhooks(ctx context.Context, channelID string) ([]Webhook, error) {
+	rows, err := p.q.GetWebhooks(ctx, channelID)
+	return convert(rows, func(r postgres.Webhook) Webhook { return Webhook(r) }), err
+}
+
+func (p *postgresQueries) InsertMessage(ctx context.Context, arg InsertMessageParams) error {
+	return p.q.InsertMessage(ctx, postgres.InsertMessageParams(arg))
+}
+
+func (p *postgresQueries) IsApproved(ctx context.Context, arg IsApprovedParams) (int64, error) {
+	return p.q.IsApproved(ctx, postgres.IsApprovedParams(arg))
+}
+
+func (p *postgresQueries) LiftBans(ctx context.Context, arg LiftBansParams) error {
+	return p.q.LiftBans(ctx, postgres.LiftBansParams(arg))
+}
+
+func (p *postgresQueries) RecordAudit(ctx context.Context, arg RecordAuditParams) error {
+	return p.q.RecordAudit(ctx, postgres.RecordAuditParams(arg))
+}
+
+func (p *postgresQueries) RecordBan(ctx context.Context, arg RecordBanParams) error {
+	return p.q.RecordBan(ctx, postgres.RecordBanParams(arg))
+}
+
+func (p *postgresQueries) RecordWebhookDelivery(ctx context.Context, arg RecordWebhookDeliveryParams) error {
+	return p.q.RecordWebhookDelivery(ctx, postgres.RecordWebhookDeliveryParams(arg))
+}
+
+func (p *postgresQueries) SaveCommand(ctx context.Context, arg SaveCommandParams) error {
+	return p.q.SaveCommand(ctx, postgres.SaveCommandParams(arg))
+}
+
+func (p *postgresQueries) SearchUserMessages(ctx context.Context, arg SearchUserMessagesParams) ([]Message, error) {
+	rows, err := p.q.SearchUserMessages(ctx, postgres.SearchUserMessagesParams(arg))
+	return convert(rows, func(r postgres.Message) Message { return Message(r) }), err
+}
+
+func (p *postgresQueries) SetCommand(ctx context.Context, arg SetCommandParams) error {
+	return p.q.SetCommand(ctx, postgres.SetCommandParams(arg))
+}
+
+func (p *postgresQueries) SetDashboardGrant(ctx context.Context, arg SetDashboardGrantParams) error {
+	return p.q.SetDashboardGrant(ctx, postgres.SetDashboardGrantParams(arg))
+}
+
+func (p *postgresQueries) SetNumber(ctx context.Context, arg SetNumberParams) error {
+	return p.q.SetNumber(ctx, postgres.SetNumberParams(arg))
+}
+
+func (p *postgresQueries) SetSecret(ctx context.Context, arg SetSecretParams) error {
+	return p.q.SetSecret(ctx, postgres.SetSecretParams(arg))
+}
+
+func (p *postgresQueries) Unapprove(ctx context.Context, arg UnapproveParams) error {
+	return p.q.Unapprove(ctx, postgres.UnapproveParams(arg))
+}
+
+func (p *postgresQueries) UpdateChannel(ctx context.Context, arg UpdateChannelParams) error {
+	return p.q.UpdateChannel(ctx, postgres.UpdateChannelParams(arg))
+}
+
+func (p *postgresQueries) UpdateChannelToken(ctx context.Context, arg UpdateChannelTokenParams) error {
+	return p.q.UpdateChannelToken(ctx, postgres.UpdateChannelTokenParams(arg))
+}
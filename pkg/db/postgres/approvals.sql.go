@@ -0,0 +1,199 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: approvals.sql
+
+package postgres
+
+import (
+	"context"
+)
+
+const approve = `-- name: Approve :exec
+INSERT INTO
+  approvals (channel_id, user_id, manual)
+VALUES
+  ($1, $2, $3) ON CONFLICT (channel_id, user_id) DO UPDATE
+  SET manual = approvals.manual OR excluded.manual
+`
+
+type ApproveParams struct {
+	ChannelID string
+	UserID    string
+	Manual    bool
+}
+
+func (q *Queries) Approve(ctx context.Context, arg ApproveParams) error {
+	_, err := q.exec(ctx, q.approveStmt, approve, arg.ChannelID, arg.UserID, arg.Manual)
+	return err
+}
+
+const getApprovals = `-- name: GetApprovals :many
+SELECT
+  channel_id, user_id, manual
+FROM
+  approvals
+WHERE
+  channel_id = $1
+  AND manual = true
+ORDER BY user_id DESC
+`
+
+func (q *Queries) GetApprovals(ctx context.Context, channelID string) ([]Approval, error) {
+	rows, err := q.query(ctx, q.getApprovalsStmt, getApprovals, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Approval
+	for rows.Next() {
+		var i Approval
+		if err := rows.Scan(&i.ChannelID, &i.UserID, &i.Manual); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getApprovalsByType = `-- name: GetApprovalsByType :many
+SELECT
+  channel_id, user_id, manual
+FROM
+  approvals
+WHERE
+  channel_id = $1
+  AND manual = $2
+  AND user_id > $3
+ORDER BY user_id ASC
+LIMIT $4::bigint
+`
+
+type GetApprovalsByTypeParams struct {
+	ChannelID string
+	Manual    bool
+	UserID    string
+	Limit     int64
+}
+
+func (q *Queries) GetApprovalsByType(ctx context.Context, arg GetApprovalsByTypeParams) ([]Approval, error) {
+	rows, err := q.query(ctx, q.getApprovalsByTypeStmt, getApprovalsByType,
+		arg.ChannelID,
+		arg.Manual,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Approval
+	for rows.Next() {
+		var i Approval
+		if err := rows.Scan(&i.ChannelID, &i.UserID, &i.Manual); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getApprovalsByTypeBefore = `-- name: GetApprovalsByTypeBefore :many
+SELECT
+  channel_id, user_id, manual
+FROM
+  approvals
+WHERE
+  channel_id = $1
+  AND manual = $2
+  AND user_id < $3
+ORDER BY user_id DESC
+LIMIT $4::bigint
+`
+
+type GetApprovalsByTypeBeforeParams struct {
+	ChannelID string
+	Manual    bool
+	UserID    string
+	Limit     int64
+}
+
+func (q *Queries) GetApprovalsByTypeBefore(ctx context.Context, arg GetApprovalsByTypeBeforeParams) ([]Approval, error) {
+	rows, err := q.query(ctx, q.getApprovalsByTypeBeforeStmt, getApprovalsByTypeBefore,
+		arg.ChannelID,
+		arg.Manual,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Approval
+	for rows.Next() {
+		var i Approval
+		if err := rows.Scan(&i.ChannelID, &i.UserID, &i.Manual); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isApproved = `-- name: IsApproved :one
+SELECT
+  COUNT(*)
+FROM
+  approvals
+WHERE
+  channel_id = $1
+  AND user_id = $2
+`
+
+type IsApprovedParams struct {
+	ChannelID string
+	UserID    string
+}
+
+func (q *Queries) IsApproved(ctx context.Context, arg IsApprovedParams) (int64, error) {
+	row := q.queryRow(ctx, q.isApprovedStmt, isApproved, arg.ChannelID, arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const unapprove = `-- name: Unapprove :exec
+DELETE FROM
+  approvals
+WHERE
+  channel_id = $1
+  AND user_id = $2
+`
+
+type UnapproveParams struct {
+	ChannelID string
+	UserID    string
+}
+
+func (q *Queries) Unapprove(ctx context.Context, arg UnapproveParams) error {
+	_, err := q.exec(ctx, q.unapproveStmt, unapprove, arg.ChannelID, arg.UserID)
+	return err
+}
@@ -0,0 +1,102 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: bans.sql
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const getBans = `-- name: GetBans :many
+SELECT id, channel_id, user_id, user_login, duration, reason, created_at, lifted_at
+  FROM bans
+  WHERE channel_id = $1
+  ORDER BY created_at DESC
+  LIMIT $2::bigint
+`
+
+type GetBansParams struct {
+	ChannelID string
+	Limit     int64
+}
+
+func (q *Queries) GetBans(ctx context.Context, arg GetBansParams) ([]Ban, error) {
+	rows, err := q.query(ctx, q.getBansStmt, getBans, arg.ChannelID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Ban
+	for rows.Next() {
+		var i Ban
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.UserID,
+			&i.UserLogin,
+			&i.Duration,
+			&i.Reason,
+			&i.CreatedAt,
+			&i.LiftedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const liftBans = `-- name: LiftBans :exec
+UPDATE bans
+  SET lifted_at = $1
+  WHERE channel_id = $2
+  AND user_id = $3
+  AND lifted_at IS NULL
+`
+
+type LiftBansParams struct {
+	LiftedAt  sql.NullTime
+	ChannelID string
+	UserID    string
+}
+
+func (q *Queries) LiftBans(ctx context.Context, arg LiftBansParams) error {
+	_, err := q.exec(ctx, q.liftBansStmt, liftBans, arg.LiftedAt, arg.ChannelID, arg.UserID)
+	return err
+}
+
+const recordBan = `-- name: RecordBan :exec
+INSERT INTO bans (channel_id, user_id, user_login, duration, reason, created_at)
+  VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type RecordBanParams struct {
+	ChannelID string
+	UserID    string
+	UserLogin string
+	Duration  int64
+	Reason    string
+	CreatedAt time.Time
+}
+
+func (q *Queries) RecordBan(ctx context.Context, arg RecordBanParams) error {
+	_, err := q.exec(ctx, q.recordBanStmt, recordBan,
+		arg.ChannelID,
+		arg.UserID,
+		arg.UserLogin,
+		arg.Duration,
+		arg.Reason,
+		arg.CreatedAt,
+	)
+	return err
+}
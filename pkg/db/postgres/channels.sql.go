@@ -0,0 +1,194 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: channels.sql
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createChannel = `-- name: CreateChannel :exec
+INSERT INTO channels (channel_id)
+  VALUES ($1)
+  ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) CreateChannel(ctx context.Context, channelID string) error {
+	_, err := q.exec(ctx, q.createChannelStmt, createChannel, channelID)
+	return err
+}
+
+const deleteChannel = `-- name: DeleteChannel :exec
+DELETE FROM channels
+  WHERE channel_id = $1
+`
+
+func (q *Queries) DeleteChannel(ctx context.Context, channelID string) error {
+	_, err := q.exec(ctx, q.deleteChannelStmt, deleteChannel, channelID)
+	return err
+}
+
+const getChannel = `-- name: GetChannel :one
+SELECT channel_id, autoreply_enabled, autoreply_frequency, reply_safety, openai_token, chatlog_enabled, chatlog_retention_days, commands_public, listed FROM channels WHERE channel_id = $1
+`
+
+func (q *Queries) GetChannel(ctx context.Context, channelID string) (Channel, error) {
+	row := q.queryRow(ctx, q.getChannelStmt, getChannel, channelID)
+	var i Channel
+	err := row.Scan(
+		&i.ChannelID,
+		&i.AutoreplyEnabled,
+		&i.AutoreplyFrequency,
+		&i.ReplySafety,
+		&i.OpenaiToken,
+		&i.ChatlogEnabled,
+		&i.ChatlogRetentionDays,
+		&i.CommandsPublic,
+		&i.Listed,
+	)
+	return i, err
+}
+
+const getChannelTokens = `-- name: GetChannelTokens :many
+SELECT channel_id, openai_token
+  FROM channels
+  WHERE openai_token IS NOT NULL
+`
+
+type GetChannelTokensRow struct {
+	ChannelID   string
+	OpenaiToken sql.NullString
+}
+
+func (q *Queries) GetChannelTokens(ctx context.Context) ([]GetChannelTokensRow, error) {
+	rows, err := q.query(ctx, q.getChannelTokensStmt, getChannelTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChannelTokensRow
+	for rows.Next() {
+		var i GetChannelTokensRow
+		if err := rows.Scan(&i.ChannelID, &i.OpenaiToken); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChannels = `-- name: GetChannels :many
+SELECT channel_id FROM channels
+`
+
+func (q *Queries) GetChannels(ctx context.Context) ([]string, error) {
+	rows, err := q.query(ctx, q.getChannelsStmt, getChannels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var channel_id string
+		if err := rows.Scan(&channel_id); err != nil {
+			return nil, err
+		}
+		items = append(items, channel_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getListedChannels = `-- name: GetListedChannels :many
+SELECT channel_id FROM channels WHERE listed = true
+`
+
+func (q *Queries) GetListedChannels(ctx context.Context) ([]string, error) {
+	rows, err := q.query(ctx, q.getListedChannelsStmt, getListedChannels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var channel_id string
+		if err := rows.Scan(&channel_id); err != nil {
+			return nil, err
+		}
+		items = append(items, channel_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChannel = `-- name: UpdateChannel :exec
+UPDATE channels
+ SET autoreply_enabled = $1,
+  autoreply_frequency = $2,
+  reply_safety = $3,
+  chatlog_enabled = $4,
+  chatlog_retention_days = $5,
+  commands_public = $6,
+  listed = $7
+ WHERE channel_id = $8
+`
+
+type UpdateChannelParams struct {
+	AutoreplyEnabled     bool
+	AutoreplyFrequency   float64
+	ReplySafety          int64
+	ChatlogEnabled       bool
+	ChatlogRetentionDays int64
+	CommandsPublic       bool
+	Listed               bool
+	ChannelID            string
+}
+
+func (q *Queries) UpdateChannel(ctx context.Context, arg UpdateChannelParams) error {
+	_, err := q.exec(ctx, q.updateChannelStmt, updateChannel,
+		arg.AutoreplyEnabled,
+		arg.AutoreplyFrequency,
+		arg.ReplySafety,
+		arg.ChatlogEnabled,
+		arg.ChatlogRetentionDays,
+		arg.CommandsPublic,
+		arg.Listed,
+		arg.ChannelID,
+	)
+	return err
+}
+
+const updateChannelToken = `-- name: UpdateChannelToken :exec
+UPDATE channels
+ SET openai_token = $1
+ WHERE channel_id = $2
+`
+
+type UpdateChannelTokenParams struct {
+	OpenaiToken sql.NullString
+	ChannelID   string
+}
+
+func (q *Queries) UpdateChannelToken(ctx context.Context, arg UpdateChannelTokenParams) error {
+	_, err := q.exec(ctx, q.updateChannelTokenStmt, updateChannelToken, arg.OpenaiToken, arg.ChannelID)
+	return err
+}
@@ -0,0 +1,253 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: commands.sql
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+const deleteCommand = `-- name: DeleteCommand :exec
+DELETE FROM commands
+  WHERE channel_id = $1
+  AND name = $2
+`
+
+type DeleteCommandParams struct {
+	ChannelID string
+	Name      string
+}
+
+func (q *Queries) DeleteCommand(ctx context.Context, arg DeleteCommandParams) error {
+	_, err := q.exec(ctx, q.deleteCommandStmt, deleteCommand, arg.ChannelID, arg.Name)
+	return err
+}
+
+const getCommand = `-- name: GetCommand :one
+SELECT template
+  FROM commands
+  WHERE name = $1
+  AND channel_id = $2
+`
+
+type GetCommandParams struct {
+	Name      string
+	ChannelID string
+}
+
+func (q *Queries) GetCommand(ctx context.Context, arg GetCommandParams) (string, error) {
+	row := q.queryRow(ctx, q.getCommandStmt, getCommand, arg.Name, arg.ChannelID)
+	var template string
+	err := row.Scan(&template)
+	return template, err
+}
+
+const getCommandDetails = `-- name: GetCommandDetails :one
+SELECT channel_id, name, template, description, updated_by, updated_at
+  FROM commands
+  WHERE channel_id = $1
+  AND name = $2
+`
+
+type GetCommandDetailsParams struct {
+	ChannelID string
+	Name      string
+}
+
+func (q *Queries) GetCommandDetails(ctx context.Context, arg GetCommandDetailsParams) (Command, error) {
+	row := q.queryRow(ctx, q.getCommandDetailsStmt, getCommandDetails, arg.ChannelID, arg.Name)
+	var i Command
+	err := row.Scan(
+		&i.ChannelID,
+		&i.Name,
+		&i.Template,
+		&i.Description,
+		&i.UpdatedBy,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getCommands = `-- name: GetCommands :many
+SELECT name
+  FROM commands
+  WHERE channel_id = $1
+  ORDER BY name ASC
+`
+
+func (q *Queries) GetCommands(ctx context.Context, channelID string) ([]string, error) {
+	rows, err := q.query(ctx, q.getCommandsStmt, getCommands, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommandsByID = `-- name: GetCommandsByID :many
+SELECT name, template, description, updated_by, updated_at
+  FROM commands
+  WHERE channel_id = $1
+  ORDER BY name ASC
+`
+
+type GetCommandsByIDRow struct {
+	Name        string
+	Template    string
+	Description string
+	UpdatedBy   string
+	UpdatedAt   sql.NullTime
+}
+
+func (q *Queries) GetCommandsByID(ctx context.Context, channelID string) ([]GetCommandsByIDRow, error) {
+	rows, err := q.query(ctx, q.getCommandsByIDStmt, getCommandsByID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommandsByIDRow
+	for rows.Next() {
+		var i GetCommandsByIDRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Template,
+			&i.Description,
+			&i.UpdatedBy,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchingCommands = `-- name: GetMatchingCommands :many
+SELECT
+    template,
+    name,
+    channel_id
+  FROM commands
+  WHERE (
+    channel_id = $1
+    OR
+    channel_id = '0'
+  )
+  AND $2::text ~ name
+`
+
+type GetMatchingCommandsParams struct {
+	ChannelID string
+	Message   string
+}
+
+type GetMatchingCommandsRow struct {
+	Template  string
+	Name      string
+	ChannelID string
+}
+
+func (q *Queries) GetMatchingCommands(ctx context.Context, arg GetMatchingCommandsParams) ([]GetMatchingCommandsRow, error) {
+	rows, err := q.query(ctx, q.getMatchingCommandsStmt, getMatchingCommands, arg.ChannelID, arg.Message)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMatchingCommandsRow
+	for rows.Next() {
+		var i GetMatchingCommandsRow
+		if err := rows.Scan(&i.Template, &i.Name, &i.ChannelID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const saveCommand = `-- name: SaveCommand :exec
+INSERT INTO commands (channel_id, name, template, description, updated_by, updated_at)
+  VALUES ($1, $2, $3, $4, $5, $6)
+  ON CONFLICT(channel_id, name) DO UPDATE
+  SET template = excluded.template,
+    description = excluded.description,
+    updated_by = excluded.updated_by,
+    updated_at = excluded.updated_at
+`
+
+type SaveCommandParams struct {
+	ChannelID   string
+	Name        string
+	Template    string
+	Description string
+	UpdatedBy   string
+	UpdatedAt   sql.NullTime
+}
+
+func (q *Queries) SaveCommand(ctx context.Context, arg SaveCommandParams) error {
+	_, err := q.exec(ctx, q.saveCommandStmt, saveCommand,
+		arg.ChannelID,
+		arg.Name,
+		arg.Template,
+		arg.Description,
+		arg.UpdatedBy,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const setCommand = `-- name: SetCommand :exec
+INSERT INTO commands (channel_id, name, template, updated_by, updated_at)
+  VALUES ($1, $2, $3, $4, $5)
+  ON CONFLICT(channel_id, name) DO UPDATE
+  SET template = excluded.template,
+    updated_by = excluded.updated_by,
+    updated_at = excluded.updated_at
+`
+
+type SetCommandParams struct {
+	ChannelID string
+	Name      string
+	Template  string
+	UpdatedBy string
+	UpdatedAt sql.NullTime
+}
+
+func (q *Queries) SetCommand(ctx context.Context, arg SetCommandParams) error {
+	_, err := q.exec(ctx, q.setCommandStmt, setCommand,
+		arg.ChannelID,
+		arg.Name,
+		arg.Template,
+		arg.UpdatedBy,
+		arg.UpdatedAt,
+	)
+	return err
+}
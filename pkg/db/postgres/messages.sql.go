@@ -0,0 +1,158 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: messages.sql
+
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+const deleteMessagesBefore = `-- name: DeleteMessagesBefore :exec
+DELETE FROM messages
+  WHERE channel_id = $1
+  AND created_at < $2
+`
+
+type DeleteMessagesBeforeParams struct {
+	ChannelID string
+	CreatedAt time.Time
+}
+
+func (q *Queries) DeleteMessagesBefore(ctx context.Context, arg DeleteMessagesBeforeParams) error {
+	_, err := q.exec(ctx, q.deleteMessagesBeforeStmt, deleteMessagesBefore, arg.ChannelID, arg.CreatedAt)
+	return err
+}
+
+const getRecentMessages = `-- name: GetRecentMessages :many
+SELECT id, message_id, channel_id, user_id, user_login, user_display_name, message, tags, created_at
+  FROM messages
+  WHERE channel_id = $1
+  ORDER BY created_at DESC
+  LIMIT $2::bigint
+`
+
+type GetRecentMessagesParams struct {
+	ChannelID string
+	Limit     int64
+}
+
+func (q *Queries) GetRecentMessages(ctx context.Context, arg GetRecentMessagesParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.getRecentMessagesStmt, getRecentMessages, arg.ChannelID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.ChannelID,
+			&i.UserID,
+			&i.UserLogin,
+			&i.UserDisplayName,
+			&i.Message,
+			&i.Tags,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertMessage = `-- name: InsertMessage :exec
+INSERT INTO messages (message_id, channel_id, user_id, user_login, user_display_name, message, tags, created_at)
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type InsertMessageParams struct {
+	MessageID       string
+	ChannelID       string
+	UserID          string
+	UserLogin       string
+	UserDisplayName string
+	Message         string
+	Tags            string
+	CreatedAt       time.Time
+}
+
+func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) error {
+	_, err := q.exec(ctx, q.insertMessageStmt, insertMessage,
+		arg.MessageID,
+		arg.ChannelID,
+		arg.UserID,
+		arg.UserLogin,
+		arg.UserDisplayName,
+		arg.Message,
+		arg.Tags,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const searchUserMessages = `-- name: SearchUserMessages :many
+SELECT id, message_id, channel_id, user_id, user_login, user_display_name, message, tags, created_at
+  FROM messages
+  WHERE channel_id = $1
+  AND user_login = $2
+  AND message LIKE '%' || $3::text || '%' ESCAPE '\'
+  ORDER BY created_at DESC
+  LIMIT $4::bigint
+`
+
+type SearchUserMessagesParams struct {
+	ChannelID string
+	UserLogin string
+	Message   string
+	Limit     int64
+}
+
+func (q *Queries) SearchUserMessages(ctx context.Context, arg SearchUserMessagesParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.searchUserMessagesStmt, searchUserMessages,
+		arg.ChannelID,
+		arg.UserLogin,
+		arg.Message,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.ChannelID,
+			&i.UserID,
+			&i.UserLogin,
+			&i.UserDisplayName,
+			&i.Message,
+			&i.Tags,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
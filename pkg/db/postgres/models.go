@@ -0,0 +1,130 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+
+package postgres
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Approval struct {
+	ChannelID string
+	UserID    string
+	Manual    bool
+}
+
+type AuditLog struct {
+	ID         int64
+	ChannelID  string
+	ActorID    string
+	ActorLogin string
+	Action     string
+	Before     sql.NullString
+	After      sql.NullString
+	CreatedAt  time.Time
+}
+
+type Authorization struct {
+	State           string
+	Flow            string
+	DeviceCode      string
+	UserCode        string
+	VerificationUri string
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
+
+type Ban struct {
+	ID        int64
+	ChannelID string
+	UserID    string
+	UserLogin string
+	Duration  int64
+	Reason    string
+	CreatedAt time.Time
+	LiftedAt  sql.NullTime
+}
+
+type Channel struct {
+	ChannelID            string
+	AutoreplyEnabled     bool
+	AutoreplyFrequency   float64
+	ReplySafety          int64
+	OpenaiToken          sql.NullString
+	ChatlogEnabled       bool
+	ChatlogRetentionDays int64
+	CommandsPublic       bool
+	Listed               bool
+}
+
+type Command struct {
+	ChannelID   string
+	Name        string
+	Template    string
+	Description string
+	UpdatedBy   string
+	UpdatedAt   sql.NullTime
+}
+
+type DashboardGrant struct {
+	ChannelID string
+	UserID    string
+	Role      string
+	GrantedBy string
+	CreatedAt time.Time
+}
+
+type IncomingWebhook struct {
+	ID        int64
+	ChannelID string
+	Command   string
+	TokenHash string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+type Message struct {
+	ID              int64
+	MessageID       string
+	ChannelID       string
+	UserID          string
+	UserLogin       string
+	UserDisplayName string
+	Message         string
+	Tags            string
+	CreatedAt       time.Time
+}
+
+type Number struct {
+	ChannelID string
+	Name      string
+	Value     int64
+}
+
+type Secret struct {
+	Name  string
+	KeyID string
+	Value []byte
+}
+
+type Webhook struct {
+	ID        int64
+	ChannelID string
+	Url       string
+	Events    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID         int64
+	WebhookID  int64
+	Event      string
+	Payload    string
+	Attempt    int64
+	StatusCode int64
+	Error      string
+	CreatedAt  time.Time
+}
@@ -0,0 +1,103 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: numbers.sql
+
+package postgres
+
+import (
+	"context"
+)
+
+const addToNumber = `-- name: AddToNumber :exec
+INSERT INTO numbers (channel_id, name, value)
+VALUES($1, $2, $3)
+ON CONFLICT(channel_id, name) 
+DO UPDATE SET value = numbers.value + excluded.value
+`
+
+type AddToNumberParams struct {
+	ChannelID string
+	Name      string
+	Value     int64
+}
+
+func (q *Queries) AddToNumber(ctx context.Context, arg AddToNumberParams) error {
+	_, err := q.exec(ctx, q.addToNumberStmt, addToNumber, arg.ChannelID, arg.Name, arg.Value)
+	return err
+}
+
+const deleteNumber = `-- name: DeleteNumber :exec
+DELETE FROM numbers WHERE channel_id = $1 AND name = $2
+`
+
+type DeleteNumberParams struct {
+	ChannelID string
+	Name      string
+}
+
+func (q *Queries) DeleteNumber(ctx context.Context, arg DeleteNumberParams) error {
+	_, err := q.exec(ctx, q.deleteNumberStmt, deleteNumber, arg.ChannelID, arg.Name)
+	return err
+}
+
+const getNumber = `-- name: GetNumber :one
+SELECT channel_id, name, value FROM numbers WHERE channel_id = $1 AND name = $2
+`
+
+type GetNumberParams struct {
+	ChannelID string
+	Name      string
+}
+
+func (q *Queries) GetNumber(ctx context.Context, arg GetNumberParams) (Number, error) {
+	row := q.queryRow(ctx, q.getNumberStmt, getNumber, arg.ChannelID, arg.Name)
+	var i Number
+	err := row.Scan(&i.ChannelID, &i.Name, &i.Value)
+	return i, err
+}
+
+const getNumbers = `-- name: GetNumbers :many
+SELECT channel_id, name, value FROM numbers WHERE channel_id = $1 ORDER BY name ASC
+`
+
+func (q *Queries) GetNumbers(ctx context.Context, channelID string) ([]Number, error) {
+	rows, err := q.query(ctx, q.getNumbersStmt, getNumbers, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Number
+	for rows.Next() {
+		var i Number
+		if err := rows.Scan(&i.ChannelID, &i.Name, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setNumber = `-- name: SetNumber :exec
+INSERT INTO numbers (channel_id, name, value)
+VALUES($1, $2, $3)
+ON CONFLICT(channel_id, name)
+DO UPDATE SET value = excluded.value
+`
+
+type SetNumberParams struct {
+	ChannelID string
+	Name      string
+	Value     int64
+}
+
+func (q *Queries) SetNumber(ctx context.Context, arg SetNumberParams) error {
+	_, err := q.exec(ctx, q.setNumberStmt, setNumber, arg.ChannelID, arg.Name, arg.Value)
+	return err
+}
@@ -0,0 +1,78 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type Querier interface {
+	AddToNumber(ctx context.Context, arg AddToNumberParams) error
+	Approve(ctx context.Context, arg ApproveParams) error
+	CreateAuthorization(ctx context.Context, arg CreateAuthorizationParams) error
+	CreateChannel(ctx context.Context, channelID string) error
+	CreateIncomingWebhook(ctx context.Context, arg CreateIncomingWebhookParams) (IncomingWebhook, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	DeleteAuthorization(ctx context.Context, state string) error
+	DeleteChannel(ctx context.Context, channelID string) error
+	DeleteCommand(ctx context.Context, arg DeleteCommandParams) error
+	DeleteDashboardGrant(ctx context.Context, arg DeleteDashboardGrantParams) error
+	DeleteExpiredAuthorizations(ctx context.Context, expiresAt time.Time) error
+	DeleteIncomingWebhook(ctx context.Context, arg DeleteIncomingWebhookParams) error
+	DeleteMessagesBefore(ctx context.Context, arg DeleteMessagesBeforeParams) error
+	DeleteNumber(ctx context.Context, arg DeleteNumberParams) error
+	DeleteSecret(ctx context.Context, name string) error
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) error
+	DeleteWebhookDeliveries(ctx context.Context, webhookID int64) error
+	DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt time.Time) error
+	GetApprovals(ctx context.Context, channelID string) ([]Approval, error)
+	GetApprovalsByType(ctx context.Context, arg GetApprovalsByTypeParams) ([]Approval, error)
+	GetApprovalsByTypeBefore(ctx context.Context, arg GetApprovalsByTypeBeforeParams) ([]Approval, error)
+	GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error)
+	GetAuthorization(ctx context.Context, state string) (Authorization, error)
+	GetBans(ctx context.Context, arg GetBansParams) ([]Ban, error)
+	GetChannel(ctx context.Context, channelID string) (Channel, error)
+	GetChannelTokens(ctx context.Context) ([]GetChannelTokensRow, error)
+	GetChannels(ctx context.Context) ([]string, error)
+	GetCommand(ctx context.Context, arg GetCommandParams) (string, error)
+	GetCommandDetails(ctx context.Context, arg GetCommandDetailsParams) (Command, error)
+	GetCommands(ctx context.Context, channelID string) ([]string, error)
+	GetCommandsByID(ctx context.Context, channelID string) ([]GetCommandsByIDRow, error)
+	GetDashboardGrant(ctx context.Context, arg GetDashboardGrantParams) (DashboardGrant, error)
+	GetDashboardGrants(ctx context.Context, channelID string) ([]DashboardGrant, error)
+	GetDashboardGrantsForUser(ctx context.Context, userID string) ([]DashboardGrant, error)
+	GetIncomingWebhook(ctx context.Context, arg GetIncomingWebhookParams) (IncomingWebhook, error)
+	GetIncomingWebhookByToken(ctx context.Context, tokenHash string) (IncomingWebhook, error)
+	GetIncomingWebhooks(ctx context.Context, channelID string) ([]IncomingWebhook, error)
+	GetListedChannels(ctx context.Context) ([]string, error)
+	GetMatchingCommands(ctx context.Context, arg GetMatchingCommandsParams) ([]GetMatchingCommandsRow, error)
+	GetNumber(ctx context.Context, arg GetNumberParams) (Number, error)
+	GetNumbers(ctx context.Context, channelID string) ([]Number, error)
+	GetPendingAuthorization(ctx context.Context, arg GetPendingAuthorizationParams) (Authorization, error)
+	GetRecentMessages(ctx context.Context, arg GetRecentMessagesParams) ([]Message, error)
+	GetSecret(ctx context.Context, name string) (Secret, error)
+	GetSecrets(ctx context.Context) ([]Secret, error)
+	GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error)
+	GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	GetWebhooks(ctx context.Context, channelID string) ([]Webhook, error)
+	InsertMessage(ctx context.Context, arg InsertMessageParams) error
+	IsApproved(ctx context.Context, arg IsApprovedParams) (int64, error)
+	LiftBans(ctx context.Context, arg LiftBansParams) error
+	RecordAudit(ctx context.Context, arg RecordAuditParams) error
+	RecordBan(ctx context.Context, arg RecordBanParams) error
+	RecordWebhookDelivery(ctx context.Context, arg RecordWebhookDeliveryParams) error
+	SaveCommand(ctx context.Context, arg SaveCommandParams) error
+	SearchUserMessages(ctx context.Context, arg SearchUserMessagesParams) ([]Message, error)
+	SetCommand(ctx context.Context, arg SetCommandParams) error
+	SetDashboardGrant(ctx context.Context, arg SetDashboardGrantParams) error
+	SetNumber(ctx context.Context, arg SetNumberParams) error
+	SetSecret(ctx context.Context, arg SetSecretParams) error
+	Unapprove(ctx context.Context, arg UnapproveParams) error
+	UpdateChannel(ctx context.Context, arg UpdateChannelParams) error
+	UpdateChannelToken(ctx context.Context, arg UpdateChannelTokenParams) error
+}
+
+var _ Querier = (*Queries)(nil)
@@ -0,0 +1,76 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: secrets.sql
+
+package db
+
+import (
+	"context"
+)
+
+const deleteSecret = `-- name: DeleteSecret :exec
+DELETE FROM secrets WHERE name = ?
+`
+
+func (q *Queries) DeleteSecret(ctx context.Context, name string) error {
+	_, err := q.exec(ctx, q.deleteSecretStmt, deleteSecret, name)
+	return err
+}
+
+const getSecret = `-- name: GetSecret :one
+SELECT name, key_id, value FROM secrets WHERE name = ?
+`
+
+func (q *Queries) GetSecret(ctx context.Context, name string) (Secret, error) {
+	row := q.queryRow(ctx, q.getSecretStmt, getSecret, name)
+	var i Secret
+	err := row.Scan(&i.Name, &i.KeyID, &i.Value)
+	return i, err
+}
+
+const getSecrets = `-- name: GetSecrets :many
+SELECT name, key_id, value FROM secrets ORDER BY name ASC
+`
+
+func (q *Queries) GetSecrets(ctx context.Context) ([]Secret, error) {
+	rows, err := q.query(ctx, q.getSecretsStmt, getSecrets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Secret
+	for rows.Next() {
+		var i Secret
+		if err := rows.Scan(&i.Name, &i.KeyID, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setSecret = `-- name: SetSecret :exec
+INSERT INTO secrets (name, key_id, value)
+  VALUES (?, ?, ?)
+  ON CONFLICT(name) DO UPDATE
+  SET key_id = excluded.key_id,
+  value = excluded.value
+`
+
+type SetSecretParams struct {
+	Name  string
+	KeyID string
+	Value []byte
+}
+
+func (q *Queries) SetSecret(ctx context.Context, arg SetSecretParams) error {
+	_, err := q.exec(ctx, q.setSecretStmt, setSecret, arg.Name, arg.KeyID, arg.Value)
+	return err
+}
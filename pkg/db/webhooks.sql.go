@@ -0,0 +1,217 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: webhooks.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (channel_id, url, events, created_by, created_at)
+  VALUES (?, ?, ?, ?, ?)
+  RETURNING id, channel_id, url, events, created_by, created_at
+`
+
+type CreateWebhookParams struct {
+	ChannelID string
+	Url       string
+	Events    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.queryRow(ctx, q.createWebhookStmt, createWebhook,
+		arg.ChannelID,
+		arg.Url,
+		arg.Events,
+		arg.CreatedBy,
+		arg.CreatedAt,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.ChannelID,
+		&i.Url,
+		&i.Events,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+  WHERE channel_id = ?
+  AND id = ?
+`
+
+type DeleteWebhookParams struct {
+	ChannelID string
+	ID        int64
+}
+
+func (q *Queries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) error {
+	_, err := q.exec(ctx, q.deleteWebhookStmt, deleteWebhook, arg.ChannelID, arg.ID)
+	return err
+}
+
+const deleteWebhookDeliveries = `-- name: DeleteWebhookDeliveries :exec
+DELETE FROM webhook_deliveries
+  WHERE webhook_id = ?
+`
+
+func (q *Queries) DeleteWebhookDeliveries(ctx context.Context, webhookID int64) error {
+	_, err := q.exec(ctx, q.deleteWebhookDeliveriesStmt, deleteWebhookDeliveries, webhookID)
+	return err
+}
+
+const deleteWebhookDeliveriesBefore = `-- name: DeleteWebhookDeliveriesBefore :exec
+DELETE FROM webhook_deliveries
+  WHERE created_at < ?
+`
+
+func (q *Queries) DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt time.Time) error {
+	_, err := q.exec(ctx, q.deleteWebhookDeliveriesBeforeStmt, deleteWebhookDeliveriesBefore, createdAt)
+	return err
+}
+
+const getWebhook = `-- name: GetWebhook :one
+SELECT id, channel_id, url, events, created_by, created_at
+  FROM webhooks
+  WHERE channel_id = ?
+  AND id = ?
+`
+
+type GetWebhookParams struct {
+	ChannelID string
+	ID        int64
+}
+
+func (q *Queries) GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error) {
+	row := q.queryRow(ctx, q.getWebhookStmt, getWebhook, arg.ChannelID, arg.ID)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.ChannelID,
+		&i.Url,
+		&i.Events,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebhookDeliveries = `-- name: GetWebhookDeliveries :many
+SELECT id, webhook_id, event, payload, attempt, status_code, error, created_at
+  FROM webhook_deliveries
+  WHERE webhook_id = ?
+  ORDER BY created_at DESC, id DESC
+  LIMIT ?
+`
+
+type GetWebhookDeliveriesParams struct {
+	WebhookID int64
+	Limit     int64
+}
+
+func (q *Queries) GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.query(ctx, q.getWebhookDeliveriesStmt, getWebhookDeliveries, arg.WebhookID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Attempt,
+			&i.StatusCode,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhooks = `-- name: GetWebhooks :many
+SELECT id, channel_id, url, events, created_by, created_at
+  FROM webhooks
+  WHERE channel_id = ?
+  ORDER BY id
+`
+
+func (q *Queries) GetWebhooks(ctx context.Context, channelID string) ([]Webhook, error) {
+	rows, err := q.query(ctx, q.getWebhooksStmt, getWebhooks, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.Url,
+			&i.Events,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordWebhookDelivery = `-- name: RecordWebhookDelivery :exec
+INSERT INTO webhook_deliveries (webhook_id, event, payload, attempt, status_code, error, created_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type RecordWebhookDeliveryParams struct {
+	WebhookID  int64
+	Event      string
+	Payload    string
+	Attempt    int64
+	StatusCode int64
+	Error      string
+	CreatedAt  time.Time
+}
+
+func (q *Queries) RecordWebhookDelivery(ctx context.Context, arg RecordWebhookDeliveryParams) error {
+	_, err := q.exec(ctx, q.recordWebhookDeliveryStmt, recordWebhookDelivery,
+		arg.WebhookID,
+		arg.Event,
+		arg.Payload,
+		arg.Attempt,
+		arg.StatusCode,
+		arg.Error,
+		arg.CreatedAt,
+	)
+	return err
+}
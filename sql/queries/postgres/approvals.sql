@@ -0,0 +1,56 @@
+-- name: IsApproved :one
+SELECT
+  COUNT(*)
+FROM
+  approvals
+WHERE
+  channel_id = $1
+  AND user_id = $2;
+
+-- name: GetApprovals :many
+SELECT
+  *
+FROM
+  approvals
+WHERE
+  channel_id = $1
+  AND manual = true
+ORDER BY user_id DESC;
+
+-- name: GetApprovalsByType :many
+SELECT
+  *
+FROM
+  approvals
+WHERE
+  channel_id = sqlc.arg(channel_id)
+  AND manual = sqlc.arg(manual)
+  AND user_id > sqlc.arg(user_id)
+ORDER BY user_id ASC
+LIMIT sqlc.arg('limit')::bigint;
+
+-- name: GetApprovalsByTypeBefore :many
+SELECT
+  *
+FROM
+  approvals
+WHERE
+  channel_id = sqlc.arg(channel_id)
+  AND manual = sqlc.arg(manual)
+  AND user_id < sqlc.arg(user_id)
+ORDER BY user_id DESC
+LIMIT sqlc.arg('limit')::bigint;
+
+-- name: Approve :exec
+INSERT INTO
+  approvals (channel_id, user_id, manual)
+VALUES
+  ($1, $2, $3) ON CONFLICT (channel_id, user_id) DO UPDATE
+  SET manual = approvals.manual OR excluded.manual;
+
+-- name: Unapprove :exec
+DELETE FROM
+  approvals
+WHERE
+  channel_id = $1
+  AND user_id = $2;
\ No newline at end of file
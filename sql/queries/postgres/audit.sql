@@ -0,0 +1,11 @@
+-- name: RecordAudit :exec
+INSERT INTO audit_log (channel_id, actor_id, actor_login, action, before, after, created_at)
+  VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetAuditLog :many
+SELECT *
+  FROM audit_log
+  WHERE channel_id = sqlc.arg(channel_id)
+  AND id < sqlc.arg(id)::bigint
+  ORDER BY id DESC
+  LIMIT sqlc.arg('limit')::bigint;
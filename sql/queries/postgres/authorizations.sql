@@ -0,0 +1,18 @@
+-- name: CreateAuthorization :exec
+INSERT INTO authorizations (state, flow, device_code, user_code, verification_uri, created_at, expires_at)
+  VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetAuthorization :one
+SELECT * FROM authorizations WHERE state = $1;
+
+-- name: GetPendingAuthorization :one
+SELECT * FROM authorizations
+  WHERE flow = $1 AND expires_at > $2
+  ORDER BY created_at DESC
+  LIMIT 1;
+
+-- name: DeleteAuthorization :exec
+DELETE FROM authorizations WHERE state = $1;
+
+-- name: DeleteExpiredAuthorizations :exec
+DELETE FROM authorizations WHERE expires_at <= $1;
@@ -0,0 +1,17 @@
+-- name: RecordBan :exec
+INSERT INTO bans (channel_id, user_id, user_login, duration, reason, created_at)
+  VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: LiftBans :exec
+UPDATE bans
+  SET lifted_at = $1
+  WHERE channel_id = $2
+  AND user_id = $3
+  AND lifted_at IS NULL;
+
+-- name: GetBans :many
+SELECT *
+  FROM bans
+  WHERE channel_id = sqlc.arg(channel_id)
+  ORDER BY created_at DESC
+  LIMIT sqlc.arg('limit')::bigint;
@@ -0,0 +1,38 @@
+-- name: GetChannels :many
+SELECT channel_id FROM channels;
+
+-- name: GetListedChannels :many
+SELECT channel_id FROM channels WHERE listed = true;
+
+-- name: GetChannel :one
+SELECT * FROM channels WHERE channel_id = $1;
+
+-- name: UpdateChannel :exec
+UPDATE channels
+ SET autoreply_enabled = $1,
+  autoreply_frequency = $2,
+  reply_safety = $3,
+  chatlog_enabled = $4,
+  chatlog_retention_days = $5,
+  commands_public = $6,
+  listed = $7
+ WHERE channel_id = $8;
+
+-- name: UpdateChannelToken :exec
+UPDATE channels
+ SET openai_token = $1
+ WHERE channel_id = $2;
+
+-- name: DeleteChannel :exec
+DELETE FROM channels
+  WHERE channel_id = $1;
+
+-- name: CreateChannel :exec
+INSERT INTO channels (channel_id)
+  VALUES ($1)
+  ON CONFLICT DO NOTHING;
+
+-- name: GetChannelTokens :many
+SELECT channel_id, openai_token
+  FROM channels
+  WHERE openai_token IS NOT NULL;
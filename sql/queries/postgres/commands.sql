@@ -0,0 +1,58 @@
+-- name: GetCommand :one
+SELECT template
+  FROM commands
+  WHERE name = $1
+  AND channel_id = $2;
+
+-- name: GetMatchingCommands :many
+SELECT
+    template,
+    name,
+    channel_id
+  FROM commands
+  WHERE (
+    channel_id = sqlc.arg(channel_id)
+    OR
+    channel_id = '0'
+  )
+  AND sqlc.arg(message)::text ~ name;
+
+-- name: GetCommands :many
+SELECT name
+  FROM commands
+  WHERE channel_id = $1
+  ORDER BY name ASC;
+
+-- name: GetCommandsByID :many
+SELECT name, template, description, updated_by, updated_at
+  FROM commands
+  WHERE channel_id = $1
+  ORDER BY name ASC;
+
+-- name: GetCommandDetails :one
+SELECT *
+  FROM commands
+  WHERE channel_id = $1
+  AND name = $2;
+
+-- name: DeleteCommand :exec
+DELETE FROM commands
+  WHERE channel_id = $1
+  AND name = $2;
+
+-- name: SetCommand :exec
+INSERT INTO commands (channel_id, name, template, updated_by, updated_at)
+  VALUES ($1, $2, $3, $4, $5)
+  ON CONFLICT(channel_id, name) DO UPDATE
+  SET template = excluded.template,
+    updated_by = excluded.updated_by,
+    updated_at = excluded.updated_at;
+
+-- name: SaveCommand :exec
+INSERT INTO commands (channel_id, name, template, description, updated_by, updated_at)
+  VALUES ($1, $2, $3, $4, $5, $6)
+  ON CONFLICT(channel_id, name) DO UPDATE
+  SET template = excluded.template,
+    description = excluded.description,
+    updated_by = excluded.updated_by,
+    updated_at = excluded.updated_at;
@@ -0,0 +1,30 @@
+-- name: GetDashboardGrant :one
+SELECT *
+  FROM dashboard_grants
+  WHERE channel_id = $1
+  AND user_id = $2;
+
+-- name: GetDashboardGrants :many
+SELECT *
+  FROM dashboard_grants
+  WHERE channel_id = $1
+  ORDER BY user_id ASC;
+
+-- name: GetDashboardGrantsForUser :many
+SELECT *
+  FROM dashboard_grants
+  WHERE user_id = $1
+  ORDER BY channel_id ASC;
+
+-- name: SetDashboardGrant :exec
+INSERT INTO dashboard_grants (channel_id, user_id, role, granted_by, created_at)
+  VALUES ($1, $2, $3, $4, $5)
+  ON CONFLICT(channel_id, user_id) DO UPDATE
+  SET role = excluded.role,
+    granted_by = excluded.granted_by,
+    created_at = excluded.created_at;
+
+-- name: DeleteDashboardGrant :exec
+DELETE FROM dashboard_grants
+  WHERE channel_id = $1
+  AND user_id = $2;
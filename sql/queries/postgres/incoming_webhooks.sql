@@ -0,0 +1,26 @@
+-- name: CreateIncomingWebhook :one
+INSERT INTO incoming_webhooks (channel_id, command, token_hash, created_by, created_at)
+  VALUES ($1, $2, $3, $4, $5)
+  RETURNING *;
+
+-- name: GetIncomingWebhook :one
+SELECT *
+  FROM incoming_webhooks
+  WHERE channel_id = $1
+  AND id = $2;
+
+-- name: GetIncomingWebhookByToken :one
+SELECT *
+  FROM incoming_webhooks
+  WHERE token_hash = $1;
+
+-- name: GetIncomingWebhooks :many
+SELECT *
+  FROM incoming_webhooks
+  WHERE channel_id = $1
+  ORDER BY id;
+
+-- name: DeleteIncomingWebhook :exec
+DELETE FROM incoming_webhooks
+  WHERE channel_id = $1
+  AND id = $2;
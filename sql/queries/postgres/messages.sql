@@ -0,0 +1,24 @@
+-- name: InsertMessage :exec
+INSERT INTO messages (message_id, channel_id, user_id, user_login, user_display_name, message, tags, created_at)
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetRecentMessages :many
+SELECT *
+  FROM messages
+  WHERE channel_id = sqlc.arg(channel_id)
+  ORDER BY created_at DESC
+  LIMIT sqlc.arg('limit')::bigint;
+
+-- name: SearchUserMessages :many
+SELECT *
+  FROM messages
+  WHERE channel_id = sqlc.arg(channel_id)
+  AND user_login = sqlc.arg(user_login)
+  AND message LIKE '%' || sqlc.arg(message)::text || '%' ESCAPE '\'
+  ORDER BY created_at DESC
+  LIMIT sqlc.arg('limit')::bigint;
+
+-- name: DeleteMessagesBefore :exec
+DELETE FROM messages
+  WHERE channel_id = $1
+  AND created_at < $2;
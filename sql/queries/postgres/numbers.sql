@@ -0,0 +1,20 @@
+-- name: GetNumber :one
+SELECT * FROM numbers WHERE channel_id = $1 AND name = $2;
+
+-- name: GetNumbers :many
+SELECT * FROM numbers WHERE channel_id = $1 ORDER BY name ASC;
+
+-- name: AddToNumber :exec
+INSERT INTO numbers (channel_id, name, value)
+VALUES($1, $2, $3)
+ON CONFLICT(channel_id, name) 
+DO UPDATE SET value = numbers.value + excluded.value;
+
+-- name: SetNumber :exec
+INSERT INTO numbers (channel_id, name, value)
+VALUES($1, $2, $3)
+ON CONFLICT(channel_id, name)
+DO UPDATE SET value = excluded.value;
+
+-- name: DeleteNumber :exec
+DELETE FROM numbers WHERE channel_id = $1 AND name = $2;
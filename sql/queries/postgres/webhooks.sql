@@ -0,0 +1,40 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (channel_id, url, events, created_by, created_at)
+  VALUES ($1, $2, $3, $4, $5)
+  RETURNING *;
+
+-- name: GetWebhook :one
+SELECT *
+  FROM webhooks
+  WHERE channel_id = $1
+  AND id = $2;
+
+-- name: GetWebhooks :many
+SELECT *
+  FROM webhooks
+  WHERE channel_id = $1
+  ORDER BY id;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+  WHERE channel_id = $1
+  AND id = $2;
+
+-- name: RecordWebhookDelivery :exec
+INSERT INTO webhook_deliveries (webhook_id, event, payload, attempt, status_code, error, created_at)
+  VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetWebhookDeliveries :many
+SELECT *
+  FROM webhook_deliveries
+  WHERE webhook_id = sqlc.arg(webhook_id)
+  ORDER BY created_at DESC, id DESC
+  LIMIT sqlc.arg('limit')::bigint;
+
+-- name: DeleteWebhookDeliveries :exec
+DELETE FROM webhook_deliveries
+  WHERE webhook_id = $1;
+
+-- name: DeleteWebhookDeliveriesBefore :exec
+DELETE FROM webhook_deliveries
+  WHERE created_at < $1;
@@ -0,0 +1,56 @@
+-- name: IsApproved :one
+SELECT
+  COUNT(*)
+FROM
+  approvals
+WHERE
+  channel_id = ?
+  AND user_id = ?;
+
+-- name: GetApprovals :many
+SELECT
+  *
+FROM
+  approvals
+WHERE
+  channel_id = ?
+  AND manual = true
+ORDER BY user_id DESC;
+
+-- name: GetApprovalsByType :many
+SELECT
+  *
+FROM
+  approvals
+WHERE
+  channel_id = ?
+  AND manual = ?
+  AND user_id > ?
+ORDER BY user_id ASC
+LIMIT ?;
+
+-- name: GetApprovalsByTypeBefore :many
+SELECT
+  *
+FROM
+  approvals
+WHERE
+  channel_id = ?
+  AND manual = ?
+  AND user_id < ?
+ORDER BY user_id DESC
+LIMIT ?;
+
+-- name: Approve :exec
+INSERT INTO
+  approvals (channel_id, user_id, manual)
+VALUES
+  (?, ?, ?) ON CONFLICT (channel_id, user_id) DO UPDATE
+  SET manual = approvals.manual OR excluded.manual;
+
+-- name: Unapprove :exec
+DELETE FROM
+  approvals
+WHERE
+  channel_id = ?
+  AND user_id = ?;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- name: RecordAudit :exec
+INSERT INTO audit_log (channel_id, actor_id, actor_login, action, before, after, created_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetAuditLog :many
+SELECT *
+  FROM audit_log
+  WHERE channel_id = ?
+  AND id < ?
+  ORDER BY id DESC
+  LIMIT ?;
@@ -0,0 +1,18 @@
+-- name: CreateAuthorization :exec
+INSERT INTO authorizations (state, flow, device_code, user_code, verification_uri, created_at, expires_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetAuthorization :one
+SELECT * FROM authorizations WHERE state = ?;
+
+-- name: GetPendingAuthorization :one
+SELECT * FROM authorizations
+  WHERE flow = ? AND expires_at > ?
+  ORDER BY created_at DESC
+  LIMIT 1;
+
+-- name: DeleteAuthorization :exec
+DELETE FROM authorizations WHERE state = ?;
+
+-- name: DeleteExpiredAuthorizations :exec
+DELETE FROM authorizations WHERE expires_at <= ?;
@@ -0,0 +1,17 @@
+-- name: RecordBan :exec
+INSERT INTO bans (channel_id, user_id, user_login, duration, reason, created_at)
+  VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: LiftBans :exec
+UPDATE bans
+  SET lifted_at = ?
+  WHERE channel_id = ?
+  AND user_id = ?
+  AND lifted_at IS NULL;
+
+-- name: GetBans :many
+SELECT *
+  FROM bans
+  WHERE channel_id = ?
+  ORDER BY created_at DESC
+  LIMIT ?;
@@ -1,6 +1,9 @@
 -- name: GetChannels :many
 SELECT channel_id FROM channels;
 
+-- name: GetListedChannels :many
+SELECT channel_id FROM channels WHERE listed = true;
+
 -- name: GetChannel :one
 SELECT * FROM channels WHERE channel_id = ?;
 
@@ -8,7 +11,11 @@ SELECT * FROM channels WHERE channel_id = ?;
 UPDATE channels
  SET autoreply_enabled = ?,
   autoreply_frequency = ?,
-  reply_safety = ?
+  reply_safety = ?,
+  chatlog_enabled = ?,
+  chatlog_retention_days = ?,
+  commands_public = ?,
+  listed = ?
  WHERE channel_id = ?;
 
 -- name: UpdateChannelToken :exec
@@ -24,3 +31,8 @@ DELETE FROM channels
 INSERT INTO channels (channel_id)
   VALUES (?)
   ON CONFLICT DO NOTHING;
+
+-- name: GetChannelTokens :many
+SELECT channel_id, openai_token
+  FROM channels
+  WHERE openai_token IS NOT NULL;
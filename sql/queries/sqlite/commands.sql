@@ -0,0 +1,58 @@
+-- name: GetCommand :one
+SELECT template
+  FROM commands
+  WHERE name = ?
+  AND channel_id = ?;
+
+-- name: GetMatchingCommands :many
+SELECT
+    template,
+    name,
+    channel_id
+  FROM commands
+  WHERE (
+    channel_id = ?
+    OR
+    channel_id = '0'
+  )
+  AND regexp(name, ?);
+
+-- name: GetCommands :many
+SELECT name
+  FROM commands
+  WHERE channel_id = ?
+  ORDER BY name ASC;
+
+-- name: GetCommandsByID :many
+SELECT name, template, description, updated_by, updated_at
+  FROM commands
+  WHERE channel_id = ?
+  ORDER BY name ASC;
+
+-- name: GetCommandDetails :one
+SELECT *
+  FROM commands
+  WHERE channel_id = ?
+  AND name = ?;
+
+-- name: DeleteCommand :exec
+DELETE FROM commands
+  WHERE channel_id = ?
+  AND name = ?;
+
+-- name: SetCommand :exec
+INSERT INTO commands (channel_id, name, template, updated_by, updated_at)
+  VALUES (?, ?, ?, ?, ?)
+  ON CONFLICT(channel_id, name) DO UPDATE
+  SET template = excluded.template,
+    updated_by = excluded.updated_by,
+    updated_at = excluded.updated_at;
+
+-- name: SaveCommand :exec
+INSERT INTO commands (channel_id, name, template, description, updated_by, updated_at)
+  VALUES (?, ?, ?, ?, ?, ?)
+  ON CONFLICT(channel_id, name) DO UPDATE
+  SET template = excluded.template,
+    description = excluded.description,
+    updated_by = excluded.updated_by,
+    updated_at = excluded.updated_at;
@@ -0,0 +1,26 @@
+-- name: CreateIncomingWebhook :one
+INSERT INTO incoming_webhooks (channel_id, command, token_hash, created_by, created_at)
+  VALUES (?, ?, ?, ?, ?)
+  RETURNING *;
+
+-- name: GetIncomingWebhook :one
+SELECT *
+  FROM incoming_webhooks
+  WHERE channel_id = ?
+  AND id = ?;
+
+-- name: GetIncomingWebhookByToken :one
+SELECT *
+  FROM incoming_webhooks
+  WHERE token_hash = ?;
+
+-- name: GetIncomingWebhooks :many
+SELECT *
+  FROM incoming_webhooks
+  WHERE channel_id = ?
+  ORDER BY id;
+
+-- name: DeleteIncomingWebhook :exec
+DELETE FROM incoming_webhooks
+  WHERE channel_id = ?
+  AND id = ?;
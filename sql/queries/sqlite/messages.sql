@@ -0,0 +1,24 @@
+-- name: InsertMessage :exec
+INSERT INTO messages (message_id, channel_id, user_id, user_login, user_display_name, message, tags, created_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetRecentMessages :many
+SELECT *
+  FROM messages
+  WHERE channel_id = ?
+  ORDER BY created_at DESC
+  LIMIT ?;
+
+-- name: SearchUserMessages :many
+SELECT *
+  FROM messages
+  WHERE channel_id = ?
+  AND user_login = ?
+  AND message LIKE '%' || ? || '%' ESCAPE '\'
+  ORDER BY created_at DESC
+  LIMIT ?;
+
+-- name: DeleteMessagesBefore :exec
+DELETE FROM messages
+  WHERE channel_id = ?
+  AND created_at < ?;
@@ -0,0 +1,20 @@
+-- name: GetNumber :one
+SELECT * FROM numbers WHERE channel_id = ? AND name = ?;
+
+-- name: GetNumbers :many
+SELECT * FROM numbers WHERE channel_id = ? ORDER BY name ASC;
+
+-- name: AddToNumber :exec
+INSERT INTO numbers (channel_id, name, value)
+VALUES(?, ?, ?)
+ON CONFLICT(channel_id, name) 
+DO UPDATE SET value = value + excluded.value;
+
+-- name: SetNumber :exec
+INSERT INTO numbers (channel_id, name, value)
+VALUES(?, ?, ?)
+ON CONFLICT(channel_id, name)
+DO UPDATE SET value = excluded.value;
+
+-- name: DeleteNumber :exec
+DELETE FROM numbers WHERE channel_id = ? AND name = ?;
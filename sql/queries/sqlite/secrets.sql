@@ -0,0 +1,15 @@
+-- name: GetSecret :one
+SELECT * FROM secrets WHERE name = ?;
+
+-- name: GetSecrets :many
+SELECT * FROM secrets ORDER BY name ASC;
+
+-- name: SetSecret :exec
+INSERT INTO secrets (name, key_id, value)
+  VALUES (?, ?, ?)
+  ON CONFLICT(name) DO UPDATE
+  SET key_id = excluded.key_id,
+  value = excluded.value;
+
+-- name: DeleteSecret :exec
+DELETE FROM secrets WHERE name = ?;
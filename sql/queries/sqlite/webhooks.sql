@@ -0,0 +1,40 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (channel_id, url, events, created_by, created_at)
+  VALUES (?, ?, ?, ?, ?)
+  RETURNING *;
+
+-- name: GetWebhook :one
+SELECT *
+  FROM webhooks
+  WHERE channel_id = ?
+  AND id = ?;
+
+-- name: GetWebhooks :many
+SELECT *
+  FROM webhooks
+  WHERE channel_id = ?
+  ORDER BY id;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+  WHERE channel_id = ?
+  AND id = ?;
+
+-- name: RecordWebhookDelivery :exec
+INSERT INTO webhook_deliveries (webhook_id, event, payload, attempt, status_code, error, created_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetWebhookDeliveries :many
+SELECT *
+  FROM webhook_deliveries
+  WHERE webhook_id = ?
+  ORDER BY created_at DESC, id DESC
+  LIMIT ?;
+
+-- name: DeleteWebhookDeliveries :exec
+DELETE FROM webhook_deliveries
+  WHERE webhook_id = ?;
+
+-- name: DeleteWebhookDeliveriesBefore :exec
+DELETE FROM webhook_deliveries
+  WHERE created_at < ?;